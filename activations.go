@@ -116,6 +116,91 @@ func K2c_softmax(x []float64) {
 }
 
 
+/**
+ * Log soft max activation function.
+ *   y = x - max(x) - log(sum(exp(x-max(x))))
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_log_softmax(x []float64) {
+	xmax := x[0]
+	var sum float64
+	for _, value := range x {
+		if value > xmax {
+			xmax = value
+		}
+	}
+
+	for _, value := range x {
+		sum += math.Exp(value - xmax)
+	}
+
+	var shift = xmax + math.Log(sum)
+	for idx, value := range x {
+		x[idx] = value - shift
+	}
+}
+
+/**
+ * Soft max along one axis of a tensor.
+ *
+ * :param output: output tensor. May be the input tensor.
+ * :param input: input tensor.
+ * :param axis: axis to normalize over.
+ */
+func K2c_softmax_axis(output *K2c_tensor, input *K2c_tensor, axis int) {
+	k2c_along_axis(output, input, axis, K2c_softmax)
+}
+
+/**
+ * Log soft max along one axis of a tensor.
+ *
+ * :param output: output tensor. May be the input tensor.
+ * :param input: input tensor.
+ * :param axis: axis to normalize over.
+ */
+func K2c_log_softmax_axis(output *K2c_tensor, input *K2c_tensor, axis int) {
+	k2c_along_axis(output, input, axis, K2c_log_softmax)
+}
+
+/**
+ * Applies an activation function separately to every vector along one axis of a tensor.
+ * vectors along the last axis are contiguous and processed in place, others are gathered into a temporary vector.
+ *
+ * :param output: output tensor. May be the input tensor.
+ * :param input: input tensor.
+ * :param axis: axis of the vectors.
+ * :param activation: activation function to apply.
+ */
+func k2c_along_axis(output *K2c_tensor, input *K2c_tensor, axis int, activation k2c_activationType) {
+	var offset = 1
+	for i := axis + 1; i < input.Ndim; i++ {
+		offset *= input.Shape[i]
+	}
+	var step = input.Shape[axis]
+	var outer = input.Numel / (step * offset)
+	copy(output.Array, input.Array[:input.Numel])
+	if offset == 1 {
+		for i := 0; i < outer; i++ {
+			activation(output.Array[i*step : (i+1)*step])
+		}
+		return
+	}
+	var vec = make([]float64, step)
+	for i := 0; i < outer; i++ {
+		for j := 0; j < offset; j++ {
+			var start = i*step*offset + j
+			for k := 0; k < step; k++ {
+				vec[k] = output.Array[start+k*offset]
+			}
+			activation(vec)
+			for k := 0; k < step; k++ {
+				output.Array[start+k*offset] = vec[k]
+			}
+		}
+	}
+}
+
 /**
  * Soft plus activation function.
  *   y = ln(1+exp(x))
@@ -140,6 +225,164 @@ func K2c_softsign(x []float64) {
 	}
 }
 
+/**
+ * Exact GELU activation function.
+ *   y = x/2 * (1 + erf(x/sqrt(2)))
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_gelu(x []float64) {
+	for idx, value := range x {
+		x[idx] = 0.5 * value * (1 + math.Erf(value/math.Sqrt2))
+	}
+}
+
+/**
+ * GELU activation function, tanh approximation.
+ *   y = x/2 * (1 + tanh(sqrt(2/pi) * (x + 0.044715*x^3)))
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_gelu_tanh(x []float64) {
+	var c = math.Sqrt(2 / math.Pi)
+	for idx, value := range x {
+		x[idx] = 0.5 * value * (1 + math.Tanh(c*(value+0.044715*value*value*value)))
+	}
+}
+
+/**
+ * Swish activation function, aka SiLU.
+ *   y = x * sigmoid(x)
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_swish(x []float64) {
+	for idx, value := range x {
+		x[idx] = value / (1 + math.Exp(-value))
+	}
+}
+
+/**
+ * SiLU activation function, same as swish.
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_silu(x []float64) {
+	K2c_swish(x)
+}
+
+/**
+ * Scaled exponential linear unit activation function.
+ *   y = scale*x if x > 0
+ *   y = scale*alpha*(exp(x)-1) if x <= 0
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_selu(x []float64) {
+	const alpha = 1.6732632423543772848170429916717
+	const scale = 1.0507009873554804934193349852946
+	for idx, value := range x {
+		if value > 0 {
+			x[idx] = scale * value
+		} else {
+			x[idx] = scale * alpha * math.Expm1(value)
+		}
+	}
+}
+
+/**
+ * Mish activation function.
+ *   y = x * tanh(softplus(x))
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_mish(x []float64) {
+	for idx, value := range x {
+		x[idx] = value * math.Tanh(math.Log1p(math.Exp(value)))
+	}
+}
+
+/**
+ * ReLU6 activation function.
+ *   y = min(max(x, 0), 6)
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_relu6(x []float64) {
+	for idx, value := range x {
+		x[idx] = math.Min(math.Max(value, 0), 6)
+	}
+}
+
+/**
+ * Hard swish activation function.
+ *   y = x * relu6(x+3) / 6
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_hard_swish(x []float64) {
+	for idx, value := range x {
+		x[idx] = value * math.Min(math.Max(value+3, 0), 6) / 6
+	}
+}
+
+/**
+ * Exponential linear unit activation function with alpha = 1.
+ *
+ * :param x: Array of input values. Gets overwritten by output.
+ */
+func K2c_elu(x []float64) {
+	k2c_ELU(x, 1)
+}
+
+/**
+ * Exponential linear unit activation function with a given alpha.
+ *
+ * :param alpha: slope of negative portion of activation curve.
+ * :return: the activation function.
+ */
+func K2c_elu_alpha(alpha float64) k2c_activationType {
+	return func(x []float64) {
+		k2c_ELU(x, alpha)
+	}
+}
+
+/**
+ * Activation functions by keras name.
+ */
+var k2c_activations = map[string]k2c_activationType{
+	"linear":       K2c_linear,
+	"exponential":  K2c_exponential,
+	"relu":         K2c_relu,
+	"hard_sigmoid": K2c_hard_sigmoid,
+	"tanh":         K2c_tanh,
+	"sigmoid":      K2c_sigmoid,
+	"softmax":      K2c_softmax,
+	"log_softmax":  K2c_log_softmax,
+	"softplus":     K2c_softplus,
+	"softsign":     K2c_softsign,
+	"gelu":         K2c_gelu,
+	"gelu_tanh":    K2c_gelu_tanh,
+	"swish":        K2c_swish,
+	"silu":         K2c_silu,
+	"selu":         K2c_selu,
+	"mish":         K2c_mish,
+	"relu6":        K2c_relu6,
+	"hard_swish":   K2c_hard_swish,
+	"hard_silu":    K2c_hard_swish,
+	"elu":          K2c_elu,
+}
+
+/**
+ * Looks up an activation function by its keras name.
+ *
+ * :param name: keras name of the activation, eg "relu" or "gelu".
+ * :return: the activation function, or nil if there is none with that name.
+ */
+func K2c_get_activation(name string) k2c_activationType {
+	return k2c_activations[name]
+}
+
 /**
  * Leaky version of a Rectified Linear Unit.
  * It allows a small gradient when the unit is not active:
@@ -0,0 +1,88 @@
+package keras2go
+
+import "testing"
+
+func TestK2c_get_activation(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []float64
+	}{
+		{"relu6", []float64{0, 0, 2}},
+		{"swish", []float64{-0.2689414213699951, 0, 1.7615941559557646}},
+		{"silu", []float64{-0.2689414213699951, 0, 1.7615941559557646}},
+		{"gelu", []float64{-0.15865525393145707, 0, 1.9544997361036416}},
+	}
+	for _, test := range tests {
+		var activation = K2c_get_activation(test.name)
+		if activation == nil {
+			t.Fatal("no activation", test.name)
+		}
+		var x = []float64{-1, 0, 2}
+		activation(x)
+		checkArrayTol(t, x, test.expected, 1e-15)
+	}
+	if K2c_get_activation("not_an_activation") != nil {
+		t.Fatal("expected no activation for an unknown name")
+	}
+}
+
+func TestK2c_activations(t *testing.T) {
+	tests := []struct {
+		name       string
+		activation k2c_activationType
+		input      []float64
+		expected   []float64
+	}{
+		{"gelu_tanh", K2c_gelu_tanh, []float64{-4, -1, -0.5, 0, 0.5, 1, 4}, []float64{-7.024594819227126e-05,
+			-0.15880800939172324, -0.15428599017485606, 0, 0.34571400982514394, 0.8411919906082768, 3.9999297540518075}},
+		{"mish", K2c_mish, []float64{-4, -1, -0.5, 0, 0.5, 1, 4}, []float64{-0.07259174079202535,
+			-0.30340146137410895, -0.22074377465173, 0, 0.3752452113048951, 0.8650983882673103, 3.9974128069762385}},
+		{"hard_swish", K2c_hard_swish, []float64{-4, -1, -0.5, 0, 0.5, 1, 4},
+			[]float64{0, -1.0 / 3, -0.20833333333333334, 0, 0.2916666666666667, 2.0 / 3, 4}},
+		{"elu_alpha", K2c_elu_alpha(0.5), []float64{-4, -1, -0.5, 0, 0.5, 1, 4}, []float64{-0.4908421805556329,
+			-0.31606027941427883, -0.1967346701436833, 0, 0.5, 1, 4}},
+		{"log_softmax", K2c_log_softmax, []float64{1, 2, 3, -1}, []float64{-2.4197166463153397, -1.4197166463153397,
+			-0.41971664631533967, -4.41971664631534}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var x = append([]float64{}, test.input...)
+			test.activation(x)
+			checkArrayClose(t, x, test.expected)
+		})
+	}
+}
+
+func TestK2c_softmax_axis(t *testing.T) {
+	tests := []struct {
+		name     string
+		softmax  func(output *K2c_tensor, input *K2c_tensor, axis int)
+		shape    []int
+		axis     int
+		input    []float64
+		expected []float64
+	}{
+		{"first axis", K2c_softmax_axis, []int{2, 3}, 0, []float64{1, 2, 3, 0.5, -1, 4}, []float64{0.6224593312018546,
+			0.9525741268224334, 0.2689414213699951, 0.37754066879814546, 0.04742587317756679, 0.7310585786300049}},
+		{"middle axis", K2c_softmax_axis, []int{2, 3, 2}, 1,
+			[]float64{0.5, -1, 2, 0.25, -0.75, 1.5, 3, -2, 0, 1, -0.5, 0.8},
+			[]float64{0.1733644920789951, 0.05997779531511428, 0.7769657493351423, 0.20934307548219694,
+				0.049669758585862527, 0.7306791292026887, 0.9259392562016979, 0.026645219968701752,
+				0.04609980105300302, 0.5351835495078163, 0.027960942745299075, 0.43817123052348195}},
+		{"log middle axis", K2c_log_softmax_axis, []int{2, 3, 2}, 1,
+			[]float64{0.5, -1, 2, 0.25, -0.75, 1.5, 3, -2, 0, 1, -0.5, 0.8},
+			[]float64{-1.7523590102358226, -2.813780863337258, -0.25235901023582263, -1.563780863337258,
+				-3.0023590102358226, -0.3137808633372581, -0.07694664454192601, -3.625145507733531,
+				-3.076946644541926, -0.6251455077335311, -3.576946644541926, -0.825145507733531}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var input = newTestTensor(test.shape)
+			copy(input.Array, test.input)
+			var output = newTestTensor(test.shape)
+			test.softmax(output, input, test.axis)
+			checkArrayClose(t, output.Array, test.expected)
+			checkArray(t, input.Array, test.input)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package keras2go
+
+import "math"
+
+/**
+* Turns raw attention scores into attention weights.
+* scores of masked out (query, key) pairs are pushed down by 1e9 before the softmax over each row, like keras does.
+* in a row where every key is masked keras float32 scores all round to -1e9, so the weights are uniform.
+*
+* :param scores: Array[rows*cols] of attention scores. Gets overwritten by the attention weights.
+* :param rows: number of queries.
+* :param cols: number of keys.
+* :param row_mask: Array[rows] mask of the queries, or nil.
+* :param value_mask: Array[cols] mask of the values, or nil.
+* :param key_mask: Array[cols] mask of the keys, or nil.
+* :param attention_mask: tensor of shape (rows, cols), nonzero where a query may attend to a key, or nil.
+* :param use_causal_mask: (0,1) whether queries may only attend to keys at or before their own position.
+*/
+func k2c_attention_weights(scores []float64, rows int, cols int, row_mask []bool, value_mask []bool, key_mask []bool,
+	attention_mask *K2c_tensor, use_causal_mask int) {
+	for i := 0; i < rows; i++ {
+		var row = scores[i*cols : (i+1)*cols]
+		var kept = 0
+		for j := 0; j < cols; j++ {
+			var keep = (row_mask == nil || row_mask[i]) &&
+				(value_mask == nil || value_mask[j]) &&
+				(key_mask == nil || key_mask[j]) &&
+				(attention_mask == nil || attention_mask.Array[i*cols+j] != 0) &&
+				(use_causal_mask == 0 || j <= i)
+			if keep {
+				kept++
+			} else {
+				row[j] -= 1e9
+			}
+		}
+		if kept == 0 {
+			for j := range row {
+				row[j] = 1 / float64(cols)
+			}
+			continue
+		}
+		K2c_softmax(row)
+	}
+}
+
+/**
+* Dot-product attention layer, aka Luong-style attention.
+*
+* :param output: output tensor, shape (Tq, dim_v).
+* :param query: query tensor, shape (Tq, dim).
+* :param value: value tensor, shape (Tv, dim_v).
+* :param key: key tensor, shape (Tv, dim). Pass value if there is no separate key.
+* :param query_mask: Array[Tq] mask of the query, or nil. masked query steps give zero output.
+* :param value_mask: Array[Tv] mask of the value, or nil.
+* :param scale: scale applied to the scores.
+* :param use_causal_mask: (0,1) whether to prevent the flow of information from the future towards the past.
+* :param fwork: Array of working space, size(fwork) = Tq*Tv + size(query) + size(key).
+*/
+func K2c_attention(output *K2c_tensor, query *K2c_tensor, value *K2c_tensor, key *K2c_tensor, query_mask []bool, value_mask []bool,
+	scale float64, use_causal_mask int, fwork []float64) {
+	var Tq = query.Shape[0]
+	var Tv = value.Shape[0]
+	var dim_v = value.Shape[1]
+	var scores = K2c_tensor{Array: fwork[:Tq*Tv], Ndim: 2, Numel: Tq * Tv, Shape: [K2C_MAX_NDIM]int{Tq, Tv}}
+	var axesA = []int{1}
+	var axesB = []int{1}
+
+	k2c_dot(&scores, query, key, axesA, axesB, 1, 0, fwork[Tq*Tv:])
+	for i := range scores.Array {
+		scores.Array[i] *= scale
+	}
+	k2c_attention_weights(scores.Array, Tq, Tv, nil, value_mask, nil, nil, use_causal_mask)
+	k2c_matmul(output.Array, scores.Array, value.Array, Tq, dim_v, Tv)
+	k2c_attention_query_mask(output, query_mask)
+}
+
+/**
+* Additive attention layer, aka Bahdanau-style attention.
+*
+* :param output: output tensor, shape (Tq, dim_v).
+* :param query: query tensor, shape (Tq, dim).
+* :param value: value tensor, shape (Tv, dim_v).
+* :param key: key tensor, shape (Tv, dim). Pass value if there is no separate key.
+* :param query_mask: Array[Tq] mask of the query, or nil. masked query steps give zero output.
+* :param value_mask: Array[Tv] mask of the value, or nil.
+* :param scale: tensor of shape (dim), scale of each feature in the scores, or nil.
+* :param use_causal_mask: (0,1) whether to prevent the flow of information from the future towards the past.
+* :param fwork: Array of working space, size(fwork) = Tq*Tv.
+*/
+func K2c_additive_attention(output *K2c_tensor, query *K2c_tensor, value *K2c_tensor, key *K2c_tensor, query_mask []bool, value_mask []bool,
+	scale *K2c_tensor, use_causal_mask int, fwork []float64) {
+	var Tq = query.Shape[0]
+	var Tv = value.Shape[0]
+	var dim = query.Shape[1]
+	var dim_v = value.Shape[1]
+	var scores = fwork[:Tq*Tv]
+
+	for i := 0; i < Tq; i++ {
+		for j := 0; j < Tv; j++ {
+			var sum = 0.0
+			for k := 0; k < dim; k++ {
+				var s = math.Tanh(query.Array[i*dim+k] + key.Array[j*dim+k])
+				if scale != nil {
+					s *= scale.Array[k]
+				}
+				sum += s
+			}
+			scores[i*Tv+j] = sum
+		}
+	}
+	k2c_attention_weights(scores, Tq, Tv, nil, value_mask, nil, nil, use_causal_mask)
+	k2c_matmul(output.Array, scores, value.Array, Tq, dim_v, Tv)
+	k2c_attention_query_mask(output, query_mask)
+}
+
+/**
+* Zeros the output rows of masked query steps.
+*
+* :param output: output tensor, shape (Tq, dim_v).
+* :param query_mask: Array[Tq] mask of the query, or nil.
+*/
+func k2c_attention_query_mask(output *K2c_tensor, query_mask []bool) {
+	if query_mask == nil {
+		return
+	}
+	var dim_v = output.Shape[1]
+	for i, keep := range query_mask {
+		if !keep {
+			float64SliceToZero(output.Array[i*dim_v : (i+1)*dim_v])
+		}
+	}
+}
+
+/**
+* Multi-head attention layer.
+* projects query, key and value with one head per slice of the kernels, runs scaled dot-product attention in each
+* head, and projects the concatenated heads back to the output dimension.
+*
+* :param output: output tensor, shape (T, dim_out).
+* :param query: query tensor, shape (T, dim_q).
+* :param value: value tensor, shape (S, dim_v).
+* :param key: key tensor, shape (S, dim_k). Pass value if there is no separate key.
+* :param query_mask: Array[T] mask of the query, or nil.
+* :param value_mask: Array[S] mask of the value, or nil.
+* :param key_mask: Array[S] mask of the key, or nil.
+* :param attention_mask: tensor of shape (T, S), nonzero where a query may attend to a key, or nil.
+* :param use_causal_mask: (0,1) whether to prevent the flow of information from the future towards the past.
+* :param query_kernel: kernel of the query projection, shape (dim_q, num_heads, key_dim).
+* :param query_bias: bias of the query projection, shape (num_heads, key_dim).
+* :param key_kernel: kernel of the key projection, shape (dim_k, num_heads, key_dim).
+* :param key_bias: bias of the key projection, shape (num_heads, key_dim).
+* :param value_kernel: kernel of the value projection, shape (dim_v, num_heads, value_dim).
+* :param value_bias: bias of the value projection, shape (num_heads, value_dim).
+* :param output_kernel: kernel of the output projection, shape (num_heads, value_dim, dim_out).
+* :param output_bias: bias of the output projection, shape (dim_out).
+* :param fwork: Array of working space, size(fwork) = (T+S)*num_heads*key_dim + (T+S)*num_heads*value_dim + T*S.
+*/
+func K2c_multi_head_attention(output *K2c_tensor, query *K2c_tensor, value *K2c_tensor, key *K2c_tensor,
+	query_mask []bool, value_mask []bool, key_mask []bool, attention_mask *K2c_tensor, use_causal_mask int,
+	query_kernel *K2c_tensor, query_bias *K2c_tensor, key_kernel *K2c_tensor, key_bias *K2c_tensor,
+	value_kernel *K2c_tensor, value_bias *K2c_tensor, output_kernel *K2c_tensor, output_bias *K2c_tensor,
+	fwork []float64) {
+	var T = query.Shape[0]
+	var S = value.Shape[0]
+	var num_heads = query_kernel.Shape[1]
+	var key_dim = query_kernel.Shape[2]
+	var value_dim = value_kernel.Shape[2]
+	var dim_out = output.Shape[1]
+	var qk = num_heads * key_dim
+	var vk = num_heads * value_dim
+
+	var q = fwork[:T*qk]
+	var k = fwork[T*qk : (T+S)*qk]
+	var v = fwork[(T+S)*qk : (T+S)*qk+S*vk]
+	var o = fwork[(T+S)*qk+S*vk : (T+S)*qk+(S+T)*vk]
+	var scores = fwork[(T+S)*qk+(S+T)*vk : (T+S)*qk+(S+T)*vk+T*S]
+
+	k2c_affine_matmul(q, query.Array, query_kernel.Array, query_bias.Array, T, qk, query.Shape[1])
+	k2c_affine_matmul(k, key.Array, key_kernel.Array, key_bias.Array, S, qk, key.Shape[1])
+	k2c_affine_matmul(v, value.Array, value_kernel.Array, value_bias.Array, S, vk, value.Shape[1])
+	var scale = 1 / math.Sqrt(float64(key_dim))
+	for i := range q {
+		q[i] *= scale
+	}
+
+	for h := 0; h < num_heads; h++ {
+		for i := 0; i < T; i++ {
+			var qi = q[(i*num_heads+h)*key_dim : (i*num_heads+h+1)*key_dim]
+			for j := 0; j < S; j++ {
+				var kj = k[(j*num_heads+h)*key_dim : (j*num_heads+h+1)*key_dim]
+				var sum = 0.0
+				for d := 0; d < key_dim; d++ {
+					sum += qi[d] * kj[d]
+				}
+				scores[i*S+j] = sum
+			}
+		}
+		k2c_attention_weights(scores, T, S, query_mask, value_mask, key_mask, attention_mask, use_causal_mask)
+		for i := 0; i < T; i++ {
+			var oi = o[(i*num_heads+h)*value_dim : (i*num_heads+h+1)*value_dim]
+			float64SliceToZero(oi)
+			for j := 0; j < S; j++ {
+				var w = scores[i*S+j]
+				var vj = v[(j*num_heads+h)*value_dim : (j*num_heads+h+1)*value_dim]
+				for d := 0; d < value_dim; d++ {
+					oi[d] += w * vj[d]
+				}
+			}
+		}
+	}
+	k2c_affine_matmul(output.Array, o, output_kernel.Array, output_bias.Array, T, dim_out, vk)
+}
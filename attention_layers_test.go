@@ -0,0 +1,84 @@
+package keras2go
+
+import "testing"
+
+// The expected values are the keras formulas evaluated in float64, with masked scores pushed down by 1e9 and
+// uniform weights in rows where every key is masked, as keras float32 gives.
+
+func newAttentionInputs() (query *K2c_tensor, value *K2c_tensor, key *K2c_tensor) {
+	query = newTestTensor([]int{2, 2})
+	query.Array = []float64{1, 0.5, -0.5, 2}
+	value = newTestTensor([]int{3, 2})
+	value.Array = []float64{1, 2, -1, 0.5, 3, -2}
+	key = newTestTensor([]int{3, 2})
+	key.Array = []float64{0.5, -1, 2, 1, -1.5, 0.25}
+	return query, value, key
+}
+
+func TestK2c_attention(t *testing.T) {
+	query, value, key := newAttentionInputs()
+	output := newTestTensor([]int{2, 2})
+	fwork := make([]float64, 6+4+6)
+	tests := []struct {
+		name            string
+		query_mask      []bool
+		value_mask      []bool
+		use_causal_mask int
+		expected        []float64
+	}{
+		{"no mask", nil, nil, 0,
+			[]float64{-0.19663807395106747, 0.5486506236360897, 1.1142875683346651, -0.5890292439550407}},
+		{"value mask", nil, []bool{true, false, true}, 0,
+			[]float64{1.6691788825063718, 0.661642234987256, 2.703905603936621, -1.407811207873242}},
+		{"all values masked", nil, []bool{false, false, false}, 0,
+			[]float64{1, 1.0 / 6, 1, 1.0 / 6}},
+		{"causal and query mask", []bool{true, false}, nil, 1,
+			[]float64{1, 2, 0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			K2c_attention(output, query, value, key, test.query_mask, test.value_mask, 0.5, test.use_causal_mask, fwork)
+			checkArrayTol(t, output.Array, test.expected, 1e-12)
+		})
+	}
+}
+
+func TestK2c_additive_attention(t *testing.T) {
+	query, value, key := newAttentionInputs()
+	output := newTestTensor([]int{2, 2})
+	scale := newTestTensor([]int{2})
+	scale.Array = []float64{1.5, -0.5}
+	K2c_additive_attention(output, query, value, key, nil, []bool{false, true, true}, scale, 0, make([]float64, 6))
+	checkArrayTol(t, output.Array, []float64{-0.544096839161097, 0.21506052447568566, -0.7696742872245589,
+		0.3560464295153493}, 1e-12)
+}
+
+func TestK2c_multi_head_attention(t *testing.T) {
+	// 2 heads of size 1, the second query may not attend to any key
+	query, value, key := newAttentionInputs()
+	output := newTestTensor([]int{2, 2})
+	attention_mask := newTestTensor([]int{2, 3})
+	attention_mask.Array = []float64{1, 0, 1, 0, 0, 0}
+	query_kernel := newTestTensor([]int{2, 2, 1})
+	query_kernel.Array = []float64{0.5, -1, 1, 0.25}
+	query_bias := newTestTensor([]int{2, 1})
+	query_bias.Array = []float64{0.1, -0.2}
+	key_kernel := newTestTensor([]int{2, 2, 1})
+	key_kernel.Array = []float64{1, 0.5, -0.5, 1.5}
+	key_bias := newTestTensor([]int{2, 1})
+	key_bias.Array = []float64{0, 0.3}
+	value_kernel := newTestTensor([]int{2, 2, 1})
+	value_kernel.Array = []float64{0.25, 1, 1, -0.5}
+	value_bias := newTestTensor([]int{2, 1})
+	value_bias.Array = []float64{0.5, 0}
+	output_kernel := newTestTensor([]int{2, 1, 2})
+	output_kernel.Array = []float64{1, -1, 0.5, 2}
+	output_bias := newTestTensor([]int{2})
+	output_bias.Array = []float64{0.05, -0.1}
+	fwork := make([]float64, 5*2+5*2+6)
+
+	K2c_multi_head_attention(output, query, value, key, nil, nil, nil, attention_mask, 0, query_kernel, query_bias,
+		key_kernel, key_bias, value_kernel, value_bias, output_kernel, output_bias, fwork)
+	checkArrayTol(t, output.Array, []float64{3.1768358894030095, -0.4190947615458488, 1.4249999999999998,
+		0.8166666666666668}, 1e-12)
+}
@@ -5,7 +5,7 @@ import "math"
 
 var _ = math.MaxInt8
 
-func Example(input_1_input *keras2go.K2c_tensor, dense_3_output *keras2go.K2c_tensor) {
+func Example(input_1_input *keras2go.K2c_tensor, dense_3_output *keras2go.K2c_tensor) error {
 
 	var dense_1_output_array = make([]float64, 160)
 	var dense_1_output = keras2go.K2c_tensor{dense_1_output_array, 2, 160, [5]int{8, 20, 1, 1, 1}}
@@ -1053,11 +1053,12 @@ func Example(input_1_input *keras2go.K2c_tensor, dense_3_output *keras2go.K2c_te
 		&dense_1_bias, keras2go.K2c_relu, dense_1_fwork)
 	keras2go.K2c_dense(&dense_2_output, &dense_1_output, &dense_2_kernel,
 		&dense_2_bias, keras2go.K2c_relu, dense_2_fwork)
-	keras2go.K2c_lstm(&lstm_1_output, &dense_2_output, lstm_1_state, &lstm_1_kernel,
+	keras2go.K2c_lstm(&lstm_1_output, &dense_2_output, nil, lstm_1_state, &lstm_1_kernel,
 		&lstm_1_recurrent_kernel, &lstm_1_bias, lstm_1_fwork,
 		lstm_1_go_backwards, lstm_1_return_sequences,
 		keras2go.K2c_hard_sigmoid, keras2go.K2c_relu)
 	keras2go.K2c_dense(dense_3_output, &lstm_1_output, &dense_3_kernel,
 		&dense_3_bias, keras2go.K2c_linear, dense_3_fwork)
 
+	return nil
 }
@@ -220,9 +220,15 @@ func TestFn_Example(t *testing.T) {
 	var num_tests = 3
 	var num_outputs = 1
 	var t0 = time.Now()
-	Example(&test1_input_1_input, &c_dense_3_test1)
-	Example(&test2_input_1_input, &c_dense_3_test2)
-	Example(&test3_input_1_input, &c_dense_3_test3)
+	if err := Example(&test1_input_1_input, &c_dense_3_test1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Example(&test2_input_1_input, &c_dense_3_test2); err != nil {
+		t.Fatal(err)
+	}
+	if err := Example(&test3_input_1_input, &c_dense_3_test3); err != nil {
+		t.Fatal(err)
+	}
 
 	var t1 = time.Now()
 	fmt.Println("Average time over 3 tests: ", strconv.FormatFloat(t1.Sub(t0).Seconds(), 'f', 5, 64), "s")
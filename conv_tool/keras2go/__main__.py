@@ -31,6 +31,8 @@ def parse_args(args):
     parser.add_argument("-p", "--package_name", help="What to name the resulting Go package")
     parser.add_argument("-t", "--num_tests", type=int,
                         help="""Number of tests to generate. Default is 10""")
+    parser.add_argument("-o", "--embedding_oov", choices=['error', 'zero', 'bucket'], default='error',
+                        help="""Handling of out of vocabulary indices in Embedding layers. Default is error""")
 
     return parser.parse_args(args)
 
@@ -43,7 +45,8 @@ def main(args=sys.argv[1:]):
     else:
         num_tests = 10
 
-    k2c(args.model_path, args.function_name, args.package_name, num_tests)
+    k2c(args.model_path, args.function_name, args.package_name, num_tests,
+        embedding_oov=args.embedding_oov)
 
 
 if __name__ == '__main__':
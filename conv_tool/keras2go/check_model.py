@@ -9,9 +9,12 @@ Checks a model before conversion to flag unsupported features
 
 # imports
 import numpy as np
-from keras2go.io_parsing import layer_type, flatten
+from keras2go.io_parsing import layer_type, flatten, get_layer_num_io, \
+    get_call_args, is_custom_layer
 from keras2go.weights2go import Weights2C
-from keras2go.layer2c import Layers2C
+from keras2go.layer2c import Layers2C, activation2go
+from keras2go.tf_ops import op_kind, op_args, is_keras_tensor, go_axes, \
+    constant_operand, slice_spec, resolve_slice, go_equation
 import tensorflow as tf
 tf.compat.v1.disable_eager_execution()
 
@@ -78,12 +81,40 @@ def layers_supported_check(model):
             flag, templog = check_layer(layer.layer)
             valid = valid and flag
             log += templog
+        if is_custom_layer(layer):
+            # run by the go implementation, see K2c_register_layer
+            if isinstance(layer.output, list):
+                valid = False
+                log += "custom layer '" + layer.name + \
+                       "' with several outputs is not supported at this time. \n"
+            return valid, log
         if not hasattr(Weights2C, '_write_weights_' + layer_type(layer)) \
            or not hasattr(Layers2C, '_write_layer_' + layer_type(layer)):
             valid = False
             log += layer_type(layer) + "' is not supported at this time. \n"
+        if layer_type(layer) == 'RNN':
+            flag, templog = check_cell(layer.cell)
+            valid = valid and flag
+            log += templog
         return valid, log
 
+    def check_cell(cell):
+        # only the keras cells are converted, custom cells can only be run
+        # from go, see K2c_rnn_cell
+        if layer_type(cell) == 'StackedRNNCells':
+            valid = True
+            log = ''
+            for c in cell.cells:
+                flag, templog = check_cell(c)
+                valid = valid and flag
+                log += templog
+            return valid, log
+        if not hasattr(Weights2C, '_write_cell_weights_' + layer_type(cell)):
+            return False, "cell '" + layer_type(cell) + \
+                "' is not supported at this time, write it in go as a " + \
+                "K2c_rnn_cell and run it with K2c_rnn. \n"
+        return True, ''
+
     valid = True
     log = ''
     for layer in model.layers:
@@ -93,21 +124,19 @@ def layers_supported_check(model):
     return valid, log
 
 
-def activation_supported_check(model):
+def activation_supported_check(model, custom_objects=None):
     """Checks if all activation functions in the model are supported
 
     Args:
        model (keras Model): model to check
+       custom_objects (dict): custom objects given to the converter, whose
+           activations are registered with K2c_register_activation
 
     Returns:
         valid (bool): 'True' if all activations are supported, 'False' otherwise
         log (str): log of unsupported activation functions
     """
 
-    supported_activations = ['linear', 'relu', 'softmax', 'softplus',
-                             'softsign', 'relu', 'tanh', 'sigmoid',
-                             'hard_sigmoid', 'exponential']
-
     def check_layer(layer):
         valid = True
         log = ''
@@ -115,18 +144,26 @@ def activation_supported_check(model):
             flag, templog = check_layer(layer.layer)
             valid = valid and flag
             log += templog
+        if layer_type(layer) == 'RNN':
+            for cell in getattr(layer.cell, 'cells', [layer.cell]):
+                flag, templog = check_layer(cell)
+                valid = valid and flag
+                log += templog
+        if is_custom_layer(layer):
+            return valid, log
         activation = layer.get_config().get('activation')
         recurrent_activation = layer.get_config().get('recurrent_activation')
-        if activation not in supported_activations and activation is not None:
+        if activation is not None and \
+           activation2go(activation, custom_objects) is None:
             valid = False
-            log += "activation type '" + layer.get_config()['activation'] + \
+            log += "activation type '" + str(activation) + \
                    "' for layer '" + layer.name + \
                    "' is not supported at this time. \n"
-        if recurrent_activation not in supported_activations and \
-           recurrent_activation is not None:
+        if recurrent_activation is not None and \
+           activation2go(recurrent_activation, custom_objects) is None:
             valid = False
             log += "recurrent activation type '" + \
-                   layer.get_config()['recurrent_activation'] + \
+                   str(recurrent_activation) + \
                    "' for layer '" + layer.name + \
                    "' is not supported at this time. \n"
         return valid, log
@@ -139,7 +176,47 @@ def activation_supported_check(model):
         log += templog
     return valid, log
 
-# add check for masking
+def masking_supported_check(model):
+    """Checks if all masks in the model are handled
+
+    Masks are carried between layers that pass them through, and used by
+    recurrent layers, global pooling and merge layers. Layers that would
+    use a mask in a way that is not implemented are flagged.
+
+    Args:
+       model (keras Model): model to check
+
+    Returns:
+        valid (bool): 'True' if all masks are supported, 'False' otherwise
+        log (str): log of unsupported masks
+    """
+
+    unsupported = ['TimeDistributed', 'ConvLSTM2D']
+
+    def is_masked(layer):
+        num_inputs, _ = get_layer_num_io(layer)
+        for i in range(num_inputs):
+            for inp in flatten(layer.get_input_at(i)):
+                if getattr(inp, '_keras_mask', None) is not None:
+                    return True
+        return False
+
+    valid = True
+    log = ''
+    for layer in model.layers:
+        if layer_type(layer) == 'InputLayer' or not is_masked(layer):
+            continue
+        if layer_type(layer) in unsupported:
+            valid = False
+            log += "masked input to layer '" + layer.name + \
+                   "' is not supported at this time. \n"
+        rnn = getattr(layer, 'forward_layer', layer)
+        if getattr(rnn, 'zero_output_for_mask', False) and \
+           getattr(rnn, 'return_sequences', False):
+            valid = False
+            log += "zero output for masked timesteps in layer '" + \
+                   layer.name + "' is not supported at this time. \n"
+    return valid, log
 
 
 def config_supported_check(model):
@@ -160,19 +237,24 @@ def config_supported_check(model):
             flag, templog = check_layer(layer.layer)
             valid = valid and flag
             log += templog
-        config = layer.get_config()
-        if config.get('merge_mode', 'foo') is None:
+        if is_custom_layer(layer):
+            return valid, log
+        input_shapes = set(str(layer.get_input_shape_at(i))
+                           for i in range(len(layer.inbound_nodes)))
+        if len(input_shapes) > 1:
             valid = False
-            log += "merge mode of 'None' for Bidirectional layers is not " +\
-                   "supported. Try using two seperate RNNs instead"
-        if config.get('data_format') not in ['channels_last', None]:
+            log += "calls of shared layer '" + layer.name + \
+                   "' with different input shapes are not supported at this time. \n"
+        config = layer.get_config()
+        if config.get('data_format') not in ['channels_last', None] and \
+           'Pooling' not in layer_type(layer):
             valid = False
             log += "data format '" + layer.get_config()['data_format'] +\
                    "' for layer '" + layer.name + \
                    "' is not supported at this time. \n"
-        if config.get('return_state'):
+        if hasattr(layer, 'layer') and layer.layer.get_config().get('return_state'):
             valid = False
-            log += "'return_state' option for layer '" + layer.name + \
+            log += "'return_state' option for wrapped layer '" + layer.name + \
                    "' is not supported at this time. \n"
         if config.get('shared_axes'):
             valid = False
@@ -180,13 +262,112 @@ def config_supported_check(model):
                    "' is not supported at this time. \n"
         if layer_type(layer) in ['Add', 'Subtract', 'Multiply', 'Average',
                                  'Maximum', 'Minimum']:
-            inshps = layer.input_shape
-            insize = [np.prod(inp[1:]) for inp in inshps]
-            if len(set(insize)) > 1:
+            # inputs are aligned on their last dimension, like numpy
+            inshps = [inp[1:] for inp in layer.input_shape]
+            ndim = max(len(shp) for shp in inshps)
+            inshps = [(1,)*(ndim - len(shp)) + tuple(shp) for shp in inshps]
+            for dims in zip(*inshps):
+                if len(set(dims) - {1}) > 1:
+                    valid = False
+                    log += "inputs of layer '" + layer.name + \
+                           "' can not be broadcast together. \n"
+                    break
+        if layer_type(layer) in ['LocallyConnected1D', 'LocallyConnected2D']:
+            if config.get('padding') != 'valid':
                 valid = False
-                log += "broadcasting merge functions between tensors" + \
-                       " of different shapes for layer '" + \
-                       layer.name + "' is not currently supported. \n"
+                log += "padding '" + config.get('padding') + \
+                       "' for layer '" + layer.name + \
+                       "' is not supported at this time. \n"
+        if layer_type(layer) in ['Attention', 'AdditiveAttention',
+                                 'MultiHeadAttention']:
+            num_inputs, _ = get_layer_num_io(layer)
+            for i in range(num_inputs):
+                if get_call_args(layer, i, []).get('return_attention_scores'):
+                    valid = False
+                    log += "returning attention scores from layer '" + \
+                           layer.name + "' is not supported at this time. \n"
+        if config.get('score_mode', 'dot') != 'dot':
+            valid = False
+            log += "score mode '" + config.get('score_mode') + \
+                   "' for layer '" + layer.name + \
+                   "' is not supported at this time. \n"
+        if config.get('interpolation', 'nearest') not in \
+           ['nearest', 'bilinear', 'area', 'bicubic']:
+            valid = False
+            log += "interpolation '" + config.get('interpolation') + \
+                   "' for layer '" + layer.name + \
+                   "' is not supported at this time. \n"
+        if config.get('pad_to_aspect_ratio'):
+            valid = False
+            log += "padding to aspect ratio in layer '" + layer.name + \
+                   "' is not supported at this time. \n"
+        if layer_type(layer) in ['Resizing', 'CenterCrop'] and \
+           len(layer.input_shape) != 4:
+            valid = False
+            log += "batches of images with more than one batch axis in " + \
+                   "layer '" + layer.name + "' are not supported at this time. \n"
+        if layer_type(layer) == 'Normalization' and \
+           None in Weights2C._normalization_shape(layer):
+            valid = False
+            log += "layer '" + layer.name + "' normalizes inputs with " + \
+                   "axes of unknown size after the normalized axes. \n"
+        if layer_type(layer) in ['StringLookup', 'IntegerLookup'] and \
+           config.get('invert'):
+            valid = False
+            log += "inverted lookup in layer '" + layer.name + \
+                   "' is not supported at this time. \n"
+        if config.get('sparse') or config.get('ragged'):
+            valid = False
+            log += "sparse or ragged outputs of layer '" + layer.name + \
+                   "' are not supported at this time. \n"
+        if layer_type(layer) in ['StringLookup', 'Hashing', 'TextVectorization'] \
+           and layer.input.dtype == tf.string and \
+           not any(layer.input is inp for inp in model.inputs):
+            # strings only exist as model inputs in the generated code
+            valid = False
+            log += "string input of layer '" + layer.name + \
+                   "' must be a model input. \n"
+        if layer_type(layer) == 'CategoryEncoding' and \
+           get_call_args(layer, 0, ['inputs', 'count_weights']).get(
+               'count_weights') is not None:
+            valid = False
+            log += "count weights for layer '" + layer.name + \
+                   "' are not supported at this time. \n"
+        if layer_type(layer) == 'TextVectorization':
+            if config.get('standardize') not in [None, 'lower', 'strip_punctuation',
+                                                 'lower_and_strip_punctuation'] or \
+               config.get('split') not in [None, 'whitespace', 'character']:
+                valid = False
+                log += "custom standardize or split in layer '" + layer.name + \
+                       "' is not supported at this time. \n"
+            if config.get('output_mode') == 'int' and \
+               config.get('output_sequence_length') is None:
+                valid = False
+                log += "layer '" + layer.name + "' needs an output sequence " + \
+                       "length in output mode 'int'. \n"
+        if layer_type(layer) in ['TFOpLambda', 'SlicingOpLambda']:
+            flag, templog = check_tf_op(layer)
+            valid = valid and flag
+            log += templog
+        if layer_type(layer) == 'EinsumDense':
+            try:
+                go_equation(config['equation'], [True, False])
+            except ValueError as e:
+                valid = False
+                log += "equation of layer '" + layer.name + \
+                       "' is not supported at this time: " + str(e) + ". \n"
+        if layer_type(layer) == 'Softmax' and \
+           len(flatten(config.get('axis'))) > 1:
+            valid = False
+            log += "softmax over multiple axes in layer '" + layer.name + \
+                   "' is not supported at this time. \n"
+        if layer_type(layer) == 'MultiHeadAttention':
+            query = get_call_args(layer, 0, ['query'])['query']
+            if len(query.shape) != 3 or \
+               config.get('attention_axes') not in [None, [1], (1,)]:
+                valid = False
+                log += "attention over more than one axis in layer '" + \
+                       layer.name + "' is not supported at this time. \n"
         if layer_type(layer) in ['BatchNormalizationV1', 'BatchNormalization']:
             if len(flatten(config.get('axis'))) > 1:
                 valid = False
@@ -194,6 +375,49 @@ def config_supported_check(model):
                        ' not currently supported. \n'
         return valid, log
 
+    def check_tf_op(layer):
+        kind = op_kind(layer)
+        symbol = layer.get_config()['function']
+        if kind is None:
+            return False, "tf function '" + symbol + "' in layer '" + \
+                layer.name + "' is not supported at this time. \n"
+        args = op_args(layer)
+        rank = len(layer.get_output_at(0).shape)
+        try:
+            if kind in ['binary', 'where']:
+                if kind == 'where' and (args.get('x') is None or args.get('y') is None):
+                    raise ValueError('tf.where without x and y')
+                for arg in ['condition', 'x', 'y']:
+                    if arg in args and not is_keras_tensor(args[arg]):
+                        constant_operand(args[arg], rank)
+            elif kind == 'reduce':
+                if args.get('axis') is None:
+                    raise ValueError('reduction over all axes')
+                go_axes(args['axis'], len(args['input_tensor'].shape))
+            elif kind in ['getitem', 'strided_slice', 'slice']:
+                tensor, spec = slice_spec(layer)
+                resolve_slice(tuple(tensor.shape), spec)
+            elif kind == 'gather':
+                if args.get('batch_dims'):
+                    raise ValueError('gather with batch dims')
+                if is_keras_tensor(args['params']):
+                    if is_keras_tensor(args['indices']):
+                        raise ValueError('gather of a tensor by a tensor')
+                    go_axes(args.get('axis') or 0, len(args['params'].shape))
+            elif kind == 'einsum':
+                operands = [args[arg] for arg in ['a', 'b'] if arg in args]
+                if len(args) > 3 or not isinstance(args['equation'], str):
+                    raise ValueError('einsum of more than two operands')
+                go_equation(args['equation'], [is_keras_tensor(op)
+                                               for op in operands])
+            elif kind in ['squeeze', 'expand_dims'] and args.get('axis') is not None:
+                go_axes(args['axis'], rank if kind == 'expand_dims' else
+                        len(args['input'].shape))
+        except ValueError as e:
+            return False, "tf function '" + symbol + "' in layer '" + \
+                layer.name + "' is not supported at this time: " + str(e) + ". \n"
+        return True, ''
+
     valid = True
     log = ''
     for layer in model.layers:
@@ -203,12 +427,13 @@ def config_supported_check(model):
     return valid, log
 
 
-def check_model(model, function_name):
+def check_model(model, function_name, custom_objects=None):
     """Checks if all names are valid and all features are supported
 
     Args:
         model (keras Model): model to check
         function_name (str): name of the function being created
+        custom_objects (dict): custom objects given to the converter
 
     Raises:
         AssertionError: If model contains invalid names or unsupported features
@@ -223,10 +448,13 @@ def check_model(model, function_name):
     log += name_log
     valid_layer, layer_log = layers_supported_check(model)
     log += layer_log
-    valid_activation, activation_log = activation_supported_check(model)
+    valid_activation, activation_log = activation_supported_check(
+        model, custom_objects)
     log += activation_log
     valid_config, config_log = config_supported_check(model)
     log += config_log
+    valid_masking, masking_log = masking_supported_check(model)
+    log += masking_log
     if not (valid_fname and valid_lname and valid_layer and
-            valid_activation and valid_config):
+            valid_activation and valid_config and valid_masking):
         raise AssertionError(log)
@@ -27,6 +27,61 @@ def layer_type(layer):
     return layer.__class__.__name__
 
 
+def is_custom_layer(layer):
+    """Checks if a layer is implemented outside of keras
+
+    Lambda layers and subclasses of Layer are run by the go implementation
+    registered with K2c_register_layer.
+
+    Args:
+        layer (keras Layer): layer you want to check
+
+    Returns:
+        custom (bool): 'True' if the layer is a Lambda or a custom layer
+    """
+
+    if layer_type(layer) == 'Lambda':
+        return True
+    module = layer.__class__.__module__
+    return not module.startswith(('keras.', 'tensorflow.', 'tf_keras.'))
+
+
+def custom_layer_name(layer):
+    """Gets the name a custom layer is registered with
+
+    Args:
+        layer (keras Layer): Lambda or custom layer
+
+    Returns:
+        name (str): class name of a custom layer, function name of a
+            Lambda layer, or layer name of a Lambda of an anonymous function
+    """
+
+    if layer_type(layer) != 'Lambda':
+        return layer_type(layer)
+    function = getattr(layer, 'function', None)
+    name = getattr(function, '__name__', '<lambda>')
+    if name == '<lambda>':
+        return layer.name
+    return name
+
+
+def get_layer_config(layer):
+    """Gets the config of a layer, or an empty config if it has none
+
+    Args:
+        layer (keras Layer): layer you want the config of
+
+    Returns:
+        config (dict): config of the layer
+    """
+
+    try:
+        return layer.get_config()
+    except NotImplementedError:
+        return {}
+
+
 def get_all_io_names(model):
     """Gets names of all  node names in the model
 
@@ -41,6 +96,48 @@ def get_all_io_names(model):
     return list(set(flatten(a)))
 
 
+def get_node_name(layer, node_index=0):
+    """Gets the name of one call of a layer
+
+    The buffers of the first call of a layer are named after the layer, the
+    later calls of a shared layer get the suffix '_node<index>'.
+
+    Args:
+        layer (keras Layer): layer you want to parse
+        node_index (int): which call of the layer
+
+    Returns:
+        name (str): name of the call
+    """
+
+    if node_index == 0:
+        return layer.name
+    return layer.name + '_node' + str(node_index)
+
+
+def get_tensor_name(tensor):
+    """Gets the name of a tensor
+
+    Tensors are named after the call of the layer that made them, see
+    get_node_name. Outputs of a call after the first one get the suffix
+    '_out<index>', so that all outputs of a layer have distinct names.
+
+    Args:
+        tensor (keras Tensor): tensor you want the name of
+
+    Returns:
+        name (str): name of the tensor
+    """
+
+    history = getattr(tensor, '_keras_history', None)
+    if history is None:
+        return tensor.name.split(':')[0].split('/')[0]
+    name = get_node_name(history[0], history[1])
+    if history[2] > 0:
+        name += '_out' + str(history[2])
+    return name
+
+
 def get_layer_num_io(layer):
     """Gets the number of inputs and outputs for a layer
 
@@ -87,17 +184,19 @@ def get_layer_io_names(layer):
     inputs = []
     # num_inputs>1 -> shared layer
     for i in range(num_inputs):
+        if layer_type(layer) == 'MultiHeadAttention':
+            inputs.insert(i, get_attention_input_names(layer, i))
+            continue
         # is the input a list?
         if isinstance(layer.get_input_at(i), list):
             temp_list = []
             list_length = len(layer.get_input_at(i))
             for j in range(list_length):
-                name = layer.get_input_at(i)[j].name.split(':')[
-                    0].split('/')[0]
+                name = get_tensor_name(layer.get_input_at(i)[j])
                 temp_list.append(name)
             inputs.insert(i, temp_list)
         else:
-            name = layer.get_input_at(i).name.split(':')[0].split('/')[0]
+            name = get_tensor_name(layer.get_input_at(i))
             inputs.insert(i, name)
 
     outputs = []
@@ -107,21 +206,77 @@ def get_layer_io_names(layer):
             temp_list = []
             list_length = len(layer.get_output_at(i))
             for j in range(list_length):
-                name = layer.get_output_at(i)[j].name.split(':')[
-                    0].split('/')[0]
+                name = get_tensor_name(layer.get_output_at(i)[j])
                 temp_list.append(name)
             outputs.insert(i, temp_list)
         else:
-            name = layer.get_output_at(i).name
-            if 'bidirectional' in name.lower():
-                name = name.split('/')[-2]
-            else:
-                name = name.split('/')[0]
+            name = get_tensor_name(layer.get_output_at(i))
             outputs.insert(i, name)
 
     return inputs, outputs
 
 
+def get_call_args(layer, node_index, arg_names):
+    """Gets the arguments a layer was called with
+
+    Args:
+        layer (keras Layer): layer you want to parse
+        node_index (int): which call of the layer to parse
+        arg_names (list): names of the positional arguments of the call
+
+    Returns:
+        args (dict): arguments of the call, by name
+    """
+
+    node = layer.inbound_nodes[node_index]
+    args = dict(zip(arg_names, node.call_args))
+    args.update(node.call_kwargs)
+    return args
+
+
+def get_attention_input_names(layer, node_index=0):
+    """Gets the names of the inputs of a MultiHeadAttention call
+
+    query and value are the first positional arguments, key and
+    attention_mask are optional.
+
+    Args:
+        layer (keras Layer): layer you want to parse
+        node_index (int): which call of the layer to parse
+
+    Returns:
+        inputs (list): names of query, value and key, followed by the
+            attention mask if there is one. key is the value if it was
+            not given
+    """
+
+    args = get_call_args(layer, node_index,
+                         ['query', 'value', 'key', 'attention_mask'])
+    if args.get('key') is None:
+        args['key'] = args['value']
+    names = [get_tensor_name(args[k]) for k in ['query', 'value', 'key']]
+    if args.get('attention_mask') is not None:
+        names.append(get_tensor_name(args['attention_mask']))
+    return names
+
+
+def has_mask(layer, node_index=0):
+    """Checks if an output of a layer carries a mask
+
+    Args:
+        layer (keras Layer): layer you want to check
+        node_index (int): which call of the layer to check
+
+    Returns:
+        masked (bool): 'True' if the output has a mask, 'False' otherwise
+    """
+
+    output = layer.get_output_at(node_index)
+    if isinstance(output, list):
+        output = output[0]
+    return getattr(output, '_keras_mask', None) is not None
+
+
 def get_model_io_names(model):
     """Gets names of the input and output nodes of the model
 
@@ -138,14 +293,65 @@ def get_model_io_names(model):
     inputs = []
     outputs = []
     for i in range(num_inputs):
-        nm = model.inputs[i].name.split(':')[0].split('/')[0]
+        nm = get_tensor_name(model.inputs[i])
         inputs.append(nm)
     for i in range(num_outputs):
-        nm = model.outputs[i].name.split(':')[0].split('/')[0]
+        nm = get_tensor_name(model.outputs[i])
         outputs.append(nm)
     return inputs, outputs
 
 
+def get_consumers(model, name):
+    """Gets the calls of layers that read a node
+
+    Args:
+        model (keras Model): model to parse
+        name (str): name of the node
+
+    Returns:
+        consumers (list): (layer, node_index) of each call reading the node
+    """
+
+    consumers = []
+    for layer in model.layers:
+        if layer_type(layer) == 'InputLayer':
+            continue
+        inputs, _ = get_layer_io_names(layer)
+        for i, inp in enumerate(inputs):
+            if name in flatten(inp):
+                consumers.append((layer, i))
+    return consumers
+
+
+def get_input_types(model):
+    """Gets the go types of the model inputs
+
+    String inputs, eg the text of a TextVectorization, are plain slices of
+    strings, and integer inputs only read by Embedding layers are plain
+    slices of indices. The other inputs are tensors.
+
+    Args:
+        model (keras Model): model to parse
+
+    Returns:
+        types (list): go type of each model input
+    """
+
+    model_inputs, model_outputs = get_model_io_names(model)
+    types = []
+    for name, inp in zip(model_inputs, model.inputs):
+        consumers = get_consumers(model, name)
+        if inp.dtype.name == 'string':
+            types.append('[]string')
+        elif inp.dtype.is_integer and name not in model_outputs and \
+                len(consumers) > 0 and \
+                all(layer_type(layer) == 'Embedding' for layer, _ in consumers):
+            types.append('[]int')
+        else:
+            types.append('*keras2go.K2c_tensor')
+    return types
+
+
 def flatten(x):
     """Flattens a nested list or tuple
 
@@ -159,3 +365,38 @@ def flatten(x):
         return [a for i in x for a in flatten(i)]
     else:
         return [x]
+
+
+def go_string(s):
+    """Quotes a string as a go string literal
+
+    Undecodable bytes, which python keeps as lone surrogates, are written as
+    the bytes they stand for.
+
+    Args:
+        s (str or bytes): string to quote
+
+    Returns:
+        literal (str): go string literal
+    """
+    if isinstance(s, bytes):
+        s = s.decode('utf-8', 'surrogateescape')
+    escapes = {'\\': '\\\\', '"': '\\"', '\n': '\\n', '\r': '\\r', '\t': '\\t'}
+    literal = '"'
+    for c in str(s):
+        if c in escapes:
+            literal += escapes[c]
+        elif 0xdc80 <= ord(c) <= 0xdcff:
+            literal += '\\x%02x' % (ord(c) - 0xdc00)
+        elif 0xd800 <= ord(c) <= 0xdfff:
+            literal += ''.join('\\x%02x' % b
+                               for b in c.encode('utf-8', 'surrogatepass'))
+        elif c.isprintable():
+            literal += c
+        elif ord(c) < 0x80:
+            literal += '\\x%02x' % ord(c)
+        elif ord(c) <= 0xffff:
+            literal += '\\u%04x' % ord(c)
+        else:
+            literal += '\\U%08x' % ord(c)
+    return literal + '"'
@@ -11,8 +11,9 @@ Converts keras model to C code
 from keras2go.layer2c import Layers2C
 from keras2go.weights2go import Weights2C
 from keras2go.io_parsing import layer_type, get_all_io_names, get_layer_io_names, \
-    get_model_io_names, flatten
+    get_model_io_names, flatten, get_input_types
 from keras2go.check_model import check_model
+from keras2go.submodels import inline_submodels
 from keras2go.make_test_suite import make_test_suite
 import numpy as np
 import subprocess
@@ -28,7 +29,8 @@ __maintainer__ = "Rory Conlin, https://github.com/f0uriest/keras2go"
 __email__ = "wconlin@princeton.edu"
 
 
-def model2c(model, function_name, package_name, verbose=True):
+def model2c(model, function_name, package_name, verbose=True, embedding_oov='error',
+            custom_objects=None):
     """Generates C code for model
 
     Writes main function definition to "function_name.c" and a public header 
@@ -37,6 +39,9 @@ def model2c(model, function_name, package_name, verbose=True):
         model (keras Model): model to convert
         function_name (str): name of C function
         verbose (bool): whether to print info to stdout
+        embedding_oov (str): handling of out of vocabulary indices in
+            Embedding layers, one of 'error', 'zero' or 'bucket'
+        custom_objects (dict): custom layers and functions of the model
 
     Returns:
         stateful (bool): whether the model must maintain state between calls
@@ -46,19 +51,22 @@ def model2c(model, function_name, package_name, verbose=True):
 
     if verbose:
         print('Gathering Weights')
-    stack_vars, static_vars = Weights2C(
-        model, function_name).write_weights(verbose)
-    stateful = len(static_vars) > 0
-    layers = Layers2C(model).write_layers(verbose)
-
+    weights = Weights2C(model, function_name, embedding_oov)
+    stack_vars, static_vars = weights.write_weights(verbose)
+    stateful = len(weights.static_vars) > 0
+    layers_writer = Layers2C(model, function_name, embedding_oov, custom_objects)
+    layers = layers_writer.write_layers(verbose)
+
+    # string inputs and indices of Embedding layers are plain slices
+    input_types = get_input_types(model)
     function_signature = 'func ' + function_name + '('
-    function_signature += ', '.join(['' +
-                                     in_nm + '_input *keras2go.K2c_tensor' for in_nm in model_inputs]) + ', '
+    if stateful:
+        function_signature += 'states keras2go.K2c_states, '
+    function_signature += ', '.join([in_nm + '_input ' + in_type for in_nm, in_type
+                                     in zip(model_inputs, input_types)]) + ', '
     function_signature += ', '.join(['' +
                                      out_nm + '_output *keras2go.K2c_tensor' for out_nm in model_outputs])
-    function_signature += ')'
-
-    reset_sig, reset_fun = gen_function_reset(function_name)
+    function_signature += ') error'
 
     with open(function_name + '.go', 'x+') as source:
         source.write('package '+package_name+'\n\n')
@@ -66,42 +74,18 @@ def model2c(model, function_name, package_name, verbose=True):
         source.write('import "math"\n')
         source.write('\n')
         source.write('var _ = math.MaxInt8\n')
-        source.write(static_vars + '\n\n')
+        source.write(static_vars + layers_writer.package_vars + '\n\n')
         source.write(function_signature)
         source.write(' { \n\n')
         source.write(stack_vars)
         source.write(layers)
-        source.write('\n } \n\n')
-        if stateful:
-            source.write(reset_fun)
+        source.write('\nreturn nil\n } \n\n')
 
     return stateful
 
 
-def gen_function_reset(function_name):
-    """Writes a reset function for stateful models
-
-    Reset function is used to clear internal state of the model
-
-    Args:
-        function_name (str): name of main function
-
-    Returns:
-       signature (str): delcaration of the reset function
-       function (str): definition of the reset function
-    """
-
-    reset_sig = 'func ' + function_name + '_reset_states()'
-
-    reset_fun = reset_sig
-    reset_fun += ' { \n\n'
-    reset_fun += 'memset(&' + function_name + \
-                 '_states,0,sizeof(' + function_name + '_states)); \n'
-    reset_fun += "} \n\n"
-    return reset_sig, reset_fun
-
-
-def k2c(model, function_name, package_name, num_tests=10, verbose=True):
+def k2c(model, function_name, package_name, num_tests=10, verbose=True, embedding_oov='error',
+        custom_objects=None):
     """Converts keras model to C code and generates test suite
 
     Args:
@@ -110,6 +94,13 @@ def k2c(model, function_name, package_name, num_tests=10, verbose=True):
         malloc (bool): whether to allocate variables on the stack or heap
         num_tests (int): how many tests to generate in the test suite
         verbose (bool): whether to print progress
+        embedding_oov (str): handling of out of vocabulary indices in
+            Embedding layers: 'error' returns an error like keras raises,
+            'zero' looks up a zero vector, 'bucket' looks up a dedicated OOV
+            row appended to the kernel, which is zero until edited
+        custom_objects (dict): custom layers and functions needed to load the
+            model from a file. Their go implementation is registered with
+            K2c_register_layer and K2c_register_activation
 
     Raises:
         ValueError: if model is not instance of keras.models.Model 
@@ -121,20 +112,24 @@ def k2c(model, function_name, package_name, num_tests=10, verbose=True):
     function_name = str(function_name)
     filename = function_name + '.c'
     if isinstance(model, str):
-        model = keras.models.load_model(model, compile=False)
+        model = keras.models.load_model(
+            model, custom_objects=custom_objects, compile=False)
     elif not isinstance(model, keras.models.Model):
 
         raise ValueError('Unknown model type. Model should ' +
                          'either be an instance of keras.models.Model, ' +
                          'or a filepath to a saved .h5 model')
 
+    # layers of nested models are converted like the other layers
+    model = inline_submodels(model, custom_objects)
+
     # check that the model can be converted
-    check_model(model, function_name)
+    check_model(model, function_name, custom_objects)
     if verbose:
         print('All checks passed')
 
     stateful = model2c(
-        model, function_name, package_name, verbose)
+        model, function_name, package_name, verbose, embedding_oov, custom_objects)
 
     s = 'Done \n'
     s += "Go code is in '" + function_name + ".go'\n"
@@ -8,7 +8,12 @@ Writes individual layers to C code
 """
 
 # imports
-from keras2go.io_parsing import layer_type, get_model_io_names, get_all_io_names, get_layer_io_names, flatten
+from keras2go.io_parsing import layer_type, get_model_io_names, get_all_io_names, get_layer_io_names, \
+    flatten, has_mask, get_call_args, is_custom_layer, get_node_name, get_layer_num_io, \
+    go_string, get_input_types
+from keras2go.tf_ops import UNARY_OPS, BINARY_OPS, REDUCE_OPS, op_kind, op_args, \
+    is_keras_tensor, embedding_combiners
+from keras2go.io_parsing import get_tensor_name
 import tensorflow as tf
 tf.compat.v1.disable_eager_execution()
 
@@ -20,17 +25,117 @@ __maintainer__ = "Rory Conlin, https://github.com/f0uriest/keras2go"
 __email__ = "wconlin@princeton.edu"
 
 
+# go functions of the supported activations, by keras name
+ACTIVATIONS = {
+    'linear': 'K2c_linear',
+    'exponential': 'K2c_exponential',
+    'relu': 'K2c_relu',
+    'hard_sigmoid': 'K2c_hard_sigmoid',
+    'tanh': 'K2c_tanh',
+    'sigmoid': 'K2c_sigmoid',
+    'softmax': 'K2c_softmax',
+    'log_softmax': 'K2c_log_softmax',
+    'softplus': 'K2c_softplus',
+    'softsign': 'K2c_softsign',
+    'gelu': 'K2c_gelu',
+    'swish': 'K2c_swish',
+    'silu': 'K2c_silu',
+    'selu': 'K2c_selu',
+    'mish': 'K2c_mish',
+    'relu6': 'K2c_relu6',
+    'hard_swish': 'K2c_hard_swish',
+    'hard_silu': 'K2c_hard_swish',
+    'elu': 'K2c_elu',
+}
+
+
+def custom_object_names(custom_objects=None):
+    """Gets the names of the custom objects keras knows
+
+    Args:
+        custom_objects (dict): custom objects given to the converter
+
+    Returns:
+        names (set): names of custom_objects and of the objects registered
+            with keras, with and without the package of the registered name
+    """
+
+    names = set(custom_objects or {}) | \
+        set(tf.keras.utils.get_custom_objects())
+    return names | set(name.split('>')[-1] for name in names)
+
+
+def activation2go(activation, custom_objects=None):
+    """Gets the go function of an activation
+
+    Custom activations, ie in custom_objects or registered with keras, are
+    looked up by name in the activations registered with
+    K2c_register_activation.
+
+    Args:
+        activation (str or dict): activation from a layer config, either a
+            name or a serialized activation
+        custom_objects (dict): custom objects given to the converter
+
+    Returns:
+        function (str): go expression of the activation function, or None if
+            the activation is not supported
+    """
+
+    if isinstance(activation, dict):
+        config = activation.get('config')
+        if activation.get('class_name') == 'ELU':
+            return 'keras2go.K2c_elu_alpha(' + \
+                str(float(config.get('alpha', 1.0))) + ')'
+        # plain functions serialize to their name
+        if isinstance(config, str):
+            return activation2go(config, custom_objects)
+        activation = activation.get('registered_name') or \
+            activation.get('class_name')
+        if activation == 'gelu' and isinstance(config, dict) and \
+                config.get('approximate'):
+            return 'keras2go.K2c_gelu_tanh'
+    if not isinstance(activation, str):
+        return None
+    if activation in ACTIVATIONS:
+        return 'keras2go.' + ACTIVATIONS[activation]
+    if activation in custom_object_names(custom_objects):
+        return 'keras2go.K2c_new_custom_activation(' + go_string(activation) + ')'
+    return None
+
+
 class Layers2C():
     """Creates an object to parse and write layer functions.
 
     Args:
         model (keras Model): model to parse
+        function_name (str): name of the function being generated
+        embedding_oov (str): handling of out of vocabulary indices in
+            Embedding layers, one of 'error', 'zero' or 'bucket'
+        custom_objects (dict): custom objects given to the converter
     """
 
-    def __init__(self, model):
+    def __init__(self, model, function_name, embedding_oov='error', custom_objects=None):
         self.model = model
+        self.function_name = function_name
+        self.embedding_oov = embedding_oov
+        self.custom_objects = custom_objects
         self.model_inputs, self.model_outputs = get_model_io_names(self.model)
+        self.input_types = dict(
+            zip(self.model_inputs, get_input_types(self.model)))
+        # Embedding calls written to the output of the layer reducing them
+        self.combiners = embedding_combiners(self.model)
+        self.combined = set(c[1] for c in self.combiners.values())
         self.layers = ''
+        # cells of the RNN layers, made once before the layers run
+        self.cells = ''
+        # names of the Go masks of the masked nodes
+        self.masks = {}
+        # package level variables of the custom activations, by expression
+        self.custom_activations = {}
+        self.package_vars = ''
+        # lookups of the custom activations, checked before the layers run
+        self.resolves = ''
 
     def write_layers(self, verbose=True):
         """Writes layers in the correct graph order.
@@ -53,14 +158,22 @@ class Layers2C():
                             layer_type(layer) == 'InputLayer':
                         if verbose:
                             print('Writing layer ', outp)
-                        method = getattr(
-                            self, '_write_layer_' + layer_type(layer))
-                        method(layer, inp, outp, i)
+                        self._write_layer(layer, inp, outp, i)
+                        self._propagate_mask(layer, inp, outp, i)
                         written_io |= set(flatten(inp))
                         written_io |= set(flatten(outp))
                         unwritten_io -= set(flatten(inp))
                         unwritten_io -= set(flatten(outp))
-        return self.layers
+        return self.resolves + self.cells + self.layers
+
+    def _write_layer(self, layer, inp, outp, i):
+        if not isinstance(outp, list) and outp in self.combined:
+            # written by the Embedding it reduces
+            return
+        if is_custom_layer(layer):
+            return self._write_layer_custom(layer, inp, outp, i)
+        method = getattr(self, '_write_layer_' + layer_type(layer))
+        return method(layer, inp, outp, i)
 
     def _format_io_names(self, layer, inp, outp, model_io=False):
         nm = layer.name
@@ -88,7 +201,7 @@ class Layers2C():
                     outp_nm.append(o + '_output')
                     is_model_output = True
                 else:
-                    outp_nm.append('&' + outp + '_output')
+                    outp_nm.append('&' + o + '_output')
         else:
             if outp in self.model_outputs or 'timeslice' in outp:
                 outp_nm = outp + '_output'
@@ -100,92 +213,200 @@ class Layers2C():
         else:
             return nm, pnm, inp_nm, outp_nm
 
-    def _write_layer_TimeDistributed(self, layer, inputs, outputs, i):
-        # nm, pnm, inputs, outputs = self._format_io_names(layer, inputs, outputs)
-        self.layers += 'for(size_t i=0; i<' + layer.name + \
-            '_timesteps; ++i) { \n'
-        if inputs in self.model_inputs:
-            self.layers += layer.layer.name + '_timeslice_input.array = &' + \
-                inputs + '_input->array[i*' + layer.name + '_in_offset]; \n'
-        else:
-            self.layers += layer.layer.name + '_timeslice_input.array = &' + \
-                inputs + '_output.array[i*' + layer.name + '_in_offset]; \n'
-        if outputs in self.model_outputs:
-            self.layers += layer.layer.name + '_timeslice_output.array = &' + \
-                outputs + '_output->array[i*' + layer.name + '_out_offset]; \n'
-        else:
-            self.layers += layer.layer.name + '_timeslice_output.array = &' + \
-                outputs + '_output.array[i*' + layer.name + '_out_offset]; \n'
+    def _activation2go(self, activation):
+        # custom activations are made once, and looked up at the start of each
+        # run, so that the model returns an error if one is not registered
+        function = activation2go(activation, self.custom_objects)
+        if function is None or \
+           not function.startswith('keras2go.K2c_new_custom_activation('):
+            return function
+        if function not in self.custom_activations:
+            name = self.function_name + '_custom_activation' + \
+                str(len(self.custom_activations))
+            self.custom_activations[function] = name
+            self.package_vars += 'var ' + name + ' = ' + function + '\n'
+            self.resolves += 'if err := ' + name + \
+                '.Resolve(); err != nil {\nreturn err\n}\n'
+        return self.custom_activations[function] + '.Apply'
+
+    def _write_checked(self, call):
+        # errors of the layer are returned by the model function
+        self.layers += 'if err := ' + call + '; err != nil {\nreturn err\n}\n'
+
+    @staticmethod
+    def _padding_mode(layer):
+        if layer.get_config()['padding'] == 'same':
+            return 'keras2go.K2C_PADDING_SAME'
+        if layer.get_config()['padding'] == 'causal':
+            return 'keras2go.K2C_PADDING_CAUSAL'
+        return 'keras2go.K2C_PADDING_VALID'
+
+    @staticmethod
+    def _interpolation(layer):
+        interpolations = {'nearest': 'keras2go.K2C_INTERPOLATION_NEAREST',
+                          'bilinear': 'keras2go.K2C_INTERPOLATION_BILINEAR',
+                          'area': 'keras2go.K2C_INTERPOLATION_AREA',
+                          'bicubic': 'keras2go.K2C_INTERPOLATION_BICUBIC'}
+        return interpolations[layer.get_config().get('interpolation', 'nearest')]
+
+    @staticmethod
+    def _data_format(layer):
+        if layer.get_config().get('data_format') == 'channels_first':
+            return 'keras2go.K2C_CHANNELS_FIRST'
+        return 'keras2go.K2C_CHANNELS_LAST'
+
+    def _input_mask(self, inp):
+        if isinstance(inp, list):
+            return [self._input_mask(j) for j in inp]
+        return self.masks.get(inp, 'nil')
+
+    def _propagate_mask(self, layer, inp, outp, i):
+        # layers that compute a new mask register it while being written,
+        # the others pass the mask of their input through
+        if isinstance(inp, list) or isinstance(outp, list) or outp in self.masks:
+            return
+        if inp in self.masks and has_mask(layer, i):
+            self.masks[outp] = self.masks[inp]
+
+    def _write_closure(self, layer, mask='nil'):
+        # the wrapped layer reads and writes the closure arguments, which
+        # _format_io_names resolves from the 'timeslice' names
+        layers = self.layers
+        self.layers = ''
+        self.masks['timeslice'] = mask
+        self._write_layer(layer, 'timeslice', 'timeslice', 0)
+        del self.masks['timeslice']
+        self.layers += 'return nil\n'
+        closure = 'func(timeslice_output, timeslice_input *keras2go.K2c_tensor) error {\n\t' + \
+            self.layers.replace('\n', '\n\t').rstrip('\t') + '}'
+        self.layers = layers
+        return closure
 
-        inp = '&' + layer.layer.name + '_timeslice'
-        outp = '&' + layer.layer.name + '_timeslice'
-        method = getattr(self, '_write_layer_' + layer_type(layer.layer))
-        method(layer.layer, inp, outp, i)
-        self.layers += '\n } \n'
+    def _write_layer_TimeDistributed(self, layer, inputs, outputs, i):
+        _, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        self._write_checked('keras2go.K2c_time_distributed(' + outputs + ',' +
+                            inputs + ', ' + self._write_closure(layer.layer) + ')')
 
     def _write_layer_Bidirectional(self, layer, inputs, outputs, i):
-        subname = layer.layer.name
-        method = getattr(self, '_write_layer_' + layer_type(layer.layer))
-        method(layer.forward_layer, inputs,
-               'forward_' + subname, i)
-        method(layer.backward_layer, inputs,
-               'backward_' + subname, i)
-        mode = layer.merge_mode
-        inputs = ['forward_' + subname,
-                  'backward_' + subname]
-        if layer.layer.return_sequences:
-            self.layers += 'k2c_flip(&backward_' + subname + '_output,0); \n'
-        if mode == 'sum':
-            self._write_layer_Merge(layer, inputs, outputs, i, 'Add')
-        elif mode == 'mul':
-            self._write_layer_Merge(layer, inputs, outputs, i, 'Multiply')
-        elif mode == 'ave':
-            self._write_layer_Merge(layer, inputs, outputs, i, 'Average')
-        elif mode == 'concat':
-            self._write_layer_Concatenate(layer, inputs, outputs, i)
+        mask = self._input_mask(inputs)
+        _, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        merge_modes = {'concat': 'keras2go.K2C_MERGE_CONCAT',
+                       'sum': 'keras2go.K2C_MERGE_SUM',
+                       'mul': 'keras2go.K2C_MERGE_MUL',
+                       'ave': 'keras2go.K2C_MERGE_AVE'}
+        if layer.merge_mode is None:
+            # the forward and backward layers write the two outputs
+            merge_mode = 'keras2go.K2C_MERGE_NONE'
+            layer_outputs = ', '.join(outputs)
+            outputs = 'nil'
+        else:
+            merge_mode = merge_modes[layer.merge_mode]
+            layer_outputs = '&' + layer.forward_layer.name + '_output,&' + \
+                layer.backward_layer.name + '_output'
+        self._write_checked('keras2go.K2c_bidirectional(' + outputs + ',' +
+                            inputs + ',' + mask + ', \n' +
+                            self._write_closure(layer.forward_layer, mask) + ', \n' +
+                            self._write_closure(layer.backward_layer, mask) + ', \n' +
+                            layer_outputs + ',' + merge_mode + ',' +
+                            str(int(layer.layer.return_sequences)) + ')')
+
+    def _write_rnn_reset(self, layer, inputs):
+        # the state is made once per run of the model, so a layer that runs
+        # more than once starts each call from zero unless it is stateful
+        if not layer.stateful and \
+           (inputs == 'timeslice' or get_layer_num_io(layer)[0] > 1):
+            self.layers += 'keras2go.K2c_rnn_reset_state(' + layer.name + \
+                '_state) \n'
+
+    def _rnn_io(self, layer, inputs, outputs):
+        # initial states follow the sequence in the inputs of the layer, and
+        # final states follow the output in its outputs
+        initial_state, final_state = [], []
+        if isinstance(inputs, list):
+            inputs, initial_state = inputs[0], inputs[1:]
+        if isinstance(outputs, list):
+            outputs, final_state = outputs[0], outputs[1:]
+        mask = self._input_mask(inputs)
+        if final_state and mask != 'nil' and layer.return_sequences:
+            self.masks[outputs] = mask
+        self._write_rnn_reset(layer, inputs)
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        if initial_state:
+            _, _, initial_state, _ = self._format_io_names(
+                layer, initial_state, [])
+            self.layers += 'keras2go.K2c_rnn_set_state(' + nm + '_state,' + \
+                ','.join(initial_state) + ') \n'
+        if final_state:
+            _, _, _, final_state = self._format_io_names(
+                layer, [], final_state)
+        return mask, nm, pnm, inputs, outputs, final_state
+
+    def _write_rnn_final_state(self, nm, final_state):
+        if final_state:
+            self.layers += 'keras2go.K2c_rnn_get_state(' + nm + '_state,' + \
+                ','.join(final_state) + ') \n'
 
     def _write_layer_LSTM(self, layer, inputs, outputs, i):
-        nm, pnm, inputs, outputs = self._format_io_names(
+        mask, nm, pnm, inputs, outputs, final_state = self._rnn_io(
             layer, inputs, outputs)
-        self.layers += 'keras2go.K2c_lstm(' + outputs + ',' + inputs + ',' + nm + \
-                       '_state,' + pnm + '_kernel, \n\t' + pnm + \
+        self.layers += 'keras2go.K2c_lstm(' + outputs + ',' + inputs + ',' + \
+                       mask + ',' + nm + '_state,' + pnm + '_kernel, \n\t' + pnm + \
                        '_recurrent_kernel,' + pnm + '_bias,' + nm + \
                        '_fwork, \n\t' + nm + '_go_backwards,' + nm + \
                        '_return_sequences, \n\t' + \
-                       'keras2go.K2c_' + layer.get_config()['recurrent_activation'] + \
-                       ',' + 'keras2go.K2c_' + \
-            layer.get_config()['activation'] + '); \n'
+                       self._activation2go(layer.get_config()['recurrent_activation']) + \
+                       ',' + self._activation2go(layer.get_config()['activation']) + '); \n'
+        self._write_rnn_final_state(nm, final_state)
+
+    def _write_layer_ConvLSTM2D(self, layer, inputs, outputs, i):
+        _, nm, pnm, inputs, outputs, final_state = self._rnn_io(
+            layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_conv_lstm2d(' + outputs + ',' + inputs + \
+                       ',' + nm + '_state,' + pnm + '_kernel, \n\t' + pnm + \
+                       '_recurrent_kernel,' + pnm + '_bias,' + nm + \
+                       '_fwork, \n\t' + nm + '_stride,' + nm + '_dilation,' + \
+                       self._padding_mode(layer) + ', \n\t' + nm + \
+                       '_go_backwards,' + nm + '_return_sequences, \n\t' + \
+                       self._activation2go(layer.get_config()['recurrent_activation']) + \
+                       ',' + self._activation2go(layer.get_config()['activation']) + ') \n'
+        self._write_rnn_final_state(nm, final_state)
 
     def _write_layer_Dense(self, layer, inputs, outputs, i):
         nm, pnm, inputs, outputs = self._format_io_names(
             layer, inputs, outputs)
-        activation = 'keras2go.K2c_' + layer.get_config()['activation']
+        activation = self._activation2go(layer.get_config()['activation'])
 
         self.layers += 'keras2go.K2c_dense(' + outputs + ',' + inputs + ',' + pnm + \
             '_kernel, \n\t' + pnm + '_bias,' + activation + ',' + \
             nm + '_fwork); \n'
 
+    def _write_layer_EinsumDense(self, layer, inputs, outputs, i):
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        activation = self._activation2go(layer.get_config()['activation'])
+        if layer.get_config()['bias_axes'] is not None:
+            bias = pnm + '_bias'
+        else:
+            bias = 'nil'
+        self.layers += 'keras2go.K2c_einsum_dense(' + outputs + ',' + inputs + \
+            ',' + pnm + '_kernel,' + bias + ',' + nm + '_plan,' + \
+            activation + ',' + nm + '_fwork) \n'
+
     def _write_layer_Conv(self, layer, inputs, outputs, i):
         nm, pnm, inputs, outputs = self._format_io_names(
             layer, inputs, outputs)
-        activation = 'keras2go.K2c_' + layer.get_config()['activation']
+        activation = self._activation2go(layer.get_config()['activation'])
         if layer_type(layer)[-2:] == '1D':
-            fname = 'k2c_conv1d('
+            fname = 'keras2go.K2c_conv1d('
         elif layer_type(layer)[-2:] == '2D':
-            fname = 'k2c_conv2d('
+            fname = 'keras2go.K2c_conv2d('
         elif layer_type(layer)[-2:] == '3D':
-            fname = 'k2c_conv3d('
-        if layer.get_config()['padding'] == 'valid':
-            self.layers += fname + outputs + ',' + inputs + ',' + \
-                pnm + '_kernel, \n\t' + pnm + '_bias,' + nm + \
-                '_stride,' + nm + '_dilation,' + activation + '); \n'
-        else:
-            self._write_layer_ZeroPad(layer, inputs, pnm +
-                                      '_padded_input', i)
-            self.layers += fname + outputs + ',' + pnm + \
-                '_padded_input,' + pnm + '_kernel, \n\t' + \
-                pnm + '_bias,' + nm + '_stride,' + nm + \
-                '_dilation,' + activation + '); \n'
+            fname = 'keras2go.K2c_conv3d('
+        self.layers += fname + outputs + ',' + inputs + ',' + \
+            pnm + '_kernel, \n\t' + pnm + '_bias,' + nm + \
+            '_stride,' + nm + '_dilation,' + self._padding_mode(layer) + \
+            ',' + nm + '_groups,' + activation + ') \n'
 
     def _write_layer_Conv1D(self, layer, inputs, outputs, i):
         self._write_layer_Conv(layer, inputs, outputs, i)
@@ -196,6 +417,47 @@ class Layers2C():
     def _write_layer_Conv3D(self, layer, inputs, outputs, i):
         self._write_layer_Conv(layer, inputs, outputs, i)
 
+    def _write_layer_ConvTranspose(self, layer, inputs, outputs, i):
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        activation = self._activation2go(layer.get_config()['activation'])
+        if layer_type(layer)[-11:-9] == '1D':
+            fname = 'keras2go.K2c_conv1d_transpose('
+        elif layer_type(layer)[-11:-9] == '2D':
+            fname = 'keras2go.K2c_conv2d_transpose('
+        elif layer_type(layer)[-11:-9] == '3D':
+            fname = 'keras2go.K2c_conv3d_transpose('
+        self.layers += fname + outputs + ',' + inputs + ',' + \
+            pnm + '_kernel, \n\t' + pnm + '_bias,' + nm + \
+            '_stride,' + nm + '_dilation,' + self._padding_mode(layer) + \
+            ',' + activation + ') \n'
+
+    def _write_layer_Conv1DTranspose(self, layer, inputs, outputs, i):
+        self._write_layer_ConvTranspose(layer, inputs, outputs, i)
+
+    def _write_layer_Conv2DTranspose(self, layer, inputs, outputs, i):
+        self._write_layer_ConvTranspose(layer, inputs, outputs, i)
+
+    def _write_layer_Conv3DTranspose(self, layer, inputs, outputs, i):
+        self._write_layer_ConvTranspose(layer, inputs, outputs, i)
+
+    def _write_layer_LocallyConnected1D(self, layer, inputs, outputs, i):
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        activation = self._activation2go(layer.get_config()['activation'])
+        self.layers += 'keras2go.K2c_locally_connected1d(' + outputs + ',' + \
+            inputs + ',' + pnm + '_kernel, \n\t' + pnm + '_bias,' + nm + \
+            '_kernel_size,' + nm + '_stride,' + activation + ') \n'
+
+    def _write_layer_LocallyConnected2D(self, layer, inputs, outputs, i):
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        activation = self._activation2go(layer.get_config()['activation'])
+        self.layers += 'keras2go.K2c_locally_connected2d(' + outputs + ',' + \
+            inputs + ',' + pnm + '_kernel, \n\t' + pnm + '_bias,' + nm + \
+            '_kernel_size,' + nm + '_stride,' + nm + '_fwork,' + \
+            activation + ') \n'
+
     def _write_layer_MaxPooling1D(self, layer, inputs, outputs, i):
         self._write_layer_Pooling(layer, inputs, outputs, i)
 
@@ -203,25 +465,16 @@ class Layers2C():
         self._write_layer_Pooling(layer, inputs, outputs, i)
 
     def _write_layer_Pooling(self, layer, inputs, outputs, i):
-        nm, pnm, inputs, outputs = self._format_io_names(
+        nm, _, inputs, outputs = self._format_io_names(
             layer, inputs, outputs)
         if 'Max' in layer_type(layer):
-            s = 'k2c_maxpool'
+            s = 'keras2go.K2c_maxpool'
         else:
-            s = 'k2c_avgpool'
-        if layer_type(layer)[-2:] == '1D':
-            s += '1d(' + outputs + ','
-        elif layer_type(layer)[-2:] == '2D':
-            s += '2d(' + outputs + ','
-
-        if layer.get_config()['padding'] == 'valid':
-            s += inputs + ','
-        else:
-            self._write_layer_ZeroPad(layer, inputs, pnm +
-                                      '_padded_input', i)
-            s += pnm + '_padded_input,'
-
-        s += nm + '_pool_size, \n\t' + nm + '_stride); \n'
+            s = 'keras2go.K2c_avgpool'
+        s += layer_type(layer)[-2:].lower() + '('
+        s += outputs + ',' + inputs + ',' + nm + '_pool_size, \n\t' + \
+            nm + '_stride,' + self._padding_mode(layer) + ',' + \
+            self._data_format(layer) + ') \n'
         self.layers += s
 
     def _write_layer_MaxPooling2D(self, layer, inputs, outputs, i):
@@ -230,6 +483,12 @@ class Layers2C():
     def _write_layer_AveragePooling2D(self, layer, inputs, outputs, i):
         self._write_layer_Pooling(layer, inputs, outputs, i)
 
+    def _write_layer_MaxPooling3D(self, layer, inputs, outputs, i):
+        self._write_layer_Pooling(layer, inputs, outputs, i)
+
+    def _write_layer_AveragePooling3D(self, layer, inputs, outputs, i):
+        self._write_layer_Pooling(layer, inputs, outputs, i)
+
     def _write_layer_GlobalMaxPooling1D(self, layer, inputs, outputs, i):
         self._write_layer_GlobalPooling(layer, inputs, outputs, i)
 
@@ -249,12 +508,17 @@ class Layers2C():
         self._write_layer_GlobalPooling(layer, inputs, outputs, i)
 
     def _write_layer_GlobalPooling(self, layer, inputs, outputs, i):
+        # like keras, only GlobalAveragePooling1D skips the masked timesteps
+        mask = self._input_mask(inputs)
         _, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
         if 'Max' in layer_type(layer):
-            self.layers += 'k2c_global_max_pooling('
-        else:
-            self.layers += 'k2c_global_avg_pooling('
-        self.layers += outputs + ',' + inputs + '); \n'
+            self.layers += 'keras2go.K2c_global_max_pooling(' + outputs + \
+                ',' + inputs + ',' + self._data_format(layer) + ') \n'
+            return
+        if layer_type(layer) != 'GlobalAveragePooling1D':
+            mask = 'nil'
+        self.layers += 'keras2go.K2c_global_avg_pooling(' + outputs + ',' + \
+            inputs + ',' + self._data_format(layer) + ',' + mask + ') \n'
 
     def _write_layer_Add(self, layer, inputs, outputs, i):
         self._write_layer_Merge(layer, inputs, outputs, i, 'Add')
@@ -275,62 +539,107 @@ class Layers2C():
         self._write_layer_Merge(layer, inputs, outputs, i, 'Average')
 
     def _write_layer_Merge(self, layer, inputs, outputs, i, mode):
+        self._write_merge_mask(layer, inputs, outputs, i)
         nm, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
         if mode == 'Subtract':
-            self.layers += 'k2c_subtract('
+            self.layers += 'keras2go.K2c_subtract('
         elif mode == 'Add':
-            self.layers += 'k2c_add('
+            self.layers += 'keras2go.K2c_add('
         elif mode == 'Multiply':
-            self.layers += 'k2c_multiply('
+            self.layers += 'keras2go.K2c_multiply('
         elif mode == 'Average':
-            self.layers += 'k2c_average('
+            self.layers += 'keras2go.K2c_average('
         elif mode == 'Maximum':
-            self.layers += 'k2c_max('
+            self.layers += 'keras2go.K2c_max('
         elif mode == 'Minimum':
-            self.layers += 'k2c_min('
-        self.layers += outputs + ',' + nm + '_num_tensors' + str(i) + ','
+            self.layers += 'keras2go.K2c_min('
+        self.layers += outputs + ','
         c = ','.join(inputs)
-        self.layers += c + '); \n'
+        self.layers += c + ') \n'
+
+    def _write_merge_mask(self, layer, inputs, outputs, i):
+        if not has_mask(layer, i):
+            return
+        mask = get_node_name(layer, i) + '_mask'
+        self.layers += 'keras2go.K2c_merge_mask(' + mask + ',' + \
+            ','.join(self._input_mask(inputs)) + ') \n'
+        self.masks[outputs] = mask
 
     def _write_layer_Concatenate(self, layer, inputs, outputs, i):
+        self._write_merge_mask(layer, inputs, outputs, i)
         nm, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
-        self.layers += 'k2c_concatenate(' + outputs + ',' + nm + \
-                       '_axis' + ',' + nm + '_num_tensors' + str(i) + ','
+        self.layers += 'keras2go.K2c_concatenate(' + outputs + ',' + nm + \
+                       '_axis,'
         c = ','.join(inputs)
-        self.layers += c + '); \n'
+        self.layers += c + ') \n'
 
     def _write_layer_GRU(self, layer, inputs, outputs, i):
-        nm, pnm, inputs, outputs = self._format_io_names(
+        mask, nm, pnm, inputs, outputs, final_state = self._rnn_io(
             layer, inputs, outputs)
-        self.layers += 'k2c_gru(' + outputs + ',' + inputs + ',' + \
-            nm + '_state,' + pnm + '_kernel, \n\t' + \
+        self.layers += 'keras2go.K2c_gru(' + outputs + ',' + inputs + ',' + \
+            mask + ',' + nm + '_state,' + pnm + '_kernel, \n\t' + \
             pnm + '_recurrent_kernel,' + pnm + '_bias,' + \
             nm + '_fwork, \n\t' + nm + '_reset_after,' + \
             nm + '_go_backwards,' + nm + '_return_sequences, \n\t' + \
-            'keras2go.Kk2c_' + layer.get_config()['recurrent_activation'] + \
-            ',' + 'keras2go.K2c_' + layer.get_config()['activation'] + '); \n'
+            self._activation2go(layer.get_config()['recurrent_activation']) + \
+            ',' + self._activation2go(layer.get_config()['activation']) + '); \n'
+        self._write_rnn_final_state(nm, final_state)
 
     def _write_layer_SimpleRNN(self, layer, inputs, outputs, i):
-        nm, pnm, inputs, outputs = self._format_io_names(
+        mask, nm, pnm, inputs, outputs, final_state = self._rnn_io(
             layer, inputs, outputs)
-        self.layers += 'k2c_simpleRNN(' + outputs + ',' + inputs + \
-            ',' + nm + '_state,' + pnm + '_kernel, \n\t' + \
+        self.layers += 'keras2go.K2c_simpleRNN(' + outputs + ',' + inputs + \
+            ',' + mask + ',' + nm + '_state,' + pnm + '_kernel, \n\t' + \
             pnm + '_recurrent_kernel,' + pnm + '_bias,' + \
             nm + '_fwork, \n\t' + nm + '_go_backwards,' + \
-            nm + '_return_sequences,' + 'keras2go.K2c_' + \
-            layer.get_config()['activation'] + '); \n'
+            nm + '_return_sequences,' + \
+            self._activation2go(layer.get_config()['activation']) + '); \n'
+        self._write_rnn_final_state(nm, final_state)
+
+    def _write_layer_RNN(self, layer, inputs, outputs, i):
+        mask, nm, pnm, inputs, outputs, final_state = self._rnn_io(
+            layer, inputs, outputs)
+        if 'var ' + nm + '_cell ' not in self.cells:
+            self.cells += 'var ' + nm + '_cell keras2go.K2c_rnn_cell = ' + \
+                self._rnn_cell(layer.cell, nm) + '\n'
+        self.layers += 'keras2go.K2c_rnn(' + outputs + ',' + inputs + ',' + \
+            mask + ',' + nm + '_state,' + nm + '_cell, \n\t' + nm + \
+            '_go_backwards,' + nm + '_return_sequences) \n'
+        self._write_rnn_final_state(nm, final_state)
+
+    def _rnn_cell(self, cell, nm):
+        # go expression of a cell, with the weights written by
+        # Weights2C._write_cell_weights
+        if layer_type(cell) == 'StackedRNNCells':
+            return 'keras2go.K2c_stacked_rnn_cells{' + ', '.join(
+                [self._rnn_cell(c, nm + '_cell' + str(k))
+                 for k, c in enumerate(cell.cells)]) + '}'
+        config = cell.get_config()
+        fields = 'Kernel: &' + nm + '_kernel, RecurrentKernel: &' + nm + \
+            '_recurrent_kernel, Bias: &' + nm + '_bias, Fwork: ' + nm + '_fwork, '
+        if layer_type(cell) == 'LSTMCell':
+            return '&keras2go.K2c_lstm_cell{' + fields + \
+                'RecurrentActivation: ' + \
+                self._activation2go(config['recurrent_activation']) + \
+                ', OutputActivation: ' + self._activation2go(config['activation']) + '}'
+        if layer_type(cell) == 'GRUCell':
+            return '&keras2go.K2c_gru_cell{' + fields + \
+                'ResetAfter: ' + nm + '_reset_after, RecurrentActivation: ' + \
+                self._activation2go(config['recurrent_activation']) + \
+                ', OutputActivation: ' + self._activation2go(config['activation']) + '}'
+        return '&keras2go.K2c_simpleRNN_cell{' + fields + \
+            'OutputActivation: ' + self._activation2go(config['activation']) + '}'
 
     def _write_layer_Activation(self, layer, inputs, outputs, i):
-        _, _, inputs, outputs, is_model_input, is_model_output = self._format_io_names(
-            layer, inputs, outputs, True)
-        activation = 'keras2go.K2c_' + layer.get_config()['activation']
-        if is_model_input:
-            inp = inputs + '->'
-        else:
-            inp = inputs[1:] + '.'
-        self.layers += activation + '(' + inp + 'array,' + inp + 'numel); \n'
-        self._write_dummy_layer(layer, inputs, outputs, i,
-                                is_model_input, is_model_output)
+        _, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        activation = self._activation2go(layer.get_config()['activation'])
+        self.layers += 'keras2go.K2c_activation(' + outputs + ',' + inputs + \
+            ',' + activation + ') \n'
+
+    def _write_layer_Softmax(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_softmax_axis(' + outputs + ',' + \
+            inputs + ',' + nm + '_axis) \n'
 
     def _write_layer_LeakyReLU(self, layer, inputs, outputs, i):
         self._write_layer_AdvancedActivation(layer, inputs, outputs, i)
@@ -449,10 +758,126 @@ class Layers2C():
                        ',' + pnm + '_mean,' + pnm + '_stdev,' + pnm + \
                        '_gamma,' + pnm + '_beta,' + nm + '_axis); \n'
 
+    @staticmethod
+    def _normalization_params(layer, pnm):
+        gamma = pnm + '_gamma' if layer.get_config()['scale'] else 'nil'
+        beta = pnm + '_beta' if layer.get_config()['center'] else 'nil'
+        return gamma + ',' + beta
+
+    def _write_layer_LayerNormalization(self, layer, inputs, outputs, i):
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_layer_norm(' + outputs + ',' + inputs + \
+            ',' + self._normalization_params(layer, pnm) + ',' + nm + \
+            '_axes,' + nm + '_epsilon) \n'
+
+    def _write_layer_GroupNormalization(self, layer, inputs, outputs, i):
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_group_norm(' + outputs + ',' + inputs + \
+            ',' + self._normalization_params(layer, pnm) + ',' + nm + \
+            '_axis,' + nm + '_groups,' + nm + '_epsilon) \n'
+
+    def _write_layer_UnitNormalization(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_unit_norm(' + outputs + ',' + inputs + \
+            ',' + nm + '_axes) \n'
+
+    @staticmethod
+    def _use_causal_mask(layer, i):
+        # older versions of keras set causal in the config of Attention
+        causal = get_call_args(layer, i, []).get('use_causal_mask') or \
+            layer.get_config().get('causal', False)
+        return str(int(bool(causal)))
+
+    def _write_layer_Attention(self, layer, inputs, outputs, i):
+        self._write_layer_AttentionBase(
+            layer, inputs, outputs, i, 'keras2go.K2c_attention',
+            layer.name + '_scale')
+
+    def _write_layer_AdditiveAttention(self, layer, inputs, outputs, i):
+        if layer.get_config()['use_scale']:
+            scale = '&' + layer.name + '_scale'
+        else:
+            scale = 'nil'
+        self._write_layer_AttentionBase(
+            layer, inputs, outputs, i, 'keras2go.K2c_additive_attention',
+            scale)
+
+    def _write_layer_AttentionBase(self, layer, inputs, outputs, i, fname,
+                                   scale):
+        outp = outputs
+        query_mask, value_mask = self._input_mask(inputs)[:2]
+        nm, _, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        key = inputs[2] if len(inputs) > 2 else inputs[1]
+        self.layers += fname + '(' + outputs + ',' + inputs[0] + ',' + \
+            inputs[1] + ',' + key + ',' + query_mask + ',' + value_mask + \
+            ',' + scale + ',' + self._use_causal_mask(layer, i) + ',' + \
+            nm + '_fwork) \n'
+        if query_mask != 'nil' and has_mask(layer, i):
+            self.masks[outp] = query_mask
+
+    def _write_layer_MultiHeadAttention(self, layer, inputs, outputs, i):
+        outp = outputs
+        query_mask, value_mask, key_mask = self._input_mask(inputs)[:3]
+        if inputs[2] == inputs[1]:
+            key_mask = 'nil'
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        attention_mask = inputs[3] if len(inputs) > 3 else 'nil'
+        self.layers += 'keras2go.K2c_multi_head_attention(' + outputs + \
+            ',' + ','.join(inputs[:3]) + ', \n\t' + query_mask + ',' + \
+            value_mask + ',' + key_mask + ',' + attention_mask + ',' + \
+            self._use_causal_mask(layer, i) + ', \n\t'
+        for w in ['query', 'key', 'value', 'output']:
+            self.layers += pnm + '_' + w + '_kernel,' + \
+                pnm + '_' + w + '_bias,'
+        self.layers += '\n\t' + nm + '_fwork) \n'
+        if query_mask != 'nil' and has_mask(layer, i):
+            self.masks[outp] = query_mask
+
     def _write_layer_Embedding(self, layer, inputs, outputs, i):
-        _, pnm, inputs, outputs = self._format_io_names(layer, inputs, outputs)
-        self.layers += 'k2c_embedding(' + outputs + ',' + inputs + \
-            ',' + pnm + '_kernel); \n'
+        inp, outp = inputs, outputs
+        combiner = 'K2C_COMBINER_NONE'
+        if outp in self.combiners:
+            # the vectors of each bag go to the output of the layer reducing
+            # them, see embedding_combiners
+            combiner, outputs = self.combiners[outp]
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        oov_modes = {'error': 'keras2go.K2C_OOV_ERROR',
+                     'zero': 'keras2go.K2C_OOV_ZERO',
+                     'bucket': 'keras2go.K2C_OOV_BUCKET'}
+        mask_zero = bool(layer.get_config().get('mask_zero'))
+        # in bucket mode the kernel ends with a dedicated OOV row
+        args = pnm + '_kernel,' + oov_modes[self.embedding_oov] + ',' + \
+            str(int(self.embedding_oov == 'bucket')) + ',' + \
+            str(int(mask_zero)) + ',keras2go.' + combiner
+        ids = self.input_types.get(inp) == '[]int'
+        if ids:
+            bag_size = 1
+            if combiner != 'K2C_COMBINER_NONE':
+                bag_size = int(layer.get_input_at(i).shape[-1])
+            self._write_checked('keras2go.K2c_embedding_ids(' + outputs + ',' +
+                                inputs + ',' + str(bag_size) + ',' + args + ')')
+        else:
+            self._write_checked('keras2go.K2c_embedding(' + outputs + ',' +
+                                inputs + ',' + args + ')')
+        if mask_zero and outp not in self.combiners:
+            mask = get_node_name(layer, i) + '_mask'
+            self.layers += 'keras2go.K2c_embedding' + \
+                ('_ids' if ids else '') + '_mask(' + mask + ',' + inputs + ') \n'
+            self.masks[outp] = mask
+
+    def _write_layer_Masking(self, layer, inputs, outputs, i):
+        outp = outputs
+        nm, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        mask = get_node_name(layer, i) + '_mask'
+        self.layers += 'keras2go.K2c_masking(' + outputs + ',' + inputs + \
+            ',' + mask + ',' + nm + '_mask_value) \n'
+        self.masks[outp] = mask
 
     def _write_layer_UpSampling1D(self, layer, inputs, outputs, i):
         self._write_layer_UpSampling(layer, inputs, outputs, i)
@@ -467,12 +892,15 @@ class Layers2C():
         nm, _, inputs, outputs = self._format_io_names(
             layer, inputs, outputs)
         if layer_type(layer)[-2:] == '1D':
-            self.layers += 'k2c_upsampling1d('
+            self.layers += 'keras2go.K2c_upsampling1d(' + outputs + ',' + \
+                inputs + ',' + nm + '_size) \n'
+            return
         elif layer_type(layer)[-2:] == '2D':
-            self.layers += 'k2c_upsampling2d('
+            self.layers += 'keras2go.K2c_upsampling2d('
         elif layer_type(layer)[-2:] == '3D':
-            self.layers += 'k2c_upsampling3d('
-        self.layers += outputs + ',' + inputs + ',' + nm + '_size); \n'
+            self.layers += 'keras2go.K2c_upsampling3d('
+        self.layers += outputs + ',' + inputs + ',' + nm + '_size,' + \
+            self._interpolation(layer) + ') \n'
 
     def _write_layer_Cropping1D(self, layer, inputs, outputs, i):
         self._write_layer_Cropping(layer, inputs, outputs, i)
@@ -566,6 +994,177 @@ class Layers2C():
         self._write_dummy_layer(layer, inputs, outputs, i,
                                 is_model_input, is_model_output)
 
+    def _write_layer_Rescaling(self, layer, inputs, outputs, i):
+        _, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_rescaling(' + outputs + ',' + inputs + \
+            ',' + pnm + '_scale,' + pnm + '_offset) \n'
+
+    def _write_layer_Normalization(self, layer, inputs, outputs, i):
+        nm, pnm, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_normalization(' + outputs + ',' + \
+            inputs + ',' + pnm + '_mean,' + pnm + '_variance,' + nm + \
+            '_invert) \n'
+
+    def _write_layer_Resizing(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_resizing(' + outputs + ',' + inputs + \
+            ',' + self._interpolation(layer) + ',' + nm + \
+            '_crop_to_aspect_ratio) \n'
+
+    def _write_layer_CenterCrop(self, layer, inputs, outputs, i):
+        _, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        self.layers += 'keras2go.K2c_center_crop(' + outputs + ',' + \
+            inputs + ') \n'
+
+    @staticmethod
+    def _encoding(output_mode):
+        encodings = {'int': '-1',
+                     'one_hot': 'keras2go.K2C_ENCODING_ONE_HOT',
+                     'multi_hot': 'keras2go.K2C_ENCODING_MULTI_HOT',
+                     'count': 'keras2go.K2C_ENCODING_COUNT',
+                     'tf_idf': 'keras2go.K2C_ENCODING_TF_IDF'}
+        return encodings[output_mode]
+
+    def _write_indices(self, layer, outputs, kernel, args):
+        # in output modes other than int the indices go to a work tensor, and
+        # are encoded into the output
+        nm = layer.name
+        output_mode = layer.get_config().get('output_mode', 'int')
+        if output_mode == 'int':
+            self.layers += 'keras2go.' + kernel + '(' + outputs + ',' + args + ') \n'
+            return
+        self.layers += 'keras2go.' + kernel + '(&' + nm + '_indices,' + args + ') \n'
+        idf_weights = '&' + nm + '_idf_weights' if output_mode == 'tf_idf' else 'nil'
+        self._write_checked('keras2go.K2c_category_encoding(' + outputs + ',&' + nm +
+                            '_indices,' + self._encoding(output_mode) + ',' +
+                            idf_weights + ')')
+
+    def _write_layer_StringLookup(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self._write_indices(layer, outputs, 'K2c_string_lookup',
+                            inputs + ',' + nm + '_lookup')
+
+    def _write_layer_IntegerLookup(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        self._write_indices(layer, outputs, 'K2c_integer_lookup',
+                            inputs + ',' + nm + '_lookup')
+
+    def _write_layer_Hashing(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        if layer.input.dtype == tf.string:
+            kernel = 'K2c_hash_strings'
+        else:
+            kernel = 'K2c_hashing'
+        self._write_indices(layer, outputs, kernel, inputs + ',' +
+                            str(layer.get_config()['num_bins']) + ',' + nm +
+                            '_mask_value,' + nm + '_use_mask_value,' + nm + '_salt')
+
+    def _write_layer_CategoryEncoding(self, layer, inputs, outputs, i):
+        _, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        self._write_checked('keras2go.K2c_category_encoding(' + outputs + ',' +
+                            inputs + ',' +
+                            self._encoding(layer.get_config()['output_mode']) +
+                            ',nil)')
+
+    def _write_layer_TextVectorization(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(
+            layer, inputs, outputs)
+        config = layer.get_config()
+        standardizations = {None: 'keras2go.K2C_STANDARDIZE_NONE',
+                            'lower': 'keras2go.K2C_STANDARDIZE_LOWER',
+                            'strip_punctuation': 'keras2go.K2C_STANDARDIZE_STRIP_PUNCTUATION',
+                            'lower_and_strip_punctuation':
+                            'keras2go.K2C_STANDARDIZE_LOWER_AND_STRIP_PUNCTUATION'}
+        splits = {None: 'keras2go.K2C_SPLIT_NONE',
+                  'whitespace': 'keras2go.K2C_SPLIT_WHITESPACE',
+                  'character': 'keras2go.K2C_SPLIT_CHARACTER'}
+        if config['output_mode'] == 'tf_idf':
+            idf_weights = '&' + nm + '_idf_weights'
+        else:
+            idf_weights = 'nil'
+        self._write_checked('keras2go.K2c_text_vectorization(' + outputs + ',' +
+                            inputs + ',' + nm + '_lookup,' +
+                            standardizations[config['standardize']] + ',' +
+                            splits[config['split']] + ',' + nm + '_ngrams,' +
+                            self._encoding(config['output_mode']) + ',' +
+                            idf_weights + ')')
+
+    def _op_operand(self, layer, args, arg):
+        # tensors are layer outputs or model inputs, constants were written
+        # with the weights
+        value = args[arg]
+        if not is_keras_tensor(value):
+            return '&' + layer.name + '_' + arg
+        name = get_tensor_name(value)
+        if name in self.model_inputs:
+            return name + '_input'
+        return '&' + name + '_output'
+
+    def _write_layer_TFOpLambda(self, layer, inputs, outputs, i):
+        nm, _, _, outputs = self._format_io_names(layer, inputs, outputs)
+        symbol = layer.get_config()['function']
+        kind = op_kind(layer)
+        args = op_args(layer, i)
+        if kind == 'unary':
+            self.layers += 'keras2go.K2c_unary(' + outputs + ',' + \
+                self._op_operand(layer, args, 'x') + ',keras2go.' + \
+                UNARY_OPS[symbol] + ') \n'
+        elif kind == 'binary':
+            self.layers += 'keras2go.K2c_binary(' + outputs + ',' + \
+                self._op_operand(layer, args, 'x') + ',' + \
+                self._op_operand(layer, args, 'y') + ',keras2go.' + \
+                BINARY_OPS[symbol] + ') \n'
+        elif kind == 'where':
+            self.layers += 'keras2go.K2c_where(' + outputs + ',' + \
+                ','.join(self._op_operand(layer, args, arg)
+                         for arg in ['condition', 'x', 'y']) + ') \n'
+        elif kind == 'reduce':
+            self.layers += 'keras2go.K2c_reduce(' + outputs + ',' + \
+                self._op_operand(layer, args, 'input_tensor') + ',' + nm + \
+                '_axes,keras2go.' + REDUCE_OPS[symbol] + ') \n'
+        elif kind in ['getitem', 'strided_slice', 'slice']:
+            tensor_arg = {'getitem': 'tensor'}.get(kind, 'input_')
+            self.layers += 'keras2go.K2c_strided_slice(' + outputs + ',' + \
+                self._op_operand(layer, args, tensor_arg) + ',' + nm + \
+                '_begin,' + nm + '_size,' + nm + '_strides) \n'
+        elif kind == 'gather':
+            self._write_checked('keras2go.K2c_gather(' + outputs + ',' +
+                                self._op_operand(layer, args, 'params') + ',' +
+                                self._op_operand(layer, args, 'indices') + ',' +
+                                nm + '_axis)')
+        elif kind == 'squeeze':
+            self.layers += 'keras2go.K2c_squeeze(' + outputs + ',' + \
+                self._op_operand(layer, args, 'input') + ',' + nm + \
+                '_axes) \n'
+        elif kind == 'einsum':
+            operands = [self._op_operand(layer, args, arg)
+                        for arg in ['a', 'b'] if arg in args]
+            self.layers += 'keras2go.K2c_einsum(' + outputs + ',' + \
+                ','.join(operands + ['nil'] * (2 - len(operands))) + ',' + \
+                nm + '_plan,' + nm + '_fwork) \n'
+        elif kind == 'expand_dims':
+            self.layers += 'keras2go.K2c_expand_dims(' + outputs + ',' + \
+                self._op_operand(layer, args, 'input') + ',' + nm + \
+                '_axis) \n'
+
+    def _write_layer_SlicingOpLambda(self, layer, inputs, outputs, i):
+        self._write_layer_TFOpLambda(layer, inputs, outputs, i)
+
+    def _write_layer_custom(self, layer, inputs, outputs, i):
+        nm, _, inputs, outputs = self._format_io_names(layer, inputs, outputs)
+        if not isinstance(inputs, list):
+            inputs = [inputs]
+        # each call of the layer has its own instance, see Weights2C
+        self._write_checked(get_node_name(layer, i) + '_call.Call(' + outputs +
+                            ',[]*keras2go.K2c_tensor{' + ','.join(inputs) +
+                            '},' + nm + '_weights)')
+
     def _write_layer_Input(self, layer, inputs, outputs, i):
         self.layers += ''
 
@@ -9,7 +9,8 @@ Generates automatic test suite for converted code
 
 # imports
 import numpy as np
-from keras2go.io_parsing import get_model_io_names
+from keras2go.io_parsing import get_model_io_names, layer_type, get_layer_io_names, \
+    flatten, go_string, get_input_types
 from keras2go.weights2go import Weights2C
 import tensorflow as tf
 import subprocess
@@ -22,6 +23,91 @@ __maintainer__ = "Rory Conlin, https://github.com/f0uriest/keras2c"
 __email__ = "wconlin@princeton.edu"
 
 
+def test_tokens(model):
+    """Gets the values to build random string and integer inputs from.
+
+    Values are mostly taken from the vocabularies of the model, so that the
+    tests look up known tokens as well as unknown ones.
+
+    Args:
+        model (keras Model): model being converted to C
+
+    Returns:
+        words (list): words for string inputs
+        integers (list): values for integer inputs
+        sentences (bool): whether string inputs are texts of several words
+    """
+
+    words = ['foo', 'bar', 'qux']
+    integers = list(range(10))
+    sentences = False
+    num_tokens = None
+    for layer in model.layers:
+        lookup = getattr(layer, '_lookup_layer', layer)
+        if hasattr(lookup, 'get_vocabulary'):
+            vocabulary = lookup.get_vocabulary(include_special_tokens=False)
+            if layer.input.dtype == tf.string:
+                words += [str(w) for w in vocabulary]
+            else:
+                integers += [int(w) for w in vocabulary]
+        if layer_type(layer) == 'TextVectorization':
+            sentences = True
+        if layer_type(layer) == 'CategoryEncoding':
+            num_tokens = min(num_tokens or np.inf,
+                             layer.get_config()['num_tokens'])
+        if layer_type(layer) == 'Embedding':
+            num_tokens = min(num_tokens or np.inf,
+                             layer.get_config()['input_dim'])
+    if num_tokens is not None:
+        # CategoryEncoding and Embedding reject values out of range
+        integers = list(range(num_tokens))
+    return words, integers, sentences
+
+
+def index_inputs(model):
+    """Gets the model inputs that are indices, even if they are floats.
+
+    Args:
+        model (keras Model): model being converted to C
+
+    Returns:
+        names (set): names of the model inputs going into an Embedding
+    """
+
+    names = set()
+    for layer in model.layers:
+        if layer_type(layer) == 'Embedding':
+            names |= set(flatten(get_layer_io_names(layer)[0]))
+    return names
+
+
+def random_input(model_input, shape, tokens, indices=False):
+    """Generates a random input for a model input.
+
+    Args:
+        model_input (keras Tensor): model input
+        shape (tuple): shape of the random input
+        tokens (tuple): values for string and integer inputs, see test_tokens
+        indices (bool): whether the input holds indices
+
+    Returns:
+        rand_input (np.ndarray): random input
+    """
+
+    words, integers, sentences = tokens
+    size = int(np.prod(shape))
+    if model_input.dtype == tf.string:
+        if sentences:
+            texts = [' '.join(np.random.choice(words, np.random.randint(1, 8))
+                              ).capitalize() + '.' for _ in range(size)]
+        else:
+            texts = [str(w) for w in np.random.choice(words, size)]
+        return np.array(texts, dtype=object).reshape(shape)
+    if model_input.dtype.is_integer or indices:
+        return np.random.choice(integers, size).reshape(shape)
+    return 4*np.random.random(shape) - 2
+
+
 def make_test_suite(model, function_name, package_name, num_tests=10, stateful=False, verbose=True, tol=1e-3):
     """Generates code to test the generated C function.
 
@@ -88,6 +174,9 @@ def make_test_suite(model, function_name, package_name, num_tests=10, stateful=F
 }\n\n"""
     file.write(s)
 
+    tokens = test_tokens(model)
+    indices = index_inputs(model)
+    input_types = get_input_types(model)
     for i in range(num_tests):
         if i == num_tests//2 and stateful:
             model.reset_states()
@@ -96,7 +185,8 @@ def make_test_suite(model, function_name, package_name, num_tests=10, stateful=F
         while True:
             rand_inputs = []
             for j, _ in enumerate(model_inputs):
-                rand_input = 4*np.random.random(input_shape[j]) - 2
+                rand_input = random_input(
+                    model.inputs[j], input_shape[j], tokens, model_inputs[j] in indices)
                 if not stateful:
                     rand_input = rand_input[np.newaxis, ...]
                 rand_inputs.insert(j, rand_input)
@@ -110,8 +200,19 @@ def make_test_suite(model, function_name, package_name, num_tests=10, stateful=F
                 raise Exception('Cannot find inputs to the \
                 network that result in a finite output')
         for j, _ in enumerate(model_inputs):
-            file.write(Weights2C.array2c((rand_inputs[j][0, :]), 'test' + str(i+1) +
-                                         '_' + model_inputs[j] + '_input'))
+            if input_types[j] == '[]string':
+                file.write('var test' + str(i+1) + '_' + model_inputs[j] +
+                           '_input = []string{' +
+                           ','.join(go_string(t) for t in np.reshape(rand_inputs[j][0], -1)) +
+                           '}\n')
+            elif input_types[j] == '[]int':
+                file.write('var test' + str(i+1) + '_' + model_inputs[j] +
+                           '_input = []int{' +
+                           ','.join(str(int(t)) for t in np.reshape(rand_inputs[j][0], -1)) +
+                           '}\n')
+            else:
+                file.write(Weights2C.array2c((rand_inputs[j][0, :]), 'test' + str(i+1) +
+                                             '_' + model_inputs[j] + '_input'))
 
             # write predictions
         if not isinstance(outputs, list):
@@ -128,21 +229,22 @@ def make_test_suite(model, function_name, package_name, num_tests=10, stateful=F
     s += 'var num_outputs = ' + str(num_outputs) + '\n'
 
     if stateful:
-        reset_sig = function_name + '_reset_states()'
-        s += reset_sig
+        s += 'var states = ' + function_name + '_new_states()\n'
     s += 'var t0 = time.Now()\n'
     file.write(s)
 
     for i in range(num_tests):
         if i == num_tests//2 and stateful:
-            file.write(reset_sig)
-        s = function_name + '('
-        model_in = ['&test' + str(i+1) + '_' + inp +
-                    '_input' for inp in model_inputs]
+            file.write('states.Reset()\n')
+        s = 'if err := ' + function_name + '('
+        if stateful:
+            s += 'states,'
+        model_in = [('&' if input_types[j] == '*keras2go.K2c_tensor' else '') + 'test' +
+                    str(i+1) + '_' + inp + '_input' for j, inp in enumerate(model_inputs)]
         model_out = ['&c_' + outp + '_test' +
                      str(i+1) for outp in model_outputs]
         s += ','.join(model_in + model_out)
-        s += ')\n'
+        s += '); err != nil {\nt.Fatal(err)\n}\n'
         file.write(s)
     file.write('\n')
     s = 'var t1 = time.Now()\n'
@@ -0,0 +1,128 @@
+"""submodels.py
+This file is part of keras2go
+Copyright 2020 Rory Conlin
+Licensed under MIT License
+https://github.com/f0uriest/keras2c
+
+Helper functions to inline nested models, ie Functional and Sequential
+models used as layers of another model
+"""
+
+# imports
+import tensorflow as tf
+from keras2go.tf_ops import is_keras_tensor
+
+__author__ = "Rory Conlin"
+__copyright__ = "Copyright 2020, Rory Conlin"
+__license__ = "MIT"
+__maintainer__ = "Rory Conlin, https://github.com/f0uriest/keras2c"
+__email__ = "wconlin@princeton.edu"
+
+
+def is_submodel(layer):
+    """Checks if a layer is a Functional or Sequential model
+
+    Subclassed models have no graph of layers to inline, they are custom
+    layers, see K2c_register_layer.
+
+    Args:
+        layer (keras Layer): layer to check
+
+    Returns:
+        submodel (bool): 'True' if the layer is a model made of layers
+    """
+
+    return isinstance(layer, tf.keras.Sequential) or \
+        getattr(layer, '_is_graph_network', False)
+
+
+def inline_submodels(model, custom_objects=None):
+    """Rebuilds a model with the layers of its submodels inlined
+
+    Each layer is cloned once, with the weights of the original, and the
+    clone is called wherever the original was, so that a layer shared
+    between several calls keeps one set of weights. Layers of a submodel get
+    the name of the submodel as a prefix, eg 'encoder_dense', and a suffix if
+    that name is already taken, eg 'encoder_dense_1'.
+
+    Args:
+        model (keras Model): model to inline
+        custom_objects (dict): custom layers and functions needed to clone
+            the layers
+
+    Returns:
+        model (keras Model): model without submodels, or the model itself if
+            it has none
+    """
+
+    if not any(is_submodel(layer) for layer in model.layers):
+        return model
+    clones = {}
+    # the layers of the model keep their names
+    names = set(layer.name for layer in model.layers if not is_submodel(layer))
+    with tf.keras.utils.custom_object_scope(custom_objects or {}):
+        outputs = _call_model(model, model.inputs, '', clones, names)
+    return tf.keras.Model(model.inputs, outputs, name=model.name)
+
+
+def _unique_name(name, names):
+    # inlined names may collide with the names of other layers, eg a layer
+    # 'encoder_dense' next to a submodel 'encoder' with a layer 'dense'
+    unique = name
+    k = 1
+    while unique in names:
+        unique = name + '_' + str(k)
+        k += 1
+    names.add(unique)
+    return unique
+
+
+def _call_model(model, inputs, prefix, clones, names):
+    # calls the clones of the layers of a model on new input tensors, and
+    # returns its output tensors
+    if not getattr(model, '_is_graph_network', False):
+        # sequential model that was never built from an input shape
+        x = inputs[0]
+        for layer in model.layers:
+            x = _call_layer(layer, (x,), {}, prefix, clones, names)
+        return [x]
+    tensors = {id(old): new for old, new in zip(model.inputs, inputs)}
+
+    def replace(value):
+        if is_keras_tensor(value):
+            return tensors[id(value)]
+        return value
+
+    for depth in sorted(model._nodes_by_depth, reverse=True):
+        for node in model._nodes_by_depth[depth]:
+            if node.is_input:
+                continue
+            args = tf.nest.map_structure(replace, node.call_args)
+            kwargs = tf.nest.map_structure(replace, node.call_kwargs)
+            outputs = _call_layer(node.layer, args, kwargs, prefix, clones,
+                                  names)
+            for old, new in zip(tf.nest.flatten(node.outputs),
+                                tf.nest.flatten(outputs)):
+                tensors[id(old)] = new
+    return [tensors[id(t)] for t in model.outputs]
+
+
+def _call_layer(layer, args, kwargs, prefix, clones, names):
+    if is_submodel(layer):
+        inputs = args[0] if args else kwargs['inputs']
+        if isinstance(inputs, dict):
+            inputs = [inputs[name] for name in layer.input_names]
+        outputs = _call_model(layer, tf.nest.flatten(inputs),
+                              prefix + layer.name + '_', clones, names)
+        return outputs[0] if len(outputs) == 1 else outputs
+    if id(layer) in clones:
+        return clones[id(layer)](*args, **kwargs)
+    config = layer.get_config()
+    config['name'] = layer.name
+    if prefix:
+        config['name'] = _unique_name(prefix + layer.name, names)
+    clone = layer.__class__.from_config(config)
+    clones[id(layer)] = clone
+    outputs = clone(*args, **kwargs)
+    clone.set_weights(layer.get_weights())
+    return outputs
@@ -0,0 +1,403 @@
+"""tf_ops.py
+This file is part of keras2go
+Copyright 2020 Rory Conlin
+Licensed under MIT License
+https://github.com/f0uriest/keras2c
+
+Helper functions to parse the tf functions called on keras tensors, ie
+TFOpLambda layers
+"""
+
+# imports
+import numbers
+import numpy as np
+from keras2go.io_parsing import get_call_args, layer_type, get_layer_io_names, \
+    get_layer_num_io, get_model_io_names, get_consumers, get_tensor_name
+
+__author__ = "Rory Conlin"
+__copyright__ = "Copyright 2020, Rory Conlin"
+__license__ = "MIT"
+__maintainer__ = "Rory Conlin, https://github.com/f0uriest/keras2c"
+__email__ = "wconlin@princeton.edu"
+
+
+# go operations of the supported element-wise functions, by tf symbol
+UNARY_OPS = {
+    'math.abs': 'K2C_UNARY_ABS',
+    'math.negative': 'K2C_UNARY_NEGATIVE',
+    'math.sign': 'K2C_UNARY_SIGN',
+    'math.square': 'K2C_UNARY_SQUARE',
+    'math.sqrt': 'K2C_UNARY_SQRT',
+    'math.rsqrt': 'K2C_UNARY_RSQRT',
+    'math.reciprocal': 'K2C_UNARY_RECIPROCAL',
+    'math.exp': 'K2C_UNARY_EXP',
+    'math.expm1': 'K2C_UNARY_EXPM1',
+    'math.log': 'K2C_UNARY_LOG',
+    'math.log1p': 'K2C_UNARY_LOG1P',
+    'math.sin': 'K2C_UNARY_SIN',
+    'math.cos': 'K2C_UNARY_COS',
+    'math.tan': 'K2C_UNARY_TAN',
+    'math.tanh': 'K2C_UNARY_TANH',
+    'math.sigmoid': 'K2C_UNARY_SIGMOID',
+    'math.erf': 'K2C_UNARY_ERF',
+    'math.floor': 'K2C_UNARY_FLOOR',
+    'math.ceil': 'K2C_UNARY_CEIL',
+    'math.round': 'K2C_UNARY_ROUND',
+    'math.logical_not': 'K2C_UNARY_LOGICAL_NOT',
+}
+
+BINARY_OPS = {
+    'math.add': 'K2C_BINARY_ADD',
+    '__operators__.add': 'K2C_BINARY_ADD',
+    'math.subtract': 'K2C_BINARY_SUBTRACT',
+    'math.multiply': 'K2C_BINARY_MULTIPLY',
+    'math.truediv': 'K2C_BINARY_DIVIDE',
+    'math.divide': 'K2C_BINARY_DIVIDE',
+    'math.floordiv': 'K2C_BINARY_FLOORDIV',
+    'math.floormod': 'K2C_BINARY_FLOORMOD',
+    'math.pow': 'K2C_BINARY_POW',
+    'math.maximum': 'K2C_BINARY_MAXIMUM',
+    'math.minimum': 'K2C_BINARY_MINIMUM',
+    'math.squared_difference': 'K2C_BINARY_SQUARED_DIFFERENCE',
+    'math.equal': 'K2C_BINARY_EQUAL',
+    'math.not_equal': 'K2C_BINARY_NOT_EQUAL',
+    'math.less': 'K2C_BINARY_LESS',
+    'math.less_equal': 'K2C_BINARY_LESS_EQUAL',
+    'math.greater': 'K2C_BINARY_GREATER',
+    'math.greater_equal': 'K2C_BINARY_GREATER_EQUAL',
+    'math.logical_and': 'K2C_BINARY_LOGICAL_AND',
+    'math.logical_or': 'K2C_BINARY_LOGICAL_OR',
+}
+
+REDUCE_OPS = {
+    'math.reduce_sum': 'K2C_REDUCE_SUM',
+    'math.reduce_mean': 'K2C_REDUCE_MEAN',
+    'math.reduce_max': 'K2C_REDUCE_MAX',
+    'math.reduce_min': 'K2C_REDUCE_MIN',
+}
+
+# combiners of an Embedding followed by a reduction of each bag of indices
+COMBINERS = {
+    'GlobalAveragePooling1D': 'K2C_COMBINER_MEAN',
+    'math.reduce_sum': 'K2C_COMBINER_SUM',
+    'math.reduce_mean': 'K2C_COMBINER_MEAN',
+}
+
+# other supported functions, by tf symbol
+OTHER_OPS = {
+    '__operators__.getitem': 'getitem',
+    'strided_slice': 'strided_slice',
+    'slice': 'slice',
+    'gather': 'gather',
+    'compat.v1.gather': 'gather',
+    'squeeze': 'squeeze',
+    'compat.v1.squeeze': 'squeeze',
+    'expand_dims': 'expand_dims',
+    'compat.v1.expand_dims': 'expand_dims',
+    'where': 'where',
+    'einsum': 'einsum',
+    'linalg.einsum': 'einsum',
+}
+
+# names of the positional arguments of each kind of function
+ARG_NAMES = {
+    'unary': ['x'],
+    'binary': ['x', 'y'],
+    'reduce': ['input_tensor', 'axis', 'keepdims'],
+    'getitem': ['tensor', 'slice_spec'],
+    'strided_slice': ['input_', 'begin', 'end', 'strides', 'begin_mask',
+                      'end_mask', 'ellipsis_mask', 'new_axis_mask',
+                      'shrink_axis_mask'],
+    'slice': ['input_', 'begin', 'size'],
+    'gather': ['params', 'indices', 'validate_indices', 'axis', 'batch_dims'],
+    'squeeze': ['input', 'axis'],
+    'expand_dims': ['input', 'axis'],
+    'where': ['condition', 'x', 'y'],
+    'einsum': ['equation', 'a', 'b'],
+}
+
+
+def op_kind(layer):
+    """Gets the kind of tf function a TFOpLambda layer calls
+
+    Args:
+        layer (keras Layer): TFOpLambda layer
+
+    Returns:
+        kind (str): one of the keys of ARG_NAMES, or None if the function
+            is not supported
+    """
+
+    symbol = layer.get_config()['function']
+    if symbol in UNARY_OPS:
+        return 'unary'
+    if symbol in BINARY_OPS:
+        return 'binary'
+    if symbol in REDUCE_OPS:
+        return 'reduce'
+    return OTHER_OPS.get(symbol)
+
+
+def op_args(layer, node_index=0):
+    """Gets the arguments of the tf function a TFOpLambda layer calls
+
+    Args:
+        layer (keras Layer): TFOpLambda layer
+        node_index (int): which call of the layer to parse
+
+    Returns:
+        args (dict): arguments of the function, by name
+    """
+
+    return get_call_args(layer, node_index, ARG_NAMES[op_kind(layer)])
+
+
+def is_keras_tensor(value):
+    """Checks if an argument of a tf function is a keras tensor, or a constant
+
+    Args:
+        value: argument to check
+
+    Returns:
+        tensor (bool): 'True' if value is the output of a layer
+    """
+
+    return hasattr(value, '_keras_history')
+
+
+def go_axes(axes, rank):
+    """Converts keras axes to axes of the go tensors, which have no batch axis
+
+    Args:
+        axes (int or list): axes, may be negative
+        rank (int): rank of the keras tensor, with the batch axis
+
+    Returns:
+        axes (list): axes of the go tensor
+
+    Raises:
+        ValueError: if one of the axes is the batch axis
+    """
+
+    axes = [int(axis) % rank for axis in np.reshape(axes, (-1,))]
+    if 0 in axes:
+        raise ValueError('axis 0 is the batch axis')
+    return [axis - 1 for axis in axes]
+
+
+def constant_operand(value, rank):
+    """Gets a constant operand of an element-wise function as an array
+
+    Args:
+        value: constant argument
+        rank (int): rank of the keras output tensor, with the batch axis
+
+    Returns:
+        array (np.ndarray): constant, without the batch axis
+
+    Raises:
+        ValueError: if the constant varies along the batch axis
+    """
+
+    array = np.array(value, dtype=float)
+    if array.ndim == rank:
+        if array.shape[0] != 1:
+            raise ValueError('constant varies along the batch axis')
+        array = array[0]
+    return np.reshape(array, array.shape or (1,))
+
+
+def slice_spec(layer, node_index=0):
+    """Gets the python indexing of a slicing function
+
+    Args:
+        layer (keras Layer): TFOpLambda layer calling tf.slice,
+            tf.strided_slice or indexing a tensor
+        node_index (int): which call of the layer to parse
+
+    Returns:
+        tensor (keras Tensor): tensor being sliced
+        spec (tuple): python indexing of the tensor
+    """
+
+    kind = op_kind(layer)
+    args = op_args(layer, node_index)
+    if kind == 'getitem':
+        spec = args['slice_spec']
+        if not isinstance(spec, tuple):
+            spec = (spec,)
+        # slices of a saved model are serialized as dicts
+        spec = tuple(slice(s['start'], s['stop'], s['step'])
+                     if isinstance(s, dict) else s for s in spec)
+        return args['tensor'], spec
+    begin = np.reshape(args['begin'], (-1,)).astype(int)
+    if kind == 'slice':
+        size = np.reshape(args['size'], (-1,)).astype(int)
+        return args['input_'], tuple(slice(b, None if s == -1 else b + s)
+                                     for b, s in zip(begin, size))
+    end = np.reshape(args['end'], (-1,)).astype(int)
+    strides = args.get('strides')
+    strides = np.ones(len(begin), dtype=int) if strides is None else \
+        np.reshape(strides, (-1,)).astype(int)
+    spec = []
+    for k in range(len(begin)):
+        if args.get('ellipsis_mask', 0) >> k & 1:
+            spec.append(Ellipsis)
+        elif args.get('new_axis_mask', 0) >> k & 1:
+            spec.append(None)
+        elif args.get('shrink_axis_mask', 0) >> k & 1:
+            spec.append(int(begin[k]))
+        else:
+            spec.append(slice(None if args.get('begin_mask', 0) >> k & 1 else int(begin[k]),
+                              None if args.get('end_mask', 0) >> k & 1 else int(end[k]),
+                              int(strides[k])))
+    return args['input_'], tuple(spec)
+
+
+def resolve_slice(shape, spec):
+    """Resolves a python indexing into the begin, size and strides of each axis
+
+    Args:
+        shape (tuple): shape of the keras tensor, with the batch axis
+        spec (tuple): python indexing of the tensor
+
+    Returns:
+        begin (list): first index along each axis, without the batch axis
+        size (list): number of indices along each axis
+        strides (list): step between indices along each axis
+
+    Raises:
+        ValueError: if the indexing is not made of constant ints and slices,
+            or does not keep the whole batch axis
+    """
+
+    num_indexed = len([s for s in spec if s is not None and s is not Ellipsis])
+    axes = []
+    for s in spec:
+        if s is Ellipsis:
+            axes += [slice(None)] * (len(shape) - num_indexed)
+        elif s is not None:
+            axes.append(s)
+    axes += [slice(None)] * (len(shape) - len(axes))
+    batch = axes[0]
+    if not isinstance(batch, slice) or batch.start not in [None, 0] or \
+       batch.stop is not None or batch.step not in [None, 1]:
+        raise ValueError('slicing the batch axis')
+    begin, size, strides = [], [], []
+    for s, dim in zip(axes[1:], shape[1:]):
+        if isinstance(s, numbers.Integral):
+            begin.append(int(s) % dim)
+            size.append(1)
+            strides.append(1)
+        elif isinstance(s, slice) and \
+                all(v is None or isinstance(v, numbers.Integral)
+                    for v in [s.start, s.stop, s.step]):
+            r = range(*s.indices(dim))
+            begin.append(r.start if len(r) > 0 else 0)
+            size.append(len(r))
+            strides.append(r.step)
+        else:
+            raise ValueError('indexing with tensors')
+    return begin, size, strides
+
+
+def go_equation(equation, batched):
+    """Converts a keras einsum equation to the go tensors, which have no batch axis
+
+    The batch axis is the first label of the output and of each batched
+    operand, unless they start with an ellipsis.
+
+    Args:
+        equation (str): einsum equation, eg 'abc,cde->abde'
+        batched (list): whether each operand has a batch axis
+
+    Returns:
+        equation (str): einsum equation of the go tensors, eg 'bc,cde->bde'
+
+    Raises:
+        ValueError: if the batch axis can not be told apart, or a label is
+            repeated within a term, ie a diagonal
+    """
+
+    equation = equation.replace(' ', '')
+    if '->' not in equation:
+        raise ValueError('einsum without an explicit output')
+    inputs, output = equation.split('->')
+    terms = inputs.split(',')
+    for term in terms + [output]:
+        labels = term.replace('...', '')
+        if len(set(labels)) != len(labels):
+            raise ValueError('repeated labels, ie diagonals')
+    batch = None
+    for k, term in enumerate(terms):
+        if batched[k] and not term.startswith('...'):
+            if batch is not None and term[0] != batch:
+                raise ValueError('operands with different batch labels')
+            batch = term[0]
+            terms[k] = term[1:]
+    if batch is not None:
+        if not output.startswith(batch) or \
+           any(batch in term for term in terms + [output[1:]]):
+            raise ValueError('batch label not only first')
+        output = output[1:]
+    elif not output.startswith('...'):
+        raise ValueError('batch axis not first in the output')
+    return ','.join(terms) + '->' + output
+
+
+def _bag_combiner(embedding, outp, consumer):
+    # the consumer must reduce the last axis of the indices, ie the axis
+    # before the vectors, and only reduce ops that skip the mask can drop it
+    if layer_type(consumer) == 'GlobalAveragePooling1D':
+        if consumer.get_config().get('data_format') == 'channels_first':
+            return None
+        return COMBINERS['GlobalAveragePooling1D']
+    if layer_type(consumer) != 'TFOpLambda' or op_kind(consumer) != 'reduce':
+        return None
+    symbol = consumer.get_config()['function']
+    args = op_args(consumer)
+    tensor = args['input_tensor']
+    if symbol not in COMBINERS or embedding.get_config().get('mask_zero') or \
+       not is_keras_tensor(tensor) or get_tensor_name(tensor) != outp or \
+       args.get('axis') is None:
+        return None
+    rank = len(tensor.shape)
+    axes = sorted(set(int(axis) % rank for axis in np.reshape(args['axis'], (-1,))))
+    if rank < 3 or axes != [rank - 2]:
+        return None
+    return COMBINERS[symbol]
+
+
+def embedding_combiners(model):
+    """Finds the calls of Embedding layers that are looked up with a combiner
+
+    An Embedding whose only consumer averages or sums the vectors of each bag
+    of indices, ie GlobalAveragePooling1D or reduce_sum or reduce_mean over
+    the last axis of the indices, writes the combined vectors directly to the
+    output of the consumer.
+
+    Args:
+        model (keras Model): model to parse
+
+    Returns:
+        combiners (dict): by output name of the Embedding call, the go
+            combiner and the output name of the consumer it replaces
+    """
+
+    model_outputs = get_model_io_names(model)[1]
+    combiners = {}
+    for layer in model.layers:
+        if layer_type(layer) != 'Embedding':
+            continue
+        for outp in get_layer_io_names(layer)[1]:
+            consumers = get_consumers(model, outp)
+            if outp in model_outputs or len(consumers) != 1:
+                continue
+            consumer, _ = consumers[0]
+            if get_layer_num_io(consumer)[0] != 1:
+                continue
+            combiner = _bag_combiner(layer, outp, consumer)
+            if combiner is not None:
+                combiners[outp] = (combiner,
+                                   get_layer_io_names(consumer)[1][0])
+    return combiners
@@ -8,8 +8,13 @@ Gets weights and other parameters from each layer and writes to C file
 """
 
 # imports
+import json
 import numpy as np
-from keras2go.io_parsing import layer_type, get_layer_io_names, get_model_io_names
+from keras2go.io_parsing import layer_type, get_layer_io_names, get_model_io_names, has_mask, \
+    get_call_args, is_custom_layer, get_layer_config, get_layer_num_io, get_node_name, flatten, \
+    go_string, custom_layer_name
+from keras2go.tf_ops import op_kind, op_args, is_keras_tensor, go_axes, \
+    constant_operand, slice_spec, resolve_slice, go_equation, embedding_combiners
 from tensorflow.keras import backend as K
 import tensorflow as tf
 tf.compat.v1.disable_eager_execution()
@@ -29,15 +34,25 @@ class Weights2C():
     Args:
         model (keras Model): model to parse
         function_name (str): name of the function being generated
+        embedding_oov (str): handling of out of vocabulary indices in
+            Embedding layers, one of 'error', 'zero' or 'bucket'
     """
 
-    def __init__(self, model, function_name):
+    def __init__(self, model, function_name, embedding_oov='error'):
 
         self.model = model
         self.function_name = function_name
+        self.embedding_oov = embedding_oov
         self.model_io = get_model_io_names(self.model)
+        # Embedding calls written to the output of the layer reducing them
+        self.combiners = embedding_combiners(self.model)
+        self.combined = set(c[1] for c in self.combiners.values())
+        # layers writing straight to the outputs of their wrapper
+        self.unbuffered = set()
         self.stack_vars = ''
         self.static_vars = {}
+        # package level variables other than states, eg vocabularies
+        self.package_vars = ''
 
     @staticmethod
     def array2c(array, name):
@@ -83,6 +98,8 @@ class Weights2C():
         self.stack_vars += temp
 
     def _write_weights_layer(self, layer):
+        if is_custom_layer(layer):
+            return self._write_weights_custom(layer)
         method = getattr(self, '_write_weights_' + layer_type(layer))
         return method(layer)
 
@@ -96,40 +113,55 @@ class Weights2C():
             (tuple): tuple containing
 
                 - **stack_vars** (*str*): code for variables allocated on the stack
-                - **static_vars** (*str*): code fora C struct containing static variables
-                    (eg, states of a stateful RNN)
+                - **static_vars** (*str*): code for the package level variables of
+                    the model (eg, states of a stateful RNN, vocabularies)
         """
         for layer in self.model.layers:
-            method = getattr(self, '_write_weights_' + layer_type(layer))
-            method(layer)
+            self._write_weights_layer(layer)
         return self.stack_vars, self._write_static_vars()
 
     def _write_static_vars(self):
         if len(self.static_vars) > 0:
-            s = 'static struct ' + self.function_name + '_static_vars \n'
-            s += '{ \n'
+            # the states are passed to each call, so that streams do not share them
+            s = 'func ' + self.function_name + '_new_states() keras2go.K2c_states {\n'
+            s += 'return keras2go.K2c_states{\n'
             for k, v in self.static_vars.items():
-                s += 'float ' + k + '[' + str(v) + ']; \n'
-            s += '} ' + self.function_name + '_states; \n'
+                s += '\t"' + k + '": keras2go.K2c_new_state(' + str(v) + '),\n'
+            s += '}\n}\n'
         else:
             s = ''
-        return s
+        return s + self.package_vars
+
+    def _write_state(self, layer, size):
+        if layer.get_config()['stateful']:
+            # kept between calls in the states passed to the model
+            self.static_vars.update({layer.name: size})
+            self.stack_vars += 'var ' + layer.name + \
+                '_state = states["' + layer.name + '"].Array\n'
+        else:
+            self.stack_vars += 'var ' + layer.name + \
+                '_state = make([]float64, ' + str(size) + ')\n'
 
     def _write_outputs(self, layer):
+        # one buffer for each output of each call of the layer, eg an RNN
+        # returning its state or a layer shared between several inputs
         _, outputs = get_layer_io_names(layer)
-        if len(outputs) > 1:
-            for i, outp in enumerate(outputs):
-                outshp = layer.get_output_at(i).shape[1:]
-                if outp not in self.model_io[1]:
+        for i, outp in enumerate(outputs):
+            for name, output in zip(flatten(outp), flatten(layer.get_output_at(i))):
+                if name not in self.model_io[1] and name not in self.combiners \
+                        and name not in self.unbuffered:
                     self._write_weights_array2c(
-                        np.zeros(outshp), outp + '_output')
-        else:
-            outshp = layer.output_shape[1:]
-            if outputs[0] not in self.model_io[1]:
-                # self._write_weights_array2c(
-                #     np.zeros(outshp), outputs[0] + '_output')
-                self._write_weights_array2c(
-                    np.zeros(outshp), layer.name + '_output')
+                        np.zeros(output.shape[1:]), name + '_output')
+
+    def _write_mask(self, layer):
+        # masks have one entry per timestep, and one mask per call
+        num_inputs, _ = get_layer_num_io(layer)
+        _, outputs = get_layer_io_names(layer)
+        for i in range(num_inputs):
+            if has_mask(layer, i) and flatten(outputs[i])[0] not in self.combiners:
+                self.stack_vars += 'var ' + get_node_name(layer, i) + \
+                    '_mask = make([]bool, ' + \
+                    str(flatten(layer.get_output_at(i))[0].shape[1]) + ')\n'
 
     def _write_weights_Bidirectional(self, layer):
         try:
@@ -141,28 +173,14 @@ class Weights2C():
             foo = layer.layer.__call__(temp_input)
             foo = layer.forward_layer.__call__(temp_input)
             foo = layer.backward_layer.__call__(temp_input)
+        if layer.merge_mode is None:
+            # the unmerged outputs of the wrapper are the outputs of the layers
+            self.unbuffered.update([layer.forward_layer.name,
+                                    layer.backward_layer.name])
         self._write_weights_layer(layer.backward_layer)
         self._write_weights_layer(layer.forward_layer)
-        if layer.merge_mode:
-
-            self._write_outputs(layer)
-            self.stack_vars += 'size_t ' + layer.name + '_num_tensors' + str(0) + \
-                ' = ' + str(2) + '; \n'
-            if layer.merge_mode == 'concat':
-                if layer.return_sequences:
-                    ax = 1
-                else:
-                    ax = 0
-                self.stack_vars += 'size_t ' + layer.name + '_axis = ' +\
-                    str(ax) + '; \n'
-
-        else:
-            output_names = get_layer_io_names(layer)[1][0]
-            subname = layer.layer.name
-            self.stack_vars += 'k2c_tensor * ' + \
-                output_names[0] + ' = forward_' + subname + '_output; \n'
-            self.stack_vars += 'k2c_tensor * ' + \
-                output_names[1] + ' = backward_' + subname + '_output; \n'
+        self._write_outputs(layer)
+        self.stack_vars += '\n\n'
 
     def _write_weights_TimeDistributed(self, layer):
         self._write_outputs(layer)
@@ -173,20 +191,7 @@ class Weights2C():
                 layer.input_shape[2:], batch_size=1)
             foo = layer.layer.__call__(temp_input)
         self._write_weights_layer(layer.layer)
-        timeslice_input = np.squeeze(np.zeros(layer.layer.input_shape[1:]))
-        timeslice_output = np.squeeze(np.zeros(layer.layer.output_shape[1:]))
-        self._write_weights_array2c(
-            timeslice_input, layer.layer.name + '_timeslice_input')
-        self._write_weights_array2c(
-            timeslice_output, layer.layer.name + '_timeslice_output')
-        self.stack_vars += 'const size_t ' + layer.name +\
-                           '_timesteps = ' + str(layer.input_shape[1]) + '; \n'
-        self.stack_vars += 'const size_t ' + layer.name +\
-                           '_in_offset = ' + \
-            str(np.prod(layer.input_shape[2:])) + '; \n'
-        self.stack_vars += 'const size_t ' + layer.name +\
-                           '_out_offset = ' + \
-            str(np.prod(layer.output_shape[2:])) + '; \n'
+        self.stack_vars += '\n\n'
 
     def _write_weights_Input(self, layer):
         self.stack_vars += ''
@@ -235,66 +240,186 @@ class Weights2C():
         self._write_weights_array2c(beta, layer.name + '_beta')
         self.stack_vars += '\n\n'
 
-    def _write_weights_LSTM(self, layer):
-        units = layer.get_config()['units']
+    @staticmethod
+    def _normalization_axes(layer):
+        # keras axes count the batch dimension, the kernels don't. keras
+        # reshapes the weights along the axes in increasing order
+        ndim = len(layer.input_shape) - 1
+        axes = layer.get_config()['axis']
+        if not isinstance(axes, (list, tuple, np.ndarray)):
+            axes = [axes]
+        return sorted(a + ndim if a < 0 else a - 1 for a in axes)
+
+    def _write_weights_LayerNormalization(self, layer):
+        nm = layer.name
+        axes = self._normalization_axes(layer)
+        weights = layer.get_weights()
+        self._write_outputs(layer)
+        self.stack_vars += 'var ' + nm + '_axes = []int{' + \
+            ','.join([str(i) for i in axes]) + '}\n'
+        self.stack_vars += 'var ' + nm + '_epsilon = ' + \
+            str(float(layer.get_config()['epsilon'])) + '\n'
+        if layer.get_config()['scale']:
+            self._write_weights_array2c(weights.pop(0), nm + '_gamma')
+        if layer.get_config()['center']:
+            self._write_weights_array2c(weights.pop(0), nm + '_beta')
+        self.stack_vars += '\n\n'
+
+    def _write_weights_GroupNormalization(self, layer):
+        nm = layer.name
+        axis = self._normalization_axes(layer)[0]
+        weights = layer.get_weights()
+        self._write_outputs(layer)
+        self.stack_vars += 'var ' + nm + '_axis = ' + str(axis) + '\n'
+        groups = layer.get_config()['groups']
+        if groups == -1:
+            # one group per channel
+            groups = layer.input_shape[axis + 1]
+        self.stack_vars += 'var ' + nm + '_groups = ' + str(groups) + '\n'
+        self.stack_vars += 'var ' + nm + '_epsilon = ' + \
+            str(float(layer.get_config()['epsilon'])) + '\n'
+        if layer.get_config()['scale']:
+            self._write_weights_array2c(weights.pop(0), nm + '_gamma')
+        if layer.get_config()['center']:
+            self._write_weights_array2c(weights.pop(0), nm + '_beta')
+        self.stack_vars += '\n\n'
+
+    def _write_weights_UnitNormalization(self, layer):
+        nm = layer.name
+        axes = self._normalization_axes(layer)
+        self._write_outputs(layer)
+        self.stack_vars += 'var ' + nm + '_axes = []int{' + \
+            ','.join([str(i) for i in axes]) + '}\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_Attention(self, layer):
+        nm = layer.name
+        qshp, vshp = layer.input_shape[0], layer.input_shape[1]
+        kshp = layer.input_shape[2] if len(layer.input_shape) > 2 else vshp
+        if layer.get_config()['use_scale']:
+            scale = float(layer.get_weights()[0])
+        else:
+            scale = 1.0
+        self._write_outputs(layer)
+        self.stack_vars += 'var ' + nm + '_scale = ' + str(scale) + '\n'
+        self.stack_vars += 'var ' + nm + '_fwork = make([]float64, ' + \
+            str(qshp[1]*vshp[1] + np.prod(qshp[1:]) + np.prod(kshp[1:])) + \
+            ')\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_AdditiveAttention(self, layer):
+        nm = layer.name
+        qshp, vshp = layer.input_shape[0], layer.input_shape[1]
+        self._write_outputs(layer)
+        if layer.get_config()['use_scale']:
+            self._write_weights_array2c(layer.get_weights()[0], nm + '_scale')
+        self.stack_vars += 'var ' + nm + '_fwork = make([]float64, ' + \
+            str(qshp[1]*vshp[1]) + ')\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_MultiHeadAttention(self, layer):
+        nm = layer.name
+        args = get_call_args(layer, 0, ['query', 'value'])
+        T = args['query'].shape[1]
+        S = args['value'].shape[1]
+        weights = layer.get_weights()
+        if layer.get_config()['use_bias']:
+            qk, qb, kk, kb, vk, vb, ok, ob = weights
+        else:
+            qk, kk, vk, ok = weights
+            qb = np.zeros(qk.shape[1:])
+            kb = np.zeros(kk.shape[1:])
+            vb = np.zeros(vk.shape[1:])
+            ob = np.zeros(ok.shape[2:])
+        num_heads, key_dim, value_dim = qk.shape[1], qk.shape[2], vk.shape[2]
+        self._write_outputs(layer)
+        self._write_weights_array2c(qk, nm + '_query_kernel')
+        self._write_weights_array2c(qb, nm + '_query_bias')
+        self._write_weights_array2c(kk, nm + '_key_kernel')
+        self._write_weights_array2c(kb, nm + '_key_bias')
+        self._write_weights_array2c(vk, nm + '_value_kernel')
+        self._write_weights_array2c(vb, nm + '_value_bias')
+        self._write_weights_array2c(ok, nm + '_output_kernel')
+        self._write_weights_array2c(ob, nm + '_output_bias')
+        self.stack_vars += 'var ' + nm + '_fwork = make([]float64, ' + \
+            str((T+S)*num_heads*(key_dim+value_dim) + T*S) + ')\n'
+        self.stack_vars += '\n\n'
+
+    def _write_rnn(self, layer):
+        # settings and state of a recurrent layer, the weights are in its cell
         self._write_outputs(layer)
-        self.stack_vars += 'var ' + layer.name + \
-                           '_fwork = make([]float64, ' + str(8*units) + ') \n'
         self.stack_vars += 'var ' + layer.name + '_go_backwards = ' + \
             str(int(layer.get_config()['go_backwards'])) + ';\n'
         self.stack_vars += 'var ' + layer.name + '_return_sequences = ' + \
             str(int(layer.get_config()['return_sequences'])) + ';\n'
-        if layer.get_config()['stateful']:
-            self.static_vars.update({layer.name + '_state': 2*units})
-            self.stack_vars += 'float * ' + layer.name + '_state = ' + \
-                self.function_name + '_states.' + \
-                layer.name + '_state; \n'
-        else:
-            self.stack_vars += 'var ' + layer.name + \
-                               '_state = make([]float64, ' + str(2*units) + ')\n'
+        size = self._write_cell_weights(layer.cell, layer.name)
+        self._write_state(layer, size)
+        self.stack_vars += '\n \n'
 
-        weights = layer.get_weights()
+    def _write_weights_RNN(self, layer):
+        self._write_rnn(layer)
+
+    def _write_weights_LSTM(self, layer):
+        self._write_rnn(layer)
+
+    def _write_weights_GRU(self, layer):
+        self._write_rnn(layer)
+
+    def _write_weights_SimpleRNN(self, layer):
+        self._write_rnn(layer)
+
+    def _write_cell_weights(self, cell, nm):
+        """Writes the weights of a recurrent cell
+
+        Args:
+            cell (keras Layer): cell to write
+            nm (str): prefix of the names of the weights
+
+        Returns:
+            size (int): size of the state of the cell
+        """
+        if layer_type(cell) == 'StackedRNNCells':
+            size = 0
+            for k, c in enumerate(cell.cells):
+                size += self._write_cell_weights(c, nm + '_cell' + str(k))
+            return size
+        method = getattr(self, '_write_cell_weights_' + layer_type(cell))
+        return method(cell, nm)
+
+    def _write_cell_weights_LSTMCell(self, cell, nm):
+        units = cell.get_config()['units']
+        self.stack_vars += 'var ' + nm + \
+                           '_fwork = make([]float64, ' + str(8*units) + ') \n'
+
+        weights = cell.get_weights()
         kernel = weights[0]
         recurrent_kernel = weights[1]
-        if layer.get_config()['use_bias']:
+        if cell.get_config()['use_bias']:
             bias = weights[2]
         else:
             bias = np.zeros(4*units)
         ckernel = np.concatenate(np.split(kernel, 4, axis=1), axis=0)
         crecurrent_kernel = np.concatenate(
             np.split(recurrent_kernel, 4, axis=1), axis=0)
-        self._write_weights_array2c(ckernel, layer.name + '_kernel')
+        self._write_weights_array2c(ckernel, nm + '_kernel')
         self._write_weights_array2c(
-            crecurrent_kernel, layer.name + '_recurrent_kernel')
-        self._write_weights_array2c(bias, layer.name + '_bias')
-        self.stack_vars += '\n \n'
+            crecurrent_kernel, nm + '_recurrent_kernel')
+        self._write_weights_array2c(bias, nm + '_bias')
+        return 2*units
 
-    def _write_weights_GRU(self, layer):
-        units = layer.get_config()['units']
-        self._write_outputs(layer)
-        self.stack_vars += 'var ' + layer.name + \
+    def _write_cell_weights_GRUCell(self, cell, nm):
+        units = cell.get_config()['units']
+        self.stack_vars += 'var ' + nm + \
             '_fwork = make([]float64, ' + str(6*units) + ') \n'
-        self.stack_vars += 'var ' + layer.name + '_reset_after = ' + \
-            str(int(layer.get_config()['reset_after'])) + ';\n'
-        self.stack_vars += 'var ' + layer.name + '_go_backwards = ' + \
-            str(int(layer.get_config()['go_backwards'])) + ';\n'
-        self.stack_vars += 'int ' + layer.name + '_return_sequences = ' + \
-            str(int(layer.get_config()['return_sequences'])) + ';\n'
-        if layer.get_config()['stateful']:
-            self.static_vars.update({layer.name + '_state': units})
-            self.stack_vars += 'float * ' + layer.name + '_state = ' + \
-                self.function_name + '_states.' + \
-                layer.name + '_state; \n'
-        else:
-            self.stack_vars += 'var ' + layer.name + \
-                '_state = make([]float64, ' + str(units) + ') \n'
+        self.stack_vars += 'var ' + nm + '_reset_after = ' + \
+            str(int(cell.get_config()['reset_after'])) + ';\n'
 
-        weights = layer.get_weights()
+        weights = cell.get_weights()
         kernel = weights[0]
         recurrent_kernel = weights[1]
-        if layer.get_config()['use_bias']:
+        if cell.get_config()['use_bias']:
             bias = weights[2]
-            if layer.get_config()['reset_after']:
+            if cell.get_config()['reset_after']:
                 rbias = bias[1]
                 bias = bias[0]
             else:
@@ -307,29 +432,50 @@ class Weights2C():
         ckernel = np.concatenate(np.split(kernel, 3, axis=1), axis=0)
         crecurrent_kernel = np.concatenate(
             np.split(recurrent_kernel, 3, axis=1), axis=0)
-        self._write_weights_array2c(ckernel, layer.name + '_kernel')
-        self._write_weights_array2c(crecurrent_kernel, layer.name +
+        self._write_weights_array2c(ckernel, nm + '_kernel')
+        self._write_weights_array2c(crecurrent_kernel, nm +
                                     '_recurrent_kernel')
-        self._write_weights_array2c(cbias, layer.name + '_bias')
-        self.stack_vars += '\n \n'
+        self._write_weights_array2c(cbias, nm + '_bias')
+        return units
 
-    def _write_weights_SimpleRNN(self, layer):
-        units = layer.get_config()['units']
+    def _write_cell_weights_SimpleRNNCell(self, cell, nm):
+        units = cell.get_config()['units']
+        self.stack_vars += 'var ' + nm + \
+            '_fwork = make([]float64, ' + str(2*units) + ')\n'
+
+        weights = cell.get_weights()
+        kernel = weights[0]
+        recurrent_kernel = weights[1]
+        if cell.get_config()['use_bias']:
+            bias = weights[2]
+        else:
+            bias = np.zeros(units)
+        self._write_weights_array2c(kernel, nm + '_kernel')
+        self._write_weights_array2c(recurrent_kernel, nm +
+                                    '_recurrent_kernel')
+        self._write_weights_array2c(bias, nm + '_bias')
+        return units
+
+    def _write_weights_ConvLSTM2D(self, layer):
+        output = layer.get_output_at(0)
+        if isinstance(output, list):
+            # the output is followed by the states when return_state is set
+            output = output[0]
+        units = int(np.prod(output.shape[-3:]))
+        stride = layer.get_config()['strides']
+        dilation = layer.get_config()['dilation_rate']
         self._write_outputs(layer)
+        self.stack_vars += 'var ' + layer.name + \
+                           '_fwork = make([]float64, ' + str(8*units) + ') \n'
+        self.stack_vars += 'var ' + layer.name + '_stride = []int{' + \
+            ','.join([str(i) for i in stride]) + '}\n'
+        self.stack_vars += 'var ' + layer.name + '_dilation = []int{' + \
+            ','.join([str(i) for i in dilation]) + '}\n'
         self.stack_vars += 'var ' + layer.name + '_go_backwards = ' + \
             str(int(layer.get_config()['go_backwards'])) + ';\n'
         self.stack_vars += 'var ' + layer.name + '_return_sequences = ' + \
             str(int(layer.get_config()['return_sequences'])) + ';\n'
-        self.stack_vars += 'var ' + layer.name + \
-            '_fwork = make([]float64, ' + str(2*units) + ')\n'
-        if layer.get_config()['stateful']:
-            self.static_vars.update({layer.name + '_state': units})
-            self.stack_vars += 'float * ' + layer.name + '_state = ' + \
-                self.function_name + '_states.' + \
-                layer.name + '_state; \n'
-        else:
-            self.stack_vars += 'float ' + layer.name + \
-                '_state = make([]float64, ' + str(units) + ') \n'
+        self._write_state(layer, 2*units)
 
         weights = layer.get_weights()
         kernel = weights[0]
@@ -337,10 +483,11 @@ class Weights2C():
         if layer.get_config()['use_bias']:
             bias = weights[2]
         else:
-            bias = np.zeros(units)
+            bias = np.zeros(kernel.shape[-1])
+        # the gates are blocked {input, forget, cell, output} along the last axis, as in LSTM
         self._write_weights_array2c(kernel, layer.name + '_kernel')
-        self._write_weights_array2c(recurrent_kernel, layer.name +
-                                    '_recurrent_kernel')
+        self._write_weights_array2c(
+            recurrent_kernel, layer.name + '_recurrent_kernel')
         self._write_weights_array2c(bias, layer.name + '_bias')
         self.stack_vars += '\n \n'
 
@@ -360,126 +507,165 @@ class Weights2C():
                             np.prod(A.shape)) + ')\n'
         self.stack_vars += '\n \n'
 
+    def _write_weights_EinsumDense(self, layer):
+        nm = layer.name
+        self._write_outputs(layer)
+        weights = layer.get_weights()
+        self._write_weights_array2c(weights[0], nm + '_kernel')
+        if layer.get_config()['bias_axes'] is not None:
+            self._write_weights_array2c(weights[1], nm + '_bias')
+        self._write_einsum_plan(
+            nm, go_equation(layer.get_config()['equation'], [True, False]),
+            [layer.input_shape[1:], weights[0].shape])
+        self.stack_vars += 'var ' + nm + '_fwork = make([]float64, ' + \
+            str(int(np.prod(layer.input_shape[1:]) + weights[0].size +
+                    np.prod(layer.output_shape[1:]))) + ')\n'
+        self.stack_vars += '\n\n'
+
     def _write_weights_Conv1D(self, layer):
-        padding = layer.get_config()['padding']
-        stride = layer.get_config()['strides'][0]
-        dilation = layer.get_config()['dilation_rate'][0]
-        kernel_size = layer.get_config()['kernel_size'][0]
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_stride = ' + str(stride) + '; \n'
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_dilation = ' + str(dilation) + '; \n'
+        return self._write_weights_Conv(layer)
+
+    def _write_weights_Conv2D(self, layer):
+        return self._write_weights_Conv(layer)
+
+    def _write_weights_Conv3D(self, layer):
+        return self._write_weights_Conv(layer)
+
+    def _write_weights_Conv(self, layer):
+        # padding is done by the kernel, so there is no padded copy of the
+        # input
+        stride = layer.get_config()['strides']
+        dilation = layer.get_config()['dilation_rate']
+        if layer_type(layer)[-2:] == '1D':
+            self.stack_vars += 'var ' + layer.name + \
+                '_stride = ' + str(stride[0]) + '\n'
+            self.stack_vars += 'var ' + layer.name + \
+                '_dilation = ' + str(dilation[0]) + '\n'
+        else:
+            self.stack_vars += 'var ' + layer.name + '_stride = []int{' + \
+                ','.join([str(i) for i in stride]) + '}\n'
+            self.stack_vars += 'var ' + layer.name + '_dilation = []int{' + \
+                ','.join([str(i) for i in dilation]) + '}\n'
+        self.stack_vars += 'var ' + layer.name + '_groups = ' + \
+            str(layer.get_config().get('groups', 1)) + '\n'
         self._write_outputs(layer)
-        inshp = layer.get_input_at(0).shape[1:]
-        if padding == 'causal':
-            pad_along_height = dilation*(kernel_size-1)
-            pad_top = pad_along_height
-            pad_bottom = 0
-            self._write_weights_array2c(np.zeros((inshp[0]+pad_top+pad_bottom, inshp[1])),
-                                        layer.name + '_padded_input')
-            self.stack_vars += 'size_t ' + layer.name + '_pad[2] = {' + str(pad_top) + ','\
-                + str(pad_bottom) + '}; \n'
-            self.stack_vars += 'float ' + layer.name + '_fill = 0.0f; \n'
-        elif padding == 'same':
-            pad_along_height = dilation*(kernel_size-1)
-            pad_top = int(pad_along_height // 2)
-            pad_bottom = int(pad_along_height - pad_top)
-            self._write_weights_array2c(np.zeros((inshp[0]+pad_top+pad_bottom, inshp[1])),
-                                        layer.name + '_padded_input')
-            self.stack_vars += 'size_t ' + layer.name + '_pad[2] = {' + str(pad_top) + ','\
-                + str(pad_bottom) + '}; \n'
-            self.stack_vars += 'float ' + layer.name + '_fill = 0.0f; \n'
 
         weights = layer.get_weights()
         kernel = weights[0]
         if layer.get_config()['use_bias']:
             bias = weights[1]
         else:
-            bias = np.zeros(kernel.shape[2])
+            bias = np.zeros(kernel.shape[-1])
         self._write_weights_array2c(kernel, layer.name + '_kernel')
         self._write_weights_array2c(bias, layer.name + '_bias')
         self.stack_vars += '\n \n'
 
-    def _write_weights_Conv2D(self, layer):
-        padding = layer.get_config()['padding']
+    def _write_weights_Conv1DTranspose(self, layer):
+        return self._write_weights_ConvTranspose(layer)
+
+    def _write_weights_Conv2DTranspose(self, layer):
+        return self._write_weights_ConvTranspose(layer)
+
+    def _write_weights_Conv3DTranspose(self, layer):
+        return self._write_weights_ConvTranspose(layer)
+
+    def _write_weights_ConvTranspose(self, layer):
         stride = layer.get_config()['strides']
         dilation = layer.get_config()['dilation_rate']
-        kernel_size = layer.get_config()['kernel_size']
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_stride[2] = {' + ','.join([str(i) for i in stride]) + '}; \n'
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_dilation[2] = {' + ','.join([str(i)
-                                           for i in dilation]) + '}; \n'
-        self._write_outputs(layer)
-        if padding == 'same':
-            inshp = layer.get_input_at(0).shape[1:]
-            pad_along_height = dilation[0]*(kernel_size[0]-1)
-            pad_top = int(pad_along_height // 2)
-            pad_bottom = int(pad_along_height - pad_top)
-            pad_along_width = dilation[1]*(kernel_size[1]-1)
-            pad_left = pad_along_width//2
-            pad_right = pad_along_width - pad_left
-            padshp = (inshp[0]+pad_along_height,
-                      inshp[1]+pad_along_width, inshp[2])
-            pad = [pad_top, pad_bottom, pad_left, pad_right]
-            self._write_weights_array2c(np.zeros(padshp), layer.name +
-                                        '_padded_input')
-            self.stack_vars += 'size_t ' + layer.name + \
-                '_pad[4] = {' + ','.join([str(i) for i in pad]) + '}; \n'
-            self.stack_vars += 'float ' + layer.name + '_fill = 0.0f; \n'
+        if layer_type(layer)[-11:-9] == '1D':
+            self.stack_vars += 'var ' + layer.name + \
+                '_stride = ' + str(stride[0]) + '\n'
+            self.stack_vars += 'var ' + layer.name + \
+                '_dilation = ' + str(dilation[0]) + '\n'
+        else:
+            self.stack_vars += 'var ' + layer.name + '_stride = []int{' + \
+                ','.join([str(i) for i in stride]) + '}\n'
+            self.stack_vars += 'var ' + layer.name + '_dilation = []int{' + \
+                ','.join([str(i) for i in dilation]) + '}\n'
+        # output_padding only changes the output shape, which the
+        # kernel reads from the output tensor
+        self._write_outputs(layer)
 
         weights = layer.get_weights()
         kernel = weights[0]
         if layer.get_config()['use_bias']:
             bias = weights[1]
         else:
-            bias = np.zeros(kernel.shape[3])
+            bias = np.zeros(kernel.shape[-2])
         self._write_weights_array2c(kernel, layer.name + '_kernel')
         self._write_weights_array2c(bias, layer.name + '_bias')
         self.stack_vars += '\n \n'
 
-    def _write_weights_Conv3D(self, layer):
-        padding = layer.get_config()['padding']
-        stride = layer.get_config()['strides']
-        dilation = layer.get_config()['dilation_rate']
-        kernel_size = layer.get_config()['kernel_size']
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_stride[3] = {' + ','.join([str(i) for i in stride]) + '}; \n'
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_dilation[3] = {' + ','.join([str(i)
-                                           for i in dilation]) + '}; \n'
-        self._write_outputs(layer)
-        if padding == 'same':
-            inshp = layer.get_input_at(0).shape[1:]
-            pad_along_height = dilation[0]*(kernel_size[0]-1)
-            pad_top = int(pad_along_height // 2)
-            pad_bottom = int(pad_along_height - pad_top)
-            pad_along_width = dilation[1]*(kernel_size[1]-1)
-            pad_left = pad_along_width//2
-            pad_right = pad_along_width - pad_left
-            pad_along_depth = dilation[1]*(kernel_size[1]-1)
-            pad_front = pad_along_depth//2
-            pad_back = pad_along_depth - pad_front
-            padshp = (inshp[0]+pad_along_height,
-                      inshp[1]+pad_along_width,
-                      inshp[2]+pad_along_depth,
-                      inshp[3])
-            pad = [pad_top, pad_bottom, pad_left,
-                   pad_right, pad_front, pad_back]
-            self._write_weights_array2c(np.zeros(padshp), layer.name +
-                                        '_padded_input')
-            self.stack_vars += 'size_t ' + layer.name + \
-                '_pad[6] = {' + ','.join([str(i) for i in pad]) + '}; \n'
-            self.stack_vars += 'float ' + layer.name + '_fill = 0.0f; \n'
+    @staticmethod
+    def _locally_connected_kernel(layer, inshp, outshp):
+        """Gets the kernel of a locally connected layer in the layout of implementation 1
 
-        weights = layer.get_weights()
-        kernel = weights[0]
+        Args:
+            layer (keras Layer): LocallyConnected1D or LocallyConnected2D layer
+            inshp (tuple): input shape, without the batch dimension
+            outshp (tuple): output shape, without the batch dimension
+
+        Returns:
+            kernel (array): kernel of shape (output positions, window size*input channels, filters)
+        """
+        kernel = layer.get_weights()[0]
+        implementation = layer.get_config().get('implementation', 1)
+        if implementation == 1:
+            return kernel
+        in_size = int(np.prod(inshp))
+        out_size = int(np.prod(outshp))
+        if implementation == 2:
+            dense = np.reshape(kernel, (in_size, out_size))
+        else:
+            # sparse kernel, indexed by (output index, input index)
+            dense = np.zeros((in_size, out_size))
+            for value, (out_idx, in_idx) in zip(kernel, layer.kernel_idxs):
+                dense[in_idx, out_idx] = value
+        dense = np.reshape(dense, tuple(inshp) + tuple(outshp))
+        kernel_size = layer.get_config()['kernel_size']
+        strides = layer.get_config()['strides']
+        filters = outshp[-1]
+        positions = []
+        for pos in np.ndindex(*outshp[:-1]):
+            window = []
+            for offset in np.ndindex(*kernel_size):
+                insub = tuple(p*s + o for p, s, o in zip(pos, strides, offset))
+                window.append(dense[insub + (slice(None),) + pos])
+            positions.append(np.reshape(window, (-1, filters)))
+        return np.array(positions)
+
+    def _write_weights_LocallyConnected1D(self, layer):
+        self._write_weights_LocallyConnected(layer)
+
+    def _write_weights_LocallyConnected2D(self, layer):
+        self._write_weights_LocallyConnected(layer)
+
+    def _write_weights_LocallyConnected(self, layer):
+        nm = layer.name
+        kernel_size = layer.get_config()['kernel_size']
+        stride = layer.get_config()['strides']
+        inshp = layer.get_input_at(0).shape[1:]
+        outshp = layer.get_output_at(0).shape[1:]
+        if layer_type(layer)[-2:] == '1D':
+            self.stack_vars += 'var ' + nm + '_kernel_size = ' + \
+                str(kernel_size[0]) + '\n'
+            self.stack_vars += 'var ' + nm + '_stride = ' + \
+                str(stride[0]) + '\n'
+        else:
+            self.stack_vars += 'var ' + nm + '_kernel_size = []int{' + \
+                ','.join([str(i) for i in kernel_size]) + '}\n'
+            self.stack_vars += 'var ' + nm + '_stride = []int{' + \
+                ','.join([str(i) for i in stride]) + '}\n'
+            self.stack_vars += 'var ' + nm + '_fwork = make([]float64, ' + \
+                str(int(np.prod(kernel_size)*inshp[-1])) + ')\n'
+        self._write_outputs(layer)
+        kernel = self._locally_connected_kernel(layer, inshp, outshp)
         if layer.get_config()['use_bias']:
-            bias = weights[1]
+            bias = layer.get_weights()[1]
         else:
-            bias = np.zeros(kernel.shape[3])
-        self._write_weights_array2c(kernel, layer.name + '_kernel')
-        self._write_weights_array2c(bias, layer.name + '_bias')
+            bias = np.zeros(outshp)
+        self._write_weights_array2c(kernel, nm + '_kernel')
+        self._write_weights_array2c(bias, nm + '_bias')
         self.stack_vars += '\n \n'
 
     def _write_weights_MaxPooling1D(self, layer):
@@ -489,63 +675,35 @@ class Weights2C():
         return self._write_weights_Pooling1D(layer)
 
     def _write_weights_Pooling1D(self, layer):
-        pad = layer.get_config()['padding']
         stride = layer.get_config()['strides'][0]
         pool_size = layer.get_config()['pool_size'][0]
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_stride = ' + str(stride) + '; \n'
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_pool_size = ' + str(pool_size) + '; \n'
+        self.stack_vars += 'var ' + layer.name + \
+            '_stride = ' + str(stride) + '\n'
+        self.stack_vars += 'var ' + layer.name + \
+            '_pool_size = ' + str(pool_size) + '\n'
         self._write_outputs(layer)
-        inshp = layer.get_input_at(0).shape[1:]
-        outshp = layer.get_output_at(0).shape[1:]
-        if pad == 'same':
-            pad_along_height = max((outshp[0] - 1) * stride +
-                                   pool_size - inshp[0], 0)
-            pad_top = int(pad_along_height // 2)
-            pad_bottom = int(pad_along_height - pad_top)
-            self._write_weights_array2c(np.zeros((inshp[0]+pad_top+pad_bottom, inshp[1])),
-                                        layer.name + '_padded_input')
-            self.stack_vars += 'size_t ' + layer.name + '_pad[2] = {' + str(pad_top) + ','\
-                + str(pad_bottom) + '}; \n'
-            self.stack_vars += 'float ' + layer.name + '_fill = -HUGE_VALF; \n'
         self.stack_vars += '\n\n'
 
     def _write_weights_MaxPooling2D(self, layer):
-        return self._write_weights_Pooling2D(layer)
+        return self._write_weights_PoolingND(layer)
 
     def _write_weights_AveragePooling2D(self, layer):
-        return self._write_weights_Pooling2D(layer)
+        return self._write_weights_PoolingND(layer)
 
-    def _write_weights_Pooling2D(self, layer):
-        padding = layer.get_config()['padding']
+    def _write_weights_MaxPooling3D(self, layer):
+        return self._write_weights_PoolingND(layer)
+
+    def _write_weights_AveragePooling3D(self, layer):
+        return self._write_weights_PoolingND(layer)
+
+    def _write_weights_PoolingND(self, layer):
         stride = layer.get_config()['strides']
         pool_size = layer.get_config()['pool_size']
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_stride[2] = {' + ','.join([str(i) for i in stride]) + '}; \n'
-        self.stack_vars += 'size_t ' + layer.name + \
-            '_pool_size[2] = {' + ','.join([str(i)
-                                            for i in pool_size]) + '}; \n'
-        self._write_outputs(layer)
-        if padding == 'same':
-            inshp = layer.get_input_at(0).shape[1:]
-            outshp = layer.get_output_at(0).shape[1:]
-            pad_along_height = max((outshp[0] - 1) * stride[0] +
-                                   pool_size[0] - inshp[0], 0)
-            pad_top = int(pad_along_height // 2)
-            pad_bottom = int(pad_along_height - pad_top)
-            pad_along_width = max((outshp[1] - 1) * stride[1] +
-                                  pool_size[1] - inshp[1], 0)
-            pad_left = pad_along_width//2
-            pad_right = pad_along_width - pad_left
-            padshp = (inshp[0]+pad_along_height,
-                      inshp[1]+pad_along_width, inshp[2])
-            pad = [pad_top, pad_bottom, pad_left, pad_right]
-            self._write_weights_array2c(np.zeros(padshp), layer.name +
-                                        '_padded_input')
-            self.stack_vars += 'size_t ' + layer.name + \
-                '_pad[4] = {' + ','.join([str(i) for i in pad]) + '}; \n'
-            self.stack_vars += 'float ' + layer.name + '_fill = -HUGE_VALF; \n'
+        self.stack_vars += 'var ' + layer.name + '_stride = []int{' + \
+            ','.join([str(i) for i in stride]) + '}\n'
+        self.stack_vars += 'var ' + layer.name + '_pool_size = []int{' + \
+            ','.join([str(i) for i in pool_size]) + '}\n'
+        self._write_outputs(layer)
         self.stack_vars += '\n\n'
 
     def _write_weights_GlobalMaxPooling1D(self, layer):
@@ -590,28 +748,17 @@ class Weights2C():
 
     def _write_weights_Merge(self, layer):
         self._write_outputs(layer)
-        inputs, outputs = get_layer_io_names(layer)
-        for i, (inp, outp) in enumerate(zip(inputs, outputs)):
-            num_tensors = len(inp)
-            self.stack_vars += 'size_t ' + layer.name + '_num_tensors' + str(i) + \
-                ' = ' + str(num_tensors) + '; \n'
+        self._write_mask(layer)
         self.stack_vars += '\n\n'
 
     def _write_weights_Concatenate(self, layer):
-        inputs, outputs = get_layer_io_names(layer)
-        for i, (inp, outp) in enumerate(zip(inputs, outputs)):
-            outshp = layer.get_output_at(i).shape[1:]
-            num_tensors = len(inp)
-            self.stack_vars += 'size_t ' + layer.name + '_num_tensors' + str(i) + \
-                ' = ' + str(num_tensors) + '; \n'
-            ax = layer.get_config()['axis']
-            if ax < 0:
-                ax += len(layer.get_input_at(i)[0].shape)
-            self.stack_vars += 'size_t ' + layer.name + '_axis = ' +\
-                str(ax-1) + '; \n'
-        if outp not in self.model_io[1]:
-            self._write_weights_array2c(np.zeros(outshp),
-                                        outp + '_output')
+        ax = layer.get_config()['axis']
+        if ax < 0:
+            ax += len(layer.get_input_at(0)[0].shape)
+        self.stack_vars += 'var ' + layer.name + '_axis = ' + \
+            str(ax-1) + '\n'
+        self._write_outputs(layer)
+        self._write_mask(layer)
         self.stack_vars += '\n\n'
 
     def _write_weights_ELU(self, layer):
@@ -699,30 +846,43 @@ class Weights2C():
         nm = layer.name
         self._write_outputs(layer)
         kernel = layer.get_weights()[0]
+        if self.embedding_oov == 'bucket':
+            # a dedicated row after the vocabulary for the OOV indices
+            kernel = np.concatenate([kernel, np.zeros((1, kernel.shape[1]))])
         self._write_weights_array2c(kernel, nm+'_kernel')
+        if layer.get_config().get('mask_zero'):
+            self._write_mask(layer)
+        self.stack_vars += '\n\n'
+
+    def _write_weights_Masking(self, layer):
+        nm = layer.name
+        self._write_outputs(layer)
+        self._write_mask(layer)
+        self.stack_vars += 'var ' + nm + '_mask_value = ' + \
+            str(float(layer.get_config()['mask_value'])) + '\n'
         self.stack_vars += '\n\n'
 
     def _write_weights_UpSampling1D(self, layer):
         nm = layer.name
         self._write_outputs(layer)
         size = layer.get_config()['size']
-        self.stack_vars += 'size_t ' + nm + '_size = ' + str(size) + '; \n'
+        self.stack_vars += 'var ' + nm + '_size = ' + str(size) + '\n'
         self.stack_vars += '\n\n'
 
     def _write_weights_UpSampling2D(self, layer):
         nm = layer.name
         self._write_outputs(layer)
         size = layer.get_config()['size']
-        self.stack_vars += 'size_t ' + nm + '_size[2] = {' + str(size[0]) + \
-            ',' + str(size[1]) + '}; \n'
+        self.stack_vars += 'var ' + nm + '_size = []int{' + str(size[0]) + \
+            ',' + str(size[1]) + '}\n'
         self.stack_vars += '\n\n'
 
     def _write_weights_UpSampling3D(self, layer):
         nm = layer.name
         self._write_outputs(layer)
         size = layer.get_config()['size']
-        self.stack_vars += 'size_t ' + nm + '_size[3] = {' + str(size[0]) + \
-            ',' + str(size[1]) + ',' + str(size[2]) + '}; \n'
+        self.stack_vars += 'var ' + nm + '_size = []int{' + str(size[0]) + \
+            ',' + str(size[1]) + ',' + str(size[2]) + '}\n'
         self.stack_vars += '\n\n'
 
     def _write_weights_Cropping1D(self, layer):
@@ -824,7 +984,266 @@ class Weights2C():
 
     def _write_weights_Activation(self, layer):
         # no weights needed
-        pass
+        self._write_outputs(layer)
+        self.stack_vars += '\n\n'
+
+    def _write_weights_Softmax(self, layer):
+        axis = layer.get_config()['axis']
+        if isinstance(axis, (list, tuple)):
+            axis = axis[0]
+        if axis < 0:
+            axis += len(layer.input_shape)
+        self._write_outputs(layer)
+        self.stack_vars += 'var ' + layer.name + '_axis = ' + \
+            str(axis - 1) + '\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_Rescaling(self, layer):
+        self._write_outputs(layer)
+        scale = np.reshape(layer.get_config()['scale'], (-1,))
+        offset = np.reshape(layer.get_config()['offset'], (-1,))
+        self._write_weights_array2c(scale, layer.name + '_scale')
+        self._write_weights_array2c(offset, layer.name + '_offset')
+        self.stack_vars += '\n\n'
+
+    @staticmethod
+    def _normalization_shape(layer):
+        """Gets the Shape of the mean and variance of a Normalization layer
+
+        The statistics are written for the trailing axes of the input from
+        the first normalized axis, and K2c_normalization repeats them over
+        the leading axes, which may have an unknown size.
+
+        Args:
+            layer (keras Layer): Normalization layer
+
+        Returns:
+            shape (tuple): trailing dims of the input, with None for unknown dims
+        """
+        shape = np.shape(K.get_value(layer.mean))[1:]
+        while len(shape) > 0 and shape[0] == 1:
+            shape = shape[1:]
+        inshp = tuple(layer.input_shape[1:])
+        return inshp[len(inshp) - len(shape):]
+
+    def _write_weights_Normalization(self, layer):
+        self._write_outputs(layer)
+        shape = self._normalization_shape(layer)
+        mean = K.get_value(layer.mean)
+        variance = K.get_value(layer.variance)
+        # the leading axes of the statistics all have size 1
+        mean = np.broadcast_to(np.reshape(
+            mean, np.shape(mean)[mean.ndim - len(shape):]), shape)
+        variance = np.broadcast_to(np.reshape(
+            variance, np.shape(variance)[variance.ndim - len(shape):]), shape)
+        self._write_weights_array2c(mean, layer.name + '_mean')
+        self._write_weights_array2c(variance, layer.name + '_variance')
+        self.stack_vars += 'var ' + layer.name + '_invert = ' + \
+            str(int(layer.get_config().get('invert', False))) + '\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_Resizing(self, layer):
+        self._write_outputs(layer)
+        self.stack_vars += 'var ' + layer.name + '_crop_to_aspect_ratio = ' + \
+            str(int(layer.get_config()['crop_to_aspect_ratio'])) + '\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_CenterCrop(self, layer):
+        # no weights needed
+        self._write_outputs(layer)
+        self.stack_vars += '\n\n'
+
+    def _write_einsum_plan(self, nm, equation, shapes):
+        # the equation is parsed once when the package is initialized
+        self.package_vars += 'var ' + self.function_name + '_' + nm + \
+            '_plan = keras2go.K2c_new_einsum_plan(' + go_string(equation) + \
+            ''.join(',[]int{' + ','.join(str(int(d)) for d in shape) + '}'
+                    for shape in shapes) + ')\n'
+        self.stack_vars += 'var ' + nm + '_plan = ' + self.function_name + \
+            '_' + nm + '_plan\n'
+
+    def _write_lookup(self, lookup, nm):
+        # the vocabulary is written in the generated code, and the lookup
+        # made once when the package is initialized
+        vocabulary = lookup.get_vocabulary(include_special_tokens=False)
+        tokens = [go_string(t if isinstance(t, (str, bytes)) else str(t))
+                  for t in vocabulary]
+        mask_token = lookup.mask_token
+        self.package_vars += 'var ' + self.function_name + '_' + nm + \
+            '_lookup = keras2go.K2c_new_lookup([]string{\n' + \
+            ''.join('\t' + ', '.join(tokens[i:i+8]) + ',\n'
+                    for i in range(0, len(tokens), 8)) + '}, ' + \
+            str(lookup.num_oov_indices) + ',' + \
+            go_string('' if mask_token is None else str(mask_token)) + ',' + \
+            str(int(mask_token is not None)) + ')\n'
+        self.stack_vars += 'var ' + nm + '_lookup = ' + self.function_name + \
+            '_' + nm + '_lookup\n'
+
+    def _write_encoding(self, layer, idf_layer=None):
+        # indices are looked up or hashed first, then encoded into the output
+        nm = layer.name
+        output_mode = layer.get_config().get('output_mode', 'int')
+        if output_mode == 'int':
+            return
+        inshp = layer.input_shape[1:]
+        self._write_weights_array2c(np.zeros(inshp), nm + '_indices')
+        if output_mode == 'tf_idf':
+            self._write_weights_array2c(
+                K.get_value(idf_layer.idf_weights), nm + '_idf_weights')
+
+    def _write_weights_StringLookup(self, layer):
+        self._write_outputs(layer)
+        self._write_lookup(layer, layer.name)
+        self._write_encoding(layer, layer)
+        self.stack_vars += '\n\n'
+
+    def _write_weights_IntegerLookup(self, layer):
+        self._write_weights_StringLookup(layer)
+
+    def _write_weights_Hashing(self, layer):
+        nm = layer.name
+        config = layer.get_config()
+        self._write_outputs(layer)
+        self._write_encoding(layer)
+        mask_value = config['mask_value']
+        if layer.input.dtype == tf.string:
+            self.stack_vars += 'var ' + nm + '_mask_value = ' + \
+                go_string('' if mask_value is None else mask_value) + '\n'
+        else:
+            self.stack_vars += 'var ' + nm + '_mask_value = ' + \
+                str(float(mask_value or 0)) + '\n'
+        self.stack_vars += 'var ' + nm + '_use_mask_value = ' + \
+            str(int(mask_value is not None)) + '\n'
+        salt = config['salt']
+        if salt is None:
+            self.stack_vars += 'var ' + nm + '_salt []uint64\n'
+        else:
+            if isinstance(salt, int):
+                salt = [salt, salt]
+            self.stack_vars += 'var ' + nm + '_salt = []uint64{' + \
+                ','.join(str(int(s)) for s in salt) + '}\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_CategoryEncoding(self, layer):
+        # no weights needed
+        self._write_outputs(layer)
+        self.stack_vars += '\n\n'
+
+    def _write_weights_TextVectorization(self, layer):
+        nm = layer.name
+        config = layer.get_config()
+        self._write_outputs(layer)
+        self._write_lookup(layer._lookup_layer, nm)
+        if config['output_mode'] == 'tf_idf':
+            self._write_weights_array2c(
+                K.get_value(layer._lookup_layer.idf_weights), nm + '_idf_weights')
+        ngrams = config['ngrams']
+        if ngrams is None:
+            ngrams = [1]
+        elif isinstance(ngrams, int):
+            ngrams = range(1, ngrams + 1)
+        self.stack_vars += 'var ' + nm + '_ngrams = []int{' + \
+            ','.join(str(int(n)) for n in ngrams) + '}\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_TFOpLambda(self, layer):
+        nm = layer.name
+        kind = op_kind(layer)
+        args = op_args(layer)
+        rank = len(layer.get_output_at(0).shape)
+        self._write_outputs(layer)
+        if get_layer_io_names(layer)[1][0] in self.combined:
+            # written by the Embedding it reduces
+            self.stack_vars += '\n\n'
+            return
+        if kind in ['binary', 'where']:
+            # constant operands are broadcast like tensors
+            for arg in ['condition', 'x', 'y']:
+                if arg in args and not is_keras_tensor(args[arg]):
+                    self._write_weights_array2c(
+                        constant_operand(args[arg], rank), nm + '_' + arg)
+        elif kind == 'reduce':
+            axes = go_axes(args['axis'], len(args['input_tensor'].shape))
+            self.stack_vars += 'var ' + nm + '_axes = []int{' + \
+                ','.join(str(a) for a in axes) + '}\n'
+        elif kind in ['getitem', 'strided_slice', 'slice']:
+            tensor, spec = slice_spec(layer)
+            for name, values in zip(['begin', 'size', 'strides'],
+                                    resolve_slice(tuple(tensor.shape), spec)):
+                self.stack_vars += 'var ' + nm + '_' + name + ' = []int{' + \
+                    ','.join(str(v) for v in values) + '}\n'
+        elif kind == 'gather':
+            # a constant keeps all its axes, a tensor loses its batch axis
+            params = args['params']
+            axis = int(args.get('axis') or 0)
+            if is_keras_tensor(params):
+                axis = go_axes(axis, len(params.shape))[0]
+            else:
+                params = np.array(params, dtype=float)
+                axis %= params.ndim
+                self._write_weights_array2c(params, nm + '_params')
+            if not is_keras_tensor(args['indices']):
+                indices = np.array(args['indices'], dtype=float)
+                self._write_weights_array2c(
+                    np.reshape(indices, indices.shape or (1,)), nm + '_indices')
+            self.stack_vars += 'var ' + nm + '_axis = ' + str(axis) + '\n'
+        elif kind == 'squeeze':
+            rank = len(args['input'].shape)
+            if args.get('axis') is None:
+                axes = [a - 1 for a in range(1, rank)
+                        if args['input'].shape[a] == 1]
+            else:
+                axes = go_axes(args['axis'], rank)
+            self.stack_vars += 'var ' + nm + '_axes = []int{' + \
+                ','.join(str(a) for a in axes) + '}\n'
+        elif kind == 'einsum':
+            operands = [args[arg] for arg in ['a', 'b'] if arg in args]
+            size = np.prod(layer.get_output_at(0).shape[1:])
+            shapes = []
+            for arg, op in zip(['a', 'b'], operands):
+                if is_keras_tensor(op):
+                    size += np.prod(op.shape[1:])
+                    shapes.append(op.shape[1:])
+                else:
+                    op = np.array(op, dtype=float)
+                    size += op.size
+                    shapes.append(op.shape)
+                    self._write_weights_array2c(op, nm + '_' + arg)
+            self._write_einsum_plan(
+                nm, go_equation(args['equation'], [is_keras_tensor(op)
+                                                   for op in operands]), shapes)
+            self.stack_vars += 'var ' + nm + '_fwork = make([]float64, ' + \
+                str(int(size)) + ')\n'
+        elif kind == 'expand_dims':
+            self.stack_vars += 'var ' + nm + '_axis = ' + \
+                str(go_axes(args['axis'], rank)[0]) + '\n'
+        self.stack_vars += '\n\n'
+
+    def _write_weights_SlicingOpLambda(self, layer):
+        self._write_weights_TFOpLambda(layer)
+
+    def _write_weights_custom(self, layer):
+        # the go implementation gets the config as JSON, and the weights in
+        # the order of get_weights. Each call of the layer has its own
+        # instance, made on its first run
+        nm = layer.name
+        self._write_outputs(layer)
+        config = json.dumps(get_layer_config(layer), default=str, sort_keys=True)
+        for i in range(get_layer_num_io(layer)[0]):
+            node = get_node_name(layer, i)
+            self.package_vars += 'var ' + self.function_name + '_' + node + \
+                '_call = keras2go.K2c_new_custom_call(' + \
+                go_string(custom_layer_name(layer)) + ',' + go_string(config) + ')\n'
+            self.stack_vars += 'var ' + node + '_call = ' + \
+                self.function_name + '_' + node + '_call\n'
+        weights = layer.get_weights()
+        for k, w in enumerate(weights):
+            self._write_weights_array2c(np.asarray(w, dtype=float),
+                                        nm + '_weight' + str(k))
+        self.stack_vars += 'var ' + nm + '_weights = []*keras2go.K2c_tensor{' + \
+            ','.join('&' + nm + '_weight' + str(k)
+                     for k in range(len(weights))) + '}\n'
+        self.stack_vars += '\n\n'
 
     def _write_weights_Dropout(self, layer):
         # no weights needed
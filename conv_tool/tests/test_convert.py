@@ -0,0 +1,129 @@
+"""test_convert.py
+This file is part of keras2go
+Licensed under MIT License
+
+Converts small models and checks the generated Go code against Keras on
+given inputs. Needs the go tool.
+Run from conv_tool with: python -m unittest discover tests
+"""
+
+# imports
+import json
+import os
+import shutil
+import subprocess
+import tempfile
+import unittest
+import numpy as np
+import tensorflow as tf
+from keras2go.keras2go_main import model2c
+
+
+def go_tensor(shape, array=None):
+    """Go expression of a tensor of a shape, zero if there is no array"""
+    shape = [int(d) for d in shape]
+    numel = int(np.prod(shape))
+    if array is None:
+        values = 'make([]float64, ' + str(numel) + ')'
+    else:
+        values = '[]float64{' + ','.join(repr(float(v))
+                                         for v in np.ravel(array)) + '}'
+    return '&keras2go.K2c_tensor{Array: ' + values + ', Ndim: ' + \
+        str(len(shape)) + ', Numel: ' + str(numel) + \
+        ', Shape: [keras2go.K2C_MAX_NDIM]int{' + \
+        ','.join(str(d) for d in shape + [1] * (5 - len(shape))) + '}}'
+
+
+def run_go(model, inputs):
+    """Converts model and runs the Go function on one sample of each input
+
+    Args:
+        model (keras Model): model to convert
+        inputs (list): arrays of one sample of each input, without batch axis
+
+    Returns:
+        outputs (list): arrays of the outputs of the Go function
+    """
+    output_shapes = [tf.keras.backend.int_shape(o)[1:] for o in model.outputs]
+    # the generated package lives in the module, to import keras2go
+    conv_tool = os.path.dirname(os.path.dirname(os.path.abspath(__file__)))
+    tmp = tempfile.mkdtemp(dir=conv_tool)
+    cwd = os.getcwd()
+    try:
+        os.chdir(tmp)
+        model2c(model, 'Converted', 'converted', verbose=False)
+        args = ['x' + str(i) for i in range(len(inputs))] + \
+            ['y' + str(i) for i in range(len(output_shapes))]
+        test = 'package converted\n\n'
+        test += 'import (\n"encoding/json"\n"fmt"\n"testing"\n\n'
+        test += '"github.com/orestonce/keras2go"\n)\n\n'
+        test += 'func TestConverted(t *testing.T) {\n'
+        for i, x in enumerate(inputs):
+            test += 'x' + str(i) + ' := ' + go_tensor(x.shape, x) + '\n'
+        for i, shape in enumerate(output_shapes):
+            test += 'y' + str(i) + ' := ' + go_tensor(shape) + '\n'
+        test += 'if err := Converted(' + ', '.join(args) + '); err != nil {\n'
+        test += 't.Fatal(err)\n}\n'
+        test += 'outputs, _ := json.Marshal([][]float64{' + ', '.join(
+            'y' + str(i) + '.Array' for i in range(len(output_shapes))) + '})\n'
+        test += 'fmt.Println("OUTPUTS", string(outputs))\n}\n'
+        with open('Converted_test.go', 'w') as f:
+            f.write(test)
+        result = subprocess.run(['go', 'test', '-count=1', '-v', '.'],
+                                stdout=subprocess.PIPE, stderr=subprocess.STDOUT,
+                                universal_newlines=True)
+    finally:
+        os.chdir(cwd)
+        shutil.rmtree(tmp)
+    if result.returncode != 0:
+        raise AssertionError(result.stdout)
+    for line in result.stdout.splitlines():
+        if line.startswith('OUTPUTS '):
+            outputs = json.loads(line[len('OUTPUTS '):])
+    return [np.reshape(y, shape) for y, shape in zip(outputs, output_shapes)]
+
+
+@unittest.skipIf(shutil.which('go') is None, 'needs the go tool')
+class TestMaskedPooling(unittest.TestCase):
+    """Global pooling after a mask, on padded sequences"""
+
+    def test_global_pooling(self):
+        # keras GlobalMaxPooling1D ignores the mask, so the padding counts,
+        # GlobalAveragePooling1D averages the unmasked timesteps
+        inp = tf.keras.layers.Input((5, 2), name='x')
+        x = tf.keras.layers.Masking(0.0, name='mask')(inp)
+        model = tf.keras.models.Model(inp, [
+            tf.keras.layers.GlobalMaxPooling1D(name='max')(x),
+            tf.keras.layers.GlobalAveragePooling1D(name='avg')(x)])
+        x = np.array([[-1.5, -0.5], [-2.0, -0.25], [-0.75, -3.0],
+                      [0.0, 0.0], [0.0, 0.0]])
+        expected = model.predict(x[np.newaxis])
+        outputs = run_go(model, [x])
+        for y, e in zip(outputs, expected):
+            np.testing.assert_allclose(y, e[0], atol=1e-6)
+        np.testing.assert_allclose(outputs[0], [0.0, 0.0])
+
+
+@unittest.skipIf(shutil.which('go') is None, 'needs the go tool')
+class TestBidirectional(unittest.TestCase):
+    """Bidirectional layers on a masked input, merged or not"""
+
+    def test_merge_modes(self):
+        # keras zeroes the masked timesteps of the returned sequences
+        inp = tf.keras.layers.Input((4, 2), name='x')
+        x = tf.keras.layers.Masking(0.0, name='mask')(inp)
+        outputs = [tf.keras.layers.Bidirectional(
+            tf.keras.layers.SimpleRNN(3, return_sequences=True, name='rnn_' + str(mode)),
+            merge_mode=mode, name='bidir_' + str(mode))(x)
+            for mode in ['concat', 'sum', 'mul', 'ave', None]]
+        model = tf.keras.models.Model(inp, outputs[:4] + outputs[4])
+        x = np.array([[0.5, -1.0], [0.25, 2.0], [0.0, 0.0], [-1.5, 0.75]])
+        expected = model.predict(x[np.newaxis])
+        outputs = run_go(model, [x])
+        for y, e in zip(outputs, expected):
+            np.testing.assert_allclose(y, e[0], atol=1e-6)
+        np.testing.assert_allclose(outputs[0][2], np.zeros(6))
+
+
+if __name__ == "__main__":
+    unittest.main()
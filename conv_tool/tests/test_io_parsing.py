@@ -0,0 +1,37 @@
+"""test_io_parsing.py
+This file is part of keras2go
+Licensed under MIT License
+
+Checks the helpers of io_parsing.
+Run from conv_tool with: python -m unittest discover tests
+"""
+
+# imports
+import unittest
+from keras2go.io_parsing import go_string
+
+
+class TestGoString(unittest.TestCase):
+    """Go string literals"""
+
+    def test_escapes(self):
+        self.assertEqual(go_string('plain'), '"plain"')
+        self.assertEqual(go_string('a"b\\c'), '"a\\"b\\\\c"')
+        self.assertEqual(go_string('line\nbreak\ttab\r'),
+                         '"line\\nbreak\\ttab\\r"')
+        self.assertEqual(go_string('\x00\x7f\x85\u2028'),
+                         '"\\x00\\x7f\\u0085\\u2028"')
+        self.assertEqual(go_string('\U000e0001'), '"\\U000e0001"')
+
+    def test_unicode(self):
+        self.assertEqual(go_string('中文 ü \U0001F600'), '"中文 ü \U0001F600"')
+
+    def test_bytes(self):
+        # invalid utf-8 is written as the same bytes
+        self.assertEqual(go_string(b'\xff\xfeab'), '"\\xff\\xfeab"')
+        self.assertEqual(go_string(b'bytes\xc3'), '"bytes\\xc3"')
+        self.assertEqual(go_string('\ud800x'), '"\\xed\\xa0\\x80x"')
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,62 @@
+"""test_layer2c.py
+This file is part of keras2go
+Licensed under MIT License
+
+Checks the helpers of layer2c.
+Run from conv_tool with: python -m unittest discover tests
+"""
+
+# imports
+import unittest
+import tensorflow as tf
+from keras2go.layer2c import activation2go, Layers2C
+
+
+def scaled_tanh(x):
+    return 2 * tf.tanh(x)
+
+
+class TestActivation2Go(unittest.TestCase):
+    """Go functions of activations"""
+
+    def test_builtin(self):
+        self.assertEqual(activation2go('relu'), 'keras2go.K2c_relu')
+        self.assertEqual(activation2go(
+            {'class_name': 'ELU', 'config': {'alpha': 0.5}}),
+            'keras2go.K2c_elu_alpha(0.5)')
+
+    def test_custom(self):
+        custom = 'keras2go.K2c_new_custom_activation("scaled_tanh")'
+        self.assertEqual(activation2go(
+            'scaled_tanh', {'scaled_tanh': scaled_tanh}), custom)
+        self.assertEqual(activation2go(
+            {'class_name': 'function', 'config': 'scaled_tanh'},
+            {'scaled_tanh': scaled_tanh}), custom)
+
+    def test_unknown(self):
+        # typos and activations keras2go does not implement are rejected
+        for activation in ['rleu', 'scaled_tanh', 'leaky_relu']:
+            self.assertIsNone(activation2go(activation))
+
+
+class TestCustomActivations(unittest.TestCase):
+    """Custom activations in the generated code"""
+
+    def test_resolved_once(self):
+        # one package variable per activation, looked up before the layers run
+        inp = tf.keras.layers.Input((4,))
+        x = tf.keras.layers.Dense(3, activation=scaled_tanh, name='d1')(inp)
+        x = tf.keras.layers.Dense(2, activation=scaled_tanh, name='d2')(x)
+        model = tf.keras.models.Model(inp, x)
+        writer = Layers2C(model, 'Test', custom_objects={'scaled_tanh': scaled_tanh})
+        layers = writer.write_layers(verbose=False)
+        self.assertEqual(writer.package_vars,
+                         'var Test_custom_activation0 = '
+                         'keras2go.K2c_new_custom_activation("scaled_tanh")\n')
+        self.assertTrue(layers.startswith(
+            'if err := Test_custom_activation0.Resolve(); err != nil {'))
+        self.assertEqual(layers.count('Test_custom_activation0.Apply'), 2)
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,72 @@
+"""test_submodels.py
+This file is part of keras2go
+Licensed under MIT License
+
+Checks the inlining of nested models, and the buffers of shared layers.
+Run from conv_tool with: python -m unittest discover tests
+"""
+
+# imports
+import unittest
+import numpy as np
+import tensorflow as tf
+from keras2go.submodels import inline_submodels, is_submodel
+from keras2go.weights2go import Weights2C
+
+
+def encoder():
+    """Functional submodel of two Dense layers"""
+    inp = tf.keras.layers.Input((4,))
+    x = tf.keras.layers.Dense(3, activation='relu', name='dense')(inp)
+    return tf.keras.Model(inp, tf.keras.layers.Dense(2, name='out')(x),
+                          name='encoder')
+
+
+class TestInlineSubmodels(unittest.TestCase):
+    """Models with nested models"""
+
+    def check(self, model, names):
+        inlined = inline_submodels(model)
+        self.assertFalse(any(is_submodel(layer) for layer in inlined.layers))
+        self.assertEqual(sorted(layer.name for layer in inlined.layers
+                                if not layer.name.startswith('input')),
+                         sorted(names))
+        x = np.random.uniform(-1, 1, (5, 4))
+        np.testing.assert_allclose(inlined.predict(x), model.predict(x),
+                                   atol=1e-6)
+
+    def test_nested(self):
+        inp = tf.keras.layers.Input((4,))
+        seq = tf.keras.Sequential([encoder(), tf.keras.layers.Dense(1, name='head')],
+                                  name='seq')
+        self.check(tf.keras.Model(inp, seq(inp)),
+                   ['seq_encoder_dense', 'seq_encoder_out', 'seq_head'])
+
+    def test_name_collision(self):
+        # the outer layer keeps its name, the inlined one gets a suffix
+        inp = tf.keras.layers.Input((4,))
+        x = encoder()(inp)
+        x = tf.keras.layers.Dense(4, name='encoder_dense')(x)
+        self.check(tf.keras.Model(inp, x),
+                   ['encoder_dense_1', 'encoder_out', 'encoder_dense'])
+
+    def test_shared_submodel(self):
+        # a submodel called twice is inlined once, with two calls of each layer
+        inp = tf.keras.layers.Input((4,))
+        shared = encoder()
+        x = shared(inp)
+        y = shared(tf.keras.layers.Dense(4, name='pre')(inp))
+        model = tf.keras.Model(inp, tf.keras.layers.Add(name='add')([x, y]))
+        self.check(model, ['encoder_dense', 'encoder_out', 'pre', 'add'])
+
+        # each call of a shared layer writes its own output buffer
+        inlined = inline_submodels(model)
+        weights = Weights2C(inlined, 'Test')
+        weights.write_weights(verbose=False)
+        for name in ['encoder_dense_output', 'encoder_dense_node1_output',
+                     'encoder_out_output', 'encoder_out_node1_output']:
+            self.assertIn('var ' + name + ' = ', weights.stack_vars)
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,83 @@
+"""test_tf_ops.py
+This file is part of keras2go
+Licensed under MIT License
+
+Checks the helpers of tf_ops on Keras models.
+Run from conv_tool with: python -m unittest discover tests
+"""
+
+# imports
+import unittest
+import tensorflow as tf
+from keras2go.io_parsing import get_input_types
+from keras2go.tf_ops import embedding_combiners, go_equation
+
+
+def embedding_model(reduce, mask_zero=False):
+    """Model looking up indices and reducing the vectors with reduce"""
+    inp = tf.keras.layers.Input((4,), dtype='int32', name='ids')
+    x = tf.keras.layers.Embedding(10, 3, mask_zero=mask_zero, name='emb')(inp)
+    return tf.keras.models.Model(inp, reduce(x))
+
+
+class TestEmbeddingCombiners(unittest.TestCase):
+    """Embedding layers looked up with a combiner"""
+
+    def test_pooling(self):
+        for mask_zero in [False, True]:
+            model = embedding_model(
+                tf.keras.layers.GlobalAveragePooling1D(name='pool'), mask_zero)
+            self.assertEqual(embedding_combiners(model),
+                             {'emb': ('K2C_COMBINER_MEAN', 'pool')})
+            self.assertEqual(get_input_types(model), ['[]int'])
+
+    def test_reduce(self):
+        model = embedding_model(lambda x: tf.reduce_sum(x, axis=1))
+        combiner, _ = embedding_combiners(model)['emb']
+        self.assertEqual(combiner, 'K2C_COMBINER_SUM')
+        model = embedding_model(lambda x: tf.reduce_mean(x, axis=-2))
+        combiner, _ = embedding_combiners(model)['emb']
+        self.assertEqual(combiner, 'K2C_COMBINER_MEAN')
+
+    def test_not_combined(self):
+        # reduce ops do not skip the padding, other axes are not bags
+        models = [
+            embedding_model(lambda x: tf.reduce_sum(x, axis=1), True),
+            embedding_model(lambda x: tf.reduce_sum(x, axis=2)),
+            embedding_model(lambda x: tf.reduce_max(x, axis=1)),
+            embedding_model(lambda x: tf.keras.layers.Add()(
+                [tf.keras.layers.GlobalAveragePooling1D()(x), x[:, 0]])),
+            embedding_model(tf.keras.layers.Flatten()),
+        ]
+        for model in models:
+            self.assertEqual(embedding_combiners(model), {})
+
+    def test_input_types(self):
+        # indices also read by another layer stay tensors
+        inp = tf.keras.layers.Input((4,), dtype='int32')
+        x = tf.keras.layers.Embedding(10, 3)(inp)
+        y = tf.keras.layers.CategoryEncoding(10)(inp)
+        model = tf.keras.models.Model(inp, [x, y])
+        self.assertEqual(get_input_types(model), ['*keras2go.K2c_tensor'])
+        text = tf.keras.layers.Input((1,), dtype=tf.string)
+        model = tf.keras.models.Model(text, tf.keras.layers.StringLookup(
+            vocabulary=['a', 'b'])(text))
+        self.assertEqual(get_input_types(model), ['[]string'])
+
+
+class TestGoEquation(unittest.TestCase):
+    """Einsum equations without the batch axis"""
+
+    def test_batch(self):
+        self.assertEqual(go_equation('abc,cd->abd', [True, False]), 'bc,cd->bd')
+        self.assertEqual(go_equation('...c,cd->...d', [True, False]),
+                         '...c,cd->...d')
+
+    def test_diagonals(self):
+        for equation in ['abb,bc->ac', 'abc,cc->abc', 'ab,bc->acc']:
+            with self.assertRaises(ValueError):
+                go_equation(equation, [True, False])
+
+
+if __name__ == "__main__":
+    unittest.main()
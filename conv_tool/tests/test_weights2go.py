@@ -0,0 +1,59 @@
+"""test_weights2go.py
+This file is part of keras2go
+Licensed under MIT License
+
+Checks the weight conversions in weights2go against Keras.
+Run from conv_tool with: python -m unittest discover tests
+"""
+
+# imports
+import unittest
+import numpy as np
+import tensorflow as tf
+from keras2go.weights2go import Weights2C
+
+
+def locally_connected(x, kernel, bias, kernel_size, strides):
+    """Locally connected layer with an implementation 1 kernel, channels last"""
+    outshp = bias.shape
+    out = np.zeros((x.shape[0],) + outshp)
+    for p, pos in enumerate(np.ndindex(*outshp[:-1])):
+        window = tuple(slice(i*s, i*s + k)
+                       for i, s, k in zip(pos, strides, kernel_size))
+        patch = np.reshape(x[(slice(None),) + window], (x.shape[0], -1))
+        out[(slice(None),) + pos] = patch @ kernel[p] + bias[pos]
+    return out
+
+
+class TestLocallyConnectedKernel(unittest.TestCase):
+    """Kernels of all implementations converted to the layout of implementation 1"""
+
+    def check(self, layer_cls, inshp, kernel_size, strides):
+        for implementation in [1, 2, 3]:
+            with self.subTest(implementation=implementation):
+                inp = tf.keras.layers.Input(inshp)
+                layer = layer_cls(3, kernel_size, strides=strides,
+                                  implementation=implementation)
+                model = tf.keras.models.Model(inp, layer(inp))
+                model.set_weights([np.random.uniform(-1, 1, w.shape)
+                                   for w in model.get_weights()])
+                x = np.random.uniform(-1, 1, (2,) + inshp)
+                outshp = tuple(layer.get_output_at(0).shape[1:])
+                kernel = Weights2C._locally_connected_kernel(
+                    layer, inshp, outshp)
+                bias = layer.get_weights()[1]
+                expected = model.predict(x)
+                got = locally_connected(x, kernel, bias, kernel_size, strides)
+                self.assertEqual(kernel.shape[0], np.prod(outshp[:-1]))
+                np.testing.assert_allclose(got, expected, atol=1e-5)
+
+    def test_LocallyConnected1D(self):
+        self.check(tf.keras.layers.LocallyConnected1D, (7, 2), (3,), (2,))
+
+    def test_LocallyConnected2D(self):
+        self.check(tf.keras.layers.LocallyConnected2D,
+                   (5, 4, 2), (2, 2), (2, 1))
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -99,24 +99,236 @@ func k2c_pad3d(output *K2c_tensor, input *K2c_tensor, fill float64, pad []int) {
 *
 * :param output: output tensor.
 * :param input: input tensor.
-* :param kernel: kernel tensor.
+* :param kernel: kernel tensor, Shape is {kernel_size, in_channels/groups, out_channels}.
 * :param bias: bias tensor.
 * :param stride: stride length of the convolution.
 * :param dilation: dilation rate to use for dilated convolution.
+* :param padding: padding mode, K2C_PADDING_VALID, K2C_PADDING_SAME or K2C_PADDING_CAUSAL.
+* :param groups: number of groups the channels are split into, see k2c_conv3d_accumulate.
 * :param activation: activation function to apply to output.
  */
-func k2c_conv1d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride int, dilation int, activation k2c_activationType) {
+func K2c_conv1d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride int, dilation int, padding int, groups int, activation k2c_activationType) {
+	var output3d = k2c_expand_dims_front(output, 4)
+	var input3d = k2c_expand_dims_front(input, 4)
+	var kernel3d = k2c_expand_dims_front(kernel, 5)
+
+	output.fillFloat64(0)
+	k2c_conv3d_accumulate(&output3d, &input3d, &kernel3d, []int{1, 1, stride}, []int{1, 1, dilation}, padding, groups)
+	k2c_bias_add(output, bias)
+	activation(output.Array[:output.Numel])
+}
+
+/**
+* 2D (spatial) Convolution.
+* Assumes a "channels last" structure.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param kernel: kernel tensor, Shape is {rows, cols, in_channels/groups, out_channels}.
+* :param bias: bias tensor.
+* :param stride: Array[2] of stride length of the convolution. Order is {stride dim 1, stride dim 2}.
+* :param dilation: Array[2] dilation rate to use for dilated convolution. Order is {dilation dim 1, dilation dim 2}.
+* :param padding: padding mode, K2C_PADDING_VALID, K2C_PADDING_SAME or K2C_PADDING_CAUSAL.
+* :param groups: number of groups the channels are split into, see k2c_conv3d_accumulate.
+* :param activation: activation function to apply to output.
+*/
+func K2c_conv2d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride []int, dilation []int, padding int, groups int, activation k2c_activationType) {
+	output.fillFloat64(0)
+	k2c_conv2d_accumulate(output, input, kernel, stride, dilation, padding, groups)
+	k2c_bias_add(output, bias)
+	activation(output.Array[:output.Numel])
+}
+
+/**
+* 3D (spatial or spatio-temporal) Convolution.
+* Assumes a "channels last" structure.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param kernel: kernel tensor, Shape is {dim 1, dim 2, dim 3, in_channels/groups, out_channels}.
+* :param bias: bias tensor.
+* :param stride: Array[3] of stride length of the convolution. Order is {stride dim 1, stride dim 2, stride dim 3}.
+* :param dilation: Array[3] dilation rate to use for dilated convolution. Order is {dilation dim 1, dilation dim 2, dilation dim 3}.
+* :param padding: padding mode, K2C_PADDING_VALID, K2C_PADDING_SAME or K2C_PADDING_CAUSAL.
+* :param groups: number of groups the channels are split into, see k2c_conv3d_accumulate.
+* :param activation: activation function to apply to output.
+*/
+func K2c_conv3d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride []int, dilation []int, padding int, groups int, activation k2c_activationType) {
+	output.fillFloat64(0)
+	k2c_conv3d_accumulate(output, input, kernel, stride, dilation, padding, groups)
+	k2c_bias_add(output, bias)
+	activation(output.Array[:output.Numel])
+}
+
+/**
+* Computes the length of one output dimension of a convolution.
+* Matches keras.utils.conv_utils.conv_output_length.
+*
+* :param input_length: length of the input dimension.
+* :param filter_size: size of the kernel along the dimension.
+* :param padding: padding mode, K2C_PADDING_VALID, K2C_PADDING_SAME or K2C_PADDING_CAUSAL.
+* :param stride: stride length of the convolution.
+* :param dilation: dilation rate of the convolution.
+* :return: length of the output dimension.
+*/
+func K2c_conv_output_length(input_length int, filter_size int, padding int, stride int, dilation int) int {
+	filter_size = filter_size + (filter_size-1)*(dilation-1)
+	if padding == K2C_PADDING_SAME || padding == K2C_PADDING_CAUSAL {
+		return (input_length + stride - 1) / stride
+	}
+	return (input_length - filter_size + stride) / stride
+}
+
+/**
+* 2D (spatial) Convolution without bias or activation, added onto the output.
+* Assumes a "channels last" structure. Padded elements are treated as zeros.
+*
+* :param output: output tensor. The convolution is added to its values.
+* :param input: input tensor.
+* :param kernel: kernel tensor, Shape is {rows, cols, in_channels/groups, out_channels}.
+* :param stride: Array[2] of stride length of the convolution. Order is {stride dim 1, stride dim 2}.
+* :param dilation: Array[2] dilation rate to use for dilated convolution. Order is {dilation dim 1, dilation dim 2}.
+* :param padding: padding mode, K2C_PADDING_VALID, K2C_PADDING_SAME or K2C_PADDING_CAUSAL.
+* :param groups: number of groups the channels are split into, see k2c_conv3d_accumulate.
+*/
+func k2c_conv2d_accumulate(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, stride []int, dilation []int, padding int, groups int) {
+	var output3d = k2c_expand_dims_front(output, 4)
+	var input3d = k2c_expand_dims_front(input, 4)
+	var kernel3d = k2c_expand_dims_front(kernel, 5)
+
+	k2c_conv3d_accumulate(&output3d, &input3d, &kernel3d, []int{1, stride[0], stride[1]}, []int{1, dilation[0], dilation[1]}, padding, groups)
+}
+
+/**
+* 3D (spatial or spatio-temporal) Convolution without bias or activation, added onto the output.
+* Assumes a "channels last" structure. Padded elements are treated as zeros.
+* The channels are split into groups, and each group of output channels only sees the matching group of input
+* channels, like the groups argument of keras convolutions.
+*
+* :param output: output tensor. The convolution is added to its values.
+* :param input: input tensor.
+* :param kernel: kernel tensor, Shape is {dim 1, dim 2, dim 3, in_channels/groups, out_channels}.
+* :param stride: Array[3] of stride length of the convolution. Order is {stride dim 1, stride dim 2, stride dim 3}.
+* :param dilation: Array[3] dilation rate to use for dilated convolution. Order is {dilation dim 1, dilation dim 2, dilation dim 3}.
+* :param padding: padding mode, K2C_PADDING_VALID, K2C_PADDING_SAME or K2C_PADDING_CAUSAL.
+* :param groups: number of groups the channels are split into.
+*/
+func k2c_conv3d_accumulate(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, stride []int, dilation []int, padding int, groups int) {
+	in_dim1 := input.Shape[0]
+	in_dim2 := input.Shape[1]
+	in_dim3 := input.Shape[2]
+	in_channels := input.Shape[3]
+	out_dim1 := output.Shape[0]
+	out_dim2 := output.Shape[1]
+	out_dim3 := output.Shape[2]
+	out_channels := output.Shape[3]
+	group_in := in_channels / groups
+	group_out := out_channels / groups
+	pad0 := k2c_pad_before(in_dim1, out_dim1, kernel.Shape[0], stride[0], dilation[0], padding)
+	pad1 := k2c_pad_before(in_dim2, out_dim2, kernel.Shape[1], stride[1], dilation[1], padding)
+	pad2 := k2c_pad_before(in_dim3, out_dim3, kernel.Shape[2], stride[2], dilation[2], padding)
+
+	for x0 := 0; x0 < out_dim1; x0++ {
+		for x1 := 0; x1 < out_dim2; x1++ {
+			for x2 := 0; x2 < out_dim3; x2++ {
+				outIdx := ((x0*out_dim2+x1)*out_dim3 + x2) * out_channels
+				for z0 := 0; z0 < kernel.Shape[0]; z0++ {
+					i0 := x0*stride[0] + z0*dilation[0] - pad0
+					if i0 < 0 || i0 >= in_dim1 {
+						continue
+					}
+					for z1 := 0; z1 < kernel.Shape[1]; z1++ {
+						i1 := x1*stride[1] + z1*dilation[1] - pad1
+						if i1 < 0 || i1 >= in_dim2 {
+							continue
+						}
+						for z2 := 0; z2 < kernel.Shape[2]; z2++ {
+							i2 := x2*stride[2] + z2*dilation[2] - pad2
+							if i2 < 0 || i2 >= in_dim3 {
+								continue
+							}
+							inIdx := ((i0*in_dim2+i1)*in_dim3 + i2) * in_channels
+							kerIdx := ((z0*kernel.Shape[1]+z1)*kernel.Shape[2] + z2) * group_in * out_channels
+							for g := 0; g < groups; g++ {
+								for q := 0; q < group_in; q++ {
+									x := input.Array[inIdx+g*group_in+q]
+									for k := g * group_out; k < (g+1)*group_out; k++ {
+										output.Array[outIdx+k] += kernel.Array[kerIdx+q*out_channels+k] * x
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+/**
+* Computes the length of one output dimension of a transposed convolution.
+* Matches keras.utils.conv_utils.deconv_output_length.
+*
+* :param input_length: length of the input dimension.
+* :param filter_size: size of the kernel along the dimension.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param output_padding: amount of padding added to one side of the output, or -1 if not set.
+* :param stride: stride length of the convolution.
+* :param dilation: dilation rate of the convolution.
+* :return: length of the output dimension.
+*/
+func K2c_conv_transpose_output_length(input_length int, filter_size int, padding int, output_padding int, stride int, dilation int) int {
+	filter_size = filter_size + (filter_size-1)*(dilation-1)
+	if output_padding < 0 {
+		if padding == K2C_PADDING_SAME {
+			return input_length * stride
+		}
+		if filter_size > stride {
+			return input_length*stride + filter_size - stride
+		}
+		return input_length * stride
+	}
+	var pad = 0
+	if padding == K2C_PADDING_SAME {
+		pad = filter_size / 2
+	}
+	return (input_length-1)*stride + filter_size - 2*pad + output_padding
+}
+
+/**
+* 1D (temporal) Transposed Convolution.
+* Assumes a "channels last" structure.
+* The output tensor must already have the size given by K2c_conv_transpose_output_length.
+* Padding is that of the forward convolution whose input has the output's Shape.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param kernel: kernel tensor, Shape is {kernel_size, out_channels, in_channels}.
+* :param bias: bias tensor.
+* :param stride: stride length of the convolution.
+* :param dilation: dilation rate to use for dilated convolution.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param activation: activation function to apply to output.
+*/
+func K2c_conv1d_transpose(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride int, dilation int, padding int, activation k2c_activationType) {
 	output.fillFloat64(0)
 
+	in_times := input.Shape[0]
+	in_channels := input.Shape[1]
 	out_times := output.Shape[0]
 	out_channels := output.Shape[1]
-	in_channels := input.Shape[1]
-
-	for x0 := 0; x0 < out_times; x0++ {
-		for z := 0; z < kernel.Shape[0]; z++ {
-			for q := 0; q < in_channels; q++ {
-				for k := 0; k < out_channels; k++ {
-					output.Array[x0*out_channels+k] += kernel.Array[z*(kernel.Shape[2]*kernel.Shape[1])+q*(kernel.Shape[2])+k] * input.Array[(x0*stride+dilation*z)*in_channels+q]
+	kernel_size := kernel.Shape[0]
+	pad := k2c_pad_before(out_times, in_times, kernel_size, stride, dilation, padding)
+
+	for x0 := 0; x0 < in_times; x0++ {
+		for z := 0; z < kernel_size; z++ {
+			o0 := x0*stride + z*dilation - pad
+			if o0 < 0 || o0 >= out_times {
+				continue
+			}
+			for k := 0; k < out_channels; k++ {
+				for q := 0; q < in_channels; q++ {
+					output.Array[o0*out_channels+k] += kernel.Array[(z*out_channels+k)*in_channels+q] * input.Array[x0*in_channels+q]
 				}
 			}
 		}
@@ -125,40 +337,51 @@ func k2c_conv1d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias
 	activation(output.Array[:output.Numel])
 }
 
-
 /**
-* 2D (spatial) Convolution.
+* 2D (spatial) Transposed Convolution.
 * Assumes a "channels last" structure.
+* The output tensor must already have the size given by K2c_conv_transpose_output_length.
+* Padding is that of the forward convolution whose input has the output's Shape.
 *
 * :param output: output tensor.
 * :param input: input tensor.
-* :param kernel: kernel tensor.
+* :param kernel: kernel tensor, Shape is {rows, cols, out_channels, in_channels}.
 * :param bias: bias tensor.
 * :param stride: Array[2] of stride length of the convolution. Order is {stride dim 1, stride dim 2}.
 * :param dilation: Array[2] dilation rate to use for dilated convolution. Order is {dilation dim 1, dilation dim 2}.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
 * :param activation: activation function to apply to output.
 */
-func k2c_conv2d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride []int, dilation []int, activation k2c_activationType) {
+func K2c_conv2d_transpose(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride []int, dilation []int, padding int, activation k2c_activationType) {
 	output.fillFloat64(0)
+
+	in_rows := input.Shape[0]
+	in_cols := input.Shape[1]
+	in_channels := input.Shape[2]
 	out_rows := output.Shape[0]
 	out_cols := output.Shape[1]
 	out_channels := output.Shape[2]
-	in_channels := input.Shape[2]
+	pad0 := k2c_pad_before(out_rows, in_rows, kernel.Shape[0], stride[0], dilation[0], padding)
+	pad1 := k2c_pad_before(out_cols, in_cols, kernel.Shape[1], stride[1], dilation[1], padding)
 
-	for x0 := 0; x0 < out_rows; x0++ {
-		for x1 := 0; x1 < out_cols; x1++ {
+	for x0 := 0; x0 < in_rows; x0++ {
+		for x1 := 0; x1 < in_cols; x1++ {
+			inIdx := (x0*in_cols + x1) * in_channels
 			for z0 := 0; z0 < kernel.Shape[0]; z0++ {
+				o0 := x0*stride[0] + z0*dilation[0] - pad0
+				if o0 < 0 || o0 >= out_rows {
+					continue
+				}
 				for z1 := 0; z1 < kernel.Shape[1]; z1++ {
-					for q := 0; q < in_channels; q++ {
-						for k := 0; k < out_channels; k++ {
-							output.Array[x0*(output.Shape[2]*output.Shape[1])+
-								x1*(output.Shape[2])+k] +=
-								kernel.Array[z0*(kernel.Shape[3]*kernel.Shape[2]*kernel.Shape[1])+
-									z1*(kernel.Shape[3]*kernel.Shape[2])+
-									q*(kernel.Shape[3]+k)] *
-									input.Array[(x0+stride[0]+dilation[0]*z0)*
-										(input.Shape[2]*input.Shape[1])+
-										(x1*stride[1]+dilation[1]*z1)*(input.Shape[2])+q]
+					o1 := x1*stride[1] + z1*dilation[1] - pad1
+					if o1 < 0 || o1 >= out_cols {
+						continue
+					}
+					outIdx := (o0*out_cols + o1) * out_channels
+					kerIdx := (z0*kernel.Shape[1] + z1) * out_channels * in_channels
+					for k := 0; k < out_channels; k++ {
+						for q := 0; q < in_channels; q++ {
+							output.Array[outIdx+k] += kernel.Array[kerIdx+k*in_channels+q] * input.Array[inIdx+q]
 						}
 					}
 				}
@@ -169,47 +392,60 @@ func k2c_conv2d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias
 	activation(output.Array[:output.Numel])
 }
 
-
 /**
-* 3D (spatial or spatio-temporal) Convolution.
+* 3D (spatial or spatio-temporal) Transposed Convolution.
 * Assumes a "channels last" structure.
+* The output tensor must already have the size given by K2c_conv_transpose_output_length.
+* Padding is that of the forward convolution whose input has the output's Shape.
 *
 * :param output: output tensor.
 * :param input: input tensor.
-* :param kernel: kernel tensor.
+* :param kernel: kernel tensor, Shape is {dim 1, dim 2, dim 3, out_channels, in_channels}.
 * :param bias: bias tensor.
 * :param stride: Array[3] of stride length of the convolution. Order is {stride dim 1, stride dim 2, stride dim 3}.
 * :param dilation: Array[3] dilation rate to use for dilated convolution. Order is {dilation dim 1, dilation dim 2, dilation dim 3}.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
 * :param activation: activation function to apply to output.
 */
-func k2c_conv3d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride []int, dilation []int, activation k2c_activationType) {
+func K2c_conv3d_transpose(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, stride []int, dilation []int, padding int, activation k2c_activationType) {
 	output.fillFloat64(0)
-	dim1 := output.Shape[0]
-	dim2 := output.Shape[1]
-	dim3 := output.Shape[2]
-	out_channels := output.Shape[3]
-	in_channels := input.Shape[3]
 
-	for x0 := 0; x0 < dim1; x0++ {
-		for x1 := 0; x1 < dim2; x1++ {
-			for x2 := 0; x2 < dim3; x2++ {
+	in_dim1 := input.Shape[0]
+	in_dim2 := input.Shape[1]
+	in_dim3 := input.Shape[2]
+	in_channels := input.Shape[3]
+	out_dim1 := output.Shape[0]
+	out_dim2 := output.Shape[1]
+	out_dim3 := output.Shape[2]
+	out_channels := output.Shape[3]
+	pad0 := k2c_pad_before(out_dim1, in_dim1, kernel.Shape[0], stride[0], dilation[0], padding)
+	pad1 := k2c_pad_before(out_dim2, in_dim2, kernel.Shape[1], stride[1], dilation[1], padding)
+	pad2 := k2c_pad_before(out_dim3, in_dim3, kernel.Shape[2], stride[2], dilation[2], padding)
+
+	for x0 := 0; x0 < in_dim1; x0++ {
+		for x1 := 0; x1 < in_dim2; x1++ {
+			for x2 := 0; x2 < in_dim3; x2++ {
+				inIdx := ((x0*in_dim2+x1)*in_dim3 + x2) * in_channels
 				for z0 := 0; z0 < kernel.Shape[0]; z0++ {
+					o0 := x0*stride[0] + z0*dilation[0] - pad0
+					if o0 < 0 || o0 >= out_dim1 {
+						continue
+					}
 					for z1 := 0; z1 < kernel.Shape[1]; z1++ {
+						o1 := x1*stride[1] + z1*dilation[1] - pad1
+						if o1 < 0 || o1 >= out_dim2 {
+							continue
+						}
 						for z2 := 0; z2 < kernel.Shape[2]; z2++ {
-							for q := 0; q < in_channels; q++ {
-								for k := 0; k < out_channels; k++ {
-									output.Array[x0*(output.Shape[3]*output.Shape[2]*
-										output.Shape[1])+
-										x1*(output.Shape[3]*output.Shape[2])+
-										x2*(output.Shape[3])+k] +=
-										kernel.Array[z0*(kernel.Shape[4]*kernel.Shape[3]*kernel.Shape[2]*kernel.Shape[1])+
-											z1*(kernel.Shape[4]*kernel.Shape[3]*kernel.Shape[2])+
-											z2*(kernel.Shape[4]*kernel.Shape[3])+
-											q*(kernel.Shape[4])+k] *
-											input.Array[(x0*stride[0]+dilation[0]*z0)*
-												(input.Shape[3]*input.Shape[2]*input.Shape[1])+
-												(x1*stride[1]+dilation[1]*z1)*(input.Shape[3]*input.Shape[2])+
-												(x2*stride[2]+dilation[2]*z2)*(input.Shape[3])+q]
+							o2 := x2*stride[2] + z2*dilation[2] - pad2
+							if o2 < 0 || o2 >= out_dim3 {
+								continue
+							}
+							outIdx := ((o0*out_dim2+o1)*out_dim3 + o2) * out_channels
+							kerIdx := ((z0*kernel.Shape[1]+z1)*kernel.Shape[2] + z2) * out_channels * in_channels
+							for k := 0; k < out_channels; k++ {
+								for q := 0; q < in_channels; q++ {
+									output.Array[outIdx+k] += kernel.Array[kerIdx+k*in_channels+q] * input.Array[inIdx+q]
 								}
 							}
 						}
@@ -218,7 +454,6 @@ func k2c_conv3d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias
 			}
 		}
 	}
-
 	k2c_bias_add(output, bias)
 	activation(output.Array[:output.Numel])
 }
@@ -301,7 +536,7 @@ func k2c_crop3d(output *K2c_tensor, input *K2c_tensor, crop []int) {
 * :param input: input tensor.
 * :param size: Upsampling factor.
 */
-func k2c_upsampling1d(output *K2c_tensor, input *K2c_tensor, size int) {
+func K2c_upsampling1d(output *K2c_tensor, input *K2c_tensor, size int) {
 	var in_height = input.Shape[0]
 	var in_width = input.Shape[1]
 
@@ -322,8 +557,14 @@ func k2c_upsampling1d(output *K2c_tensor, input *K2c_tensor, size int) {
 * :param output: output tensor.
 * :param input: input tensor.
 * :param size: Array[2] of upsampling factors. Order is {upsampling dim 1, upsampling dim 2}.
+* :param interpolation: K2C_INTERPOLATION_NEAREST to repeat, or K2C_INTERPOLATION_BILINEAR, K2C_INTERPOLATION_BICUBIC
+*     or K2C_INTERPOLATION_AREA to interpolate like tf.image.resize.
 */
-func k2c_upsampling2d(output *K2c_tensor, input *K2c_tensor, size []int) {
+func K2c_upsampling2d(output *K2c_tensor, input *K2c_tensor, size []int, interpolation int) {
+	if interpolation != K2C_INTERPOLATION_NEAREST {
+		k2c_resize(output, input, nil, nil, interpolation)
+		return
+	}
 	var out_height = output.Shape[0]
 	var out_width = output.Shape[1]
 	var channels = output.Shape[2]
@@ -341,14 +582,20 @@ func k2c_upsampling2d(output *K2c_tensor, input *K2c_tensor, size []int) {
 
 
 /**
-* 2D (spatial) Upsampling.
+* 3D (spatial or spatio-temporal) Upsampling.
 * Repeats the 1st, 2nd and 3rd dimensions of the data by size[0], size[1] and size[2] respectively.
 *
 * :param output: output tensor.
 * :param input: input tensor.
 * :param size: Array[3] of upsampling factors. Order is {upsampling dim 1, upsampling dim 2, upsampling dim 3}.
+* :param interpolation: K2C_INTERPOLATION_NEAREST to repeat, or K2C_INTERPOLATION_BILINEAR (trilinear),
+*     K2C_INTERPOLATION_BICUBIC (tricubic) or K2C_INTERPOLATION_AREA to interpolate like tf.image.resize.
 */
-func k2c_upsampling3d(output *K2c_tensor, input *K2c_tensor, size []int) {
+func K2c_upsampling3d(output *K2c_tensor, input *K2c_tensor, size []int, interpolation int) {
+	if interpolation != K2C_INTERPOLATION_NEAREST {
+		k2c_resize(output, input, nil, nil, interpolation)
+		return
+	}
 	var dim1 = output.Shape[0]
 	var dim2 = output.Shape[1]
 	var dim3 = output.Shape[2]
@@ -0,0 +1,251 @@
+package keras2go
+
+import "testing"
+
+// The expected outputs of the transposed convolutions are the adjoint of the tensorflow forward convolution, ie
+// tf.nn.conv_transpose as keras calls it, and their shapes follow keras deconv_output_length.
+
+func TestK2c_conv_transpose(t *testing.T) {
+	tests := []struct {
+		name           string
+		input_shape    []int
+		kernel_shape   []int
+		stride         []int
+		dilation       []int
+		padding        int
+		output_padding []int
+		input          []float64
+		kernel         []float64
+		bias           []float64
+		output_shape   []int
+		expected       []float64
+	}{
+		{
+			name:        "1d same stride 2 output_padding 1",
+			input_shape: []int{4, 2}, kernel_shape: []int{3, 2, 2},
+			stride: []int{2}, dilation: []int{1}, padding: K2C_PADDING_SAME, output_padding: []int{1},
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25},
+			bias:         []float64{-0.1, 0.0},
+			output_shape: []int{8, 2},
+			expected:     []float64{0.775, 0.75, -0.6, -0.625, -0.1, -0.5, -0.1, -0.375, -1.975, -2.0, 0.4, 0.75, -0.35, 0.0, 0.9, 1.0},
+		},
+		{
+			name:        "1d valid stride 2",
+			input_shape: []int{4, 2}, kernel_shape: []int{3, 2, 2},
+			stride: []int{2}, dilation: []int{1}, padding: K2C_PADDING_VALID, output_padding: []int{-1},
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25},
+			bias:         []float64{-0.1, 0.0},
+			output_shape: []int{9, 2},
+			expected:     []float64{0.775, 0.75, -0.6, -0.625, -0.1, -0.5, -0.1, -0.375, -1.975, -2.0, 0.4, 0.75, -0.35, 0.0, 0.9, 1.0, 0.9, 1.0},
+		},
+		{
+			name:        "1d same dilation 2",
+			input_shape: []int{4, 2}, kernel_shape: []int{3, 2, 2},
+			stride: []int{1}, dilation: []int{2}, padding: K2C_PADDING_SAME, output_padding: []int{-1},
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25},
+			bias:         []float64{-0.1, 0.0},
+			output_shape: []int{4, 2},
+			expected:     []float64{-1.725, -1.5, -1.35, -1.625, -0.35, -0.125, 0.15, -0.125},
+		},
+		{
+			name:        "1d valid dilation 2 output_padding 0",
+			input_shape: []int{3, 1}, kernel_shape: []int{2, 1, 1},
+			stride: []int{1}, dilation: []int{2}, padding: K2C_PADDING_VALID, output_padding: []int{0},
+			input:        []float64{-1.0, 0.5, -1.5},
+			kernel:       []float64{-0.5, 0.75},
+			bias:         []float64{-0.1},
+			output_shape: []int{5, 1},
+			expected:     []float64{0.4, -0.35, -0.1, 0.275, -1.225},
+		},
+		{
+			name:        "2d same stride 2 output_padding (1,0)",
+			input_shape: []int{2, 3, 1}, kernel_shape: []int{3, 2, 2, 1},
+			stride: []int{2, 2}, dilation: []int{1, 1}, padding: K2C_PADDING_SAME, output_padding: []int{1, 0},
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25},
+			bias:         []float64{-0.1, 0.0},
+			output_shape: []int{4, 4, 2},
+			expected:     []float64{0.15, -1.0, -0.35, 0.375, -0.225, 0.5, 0.65, -1.125, -0.35, 0.75, -0.1, -0.5, 0.025, -0.375, -0.1, 1.5, -0.85, 0.25, -0.6, 0.875, -0.1, 1.375, -0.6, 0.375, -0.1, 0.0, -0.1, -1.5, 0.275, -1.125, -0.1, 0.5},
+		},
+		{
+			name:        "2d valid stride (1,2)",
+			input_shape: []int{2, 2, 2}, kernel_shape: []int{2, 2, 1, 2},
+			stride: []int{1, 2}, dilation: []int{1, 1}, padding: K2C_PADDING_VALID, output_padding: []int{-1, -1},
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75},
+			bias:         []float64{-0.1},
+			output_shape: []int{3, 4, 1},
+			expected:     []float64{0.775, 0.65, 0.65, 0.275, -1.725, -1.6, -1.35, -1.725, 0.4, 0.65, 0.9, 0.9},
+		},
+		{
+			name:        "3d same stride (2,1,1) dilation (1,1,2)",
+			input_shape: []int{2, 1, 3, 1}, kernel_shape: []int{2, 1, 2, 1, 1},
+			stride: []int{2, 1, 1}, dilation: []int{1, 1, 2}, padding: K2C_PADDING_SAME, output_padding: []int{-1, -1, -1},
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0},
+			bias:         []float64{-0.1},
+			output_shape: []int{4, 1, 3, 1},
+			expected:     []float64{-0.35, -0.1, 0.275, -0.225, -0.725, 0.4, -0.85, 0.15, 1.025, -0.475, 0.025, 1.4},
+		},
+		{
+			name:        "3d valid output_padding (1,0,0)",
+			input_shape: []int{2, 2, 1, 1}, kernel_shape: []int{2, 1, 1, 2, 1},
+			stride: []int{2, 1, 1}, dilation: []int{1, 1, 1}, padding: K2C_PADDING_VALID, output_padding: []int{1, 0, 0},
+			input:        []float64{-1.0, 0.5, -1.5, 0.0},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0},
+			bias:         []float64{-0.1, 0.0},
+			output_shape: []int{5, 2, 1, 2},
+			expected:     []float64{0.4, -0.75, -0.35, 0.375, 0.15, -1.0, -0.225, 0.5, 0.65, -1.125, -0.1, 0.0, 0.275, -1.5, -0.1, 0.0, -0.1, 0.0, -0.1, 0.0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ndim = len(test.stride)
+			var shape = make([]int, ndim+1)
+			for i := 0; i < ndim; i++ {
+				shape[i] = K2c_conv_transpose_output_length(test.input_shape[i], test.kernel_shape[i], test.padding,
+					test.output_padding[i], test.stride[i], test.dilation[i])
+			}
+			shape[ndim] = test.kernel_shape[ndim]
+			for i := range shape {
+				if shape[i] != test.output_shape[i] {
+					t.Fatalf("got shape %v, expected %v", shape, test.output_shape)
+				}
+			}
+			input := newTestTensor(test.input_shape)
+			copy(input.Array, test.input)
+			kernel := newTestTensor(test.kernel_shape)
+			copy(kernel.Array, test.kernel)
+			bias := newTestTensor([]int{len(test.bias)})
+			copy(bias.Array, test.bias)
+			output := newTestTensor(shape)
+			switch ndim {
+			case 1:
+				K2c_conv1d_transpose(output, input, kernel, bias, test.stride[0], test.dilation[0], test.padding, K2c_linear)
+			case 2:
+				K2c_conv2d_transpose(output, input, kernel, bias, test.stride, test.dilation, test.padding, K2c_linear)
+			case 3:
+				K2c_conv3d_transpose(output, input, kernel, bias, test.stride, test.dilation, test.padding, K2c_linear)
+			}
+			checkArrayTol(t, output.Array, test.expected, 1e-12)
+		})
+	}
+}
+
+// The expected outputs of the convolutions pad the input explicitly like tensorflow, with the extra element of
+// 'same' padding after and all of 'causal' padding before, and convolve each group of channels separately.
+
+func TestK2c_conv_padding_groups(t *testing.T) {
+	tests := []struct {
+		name         string
+		input_shape  []int
+		kernel_shape []int
+		groups       int
+		stride       []int
+		dilation     []int
+		padding      int
+		input        []float64
+		kernel       []float64
+		output_shape []int
+		expected     []float64
+	}{
+		{
+			name:        "1d causal dilation 2",
+			input_shape: []int{5, 2}, kernel_shape: []int{3, 2, 2}, groups: 1,
+			stride: []int{1}, dilation: []int{2}, padding: K2C_PADDING_CAUSAL,
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25},
+			output_shape: []int{5, 2},
+			expected:     []float64{-0.125, 0.375, -0.75, 0.75, 0.5, 0.0, -0.25, 1.25, -0.625, -1.25},
+		},
+		{
+			name:        "1d causal stride 2",
+			input_shape: []int{5, 1}, kernel_shape: []int{2, 1, 2}, groups: 1,
+			stride: []int{2}, dilation: []int{1}, padding: K2C_PADDING_CAUSAL,
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0},
+			output_shape: []int{3, 2},
+			expected:     []float64{0.25, -1.0, 0.125, -1.125, -0.375, 1.5},
+		},
+		{
+			name:        "2d same even kernel stride (1,2)",
+			input_shape: []int{4, 5, 1}, kernel_shape: []int{2, 4, 1, 2}, groups: 1,
+			stride: []int{1, 2}, dilation: []int{1, 1}, padding: K2C_PADDING_SAME,
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25},
+			output_shape: []int{4, 3, 2},
+			expected:     []float64{1.5, -0.5, 2.25, -2.875, -1.25, 1.625, 1.875, -0.875, 1.75, -0.5, -0.125, -1.375, -1.25, -1.25, 0.375, 1.0, 0.125, -0.875, -0.125, 2.0, 0.625, -2.25, -0.625, 0.625},
+		},
+		{
+			name:        "2d same stride 2",
+			input_shape: []int{6, 6, 2}, kernel_shape: []int{3, 3, 2, 1}, groups: 1,
+			stride: []int{2, 2}, dilation: []int{1, 1}, padding: K2C_PADDING_SAME,
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5},
+			output_shape: []int{3, 3, 1},
+			expected:     []float64{0.75, -0.125, 2.375, 2.5, 0.75, -1.25, -1.875, 1.625, -0.375},
+		},
+		{
+			name:        "2d valid 2 groups of 2 to 3 channels",
+			input_shape: []int{3, 3, 4}, kernel_shape: []int{2, 2, 2, 6}, groups: 2,
+			stride: []int{1, 1}, dilation: []int{1, 1}, padding: K2C_PADDING_VALID,
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25},
+			output_shape: []int{2, 2, 6},
+			expected:     []float64{4.0, -2.25, -1.75, 0.125, -2.25, 2.125, -0.75, 3.0, -2.25, 3.375, -0.375, -3.0, 1.125, -2.25, 1.125, -2.375, -1.875, 4.25, 1.625, 0.375, -2.0, 2.625, -2.625, 0.0},
+		},
+		{
+			name:        "1d same 3 groups of 2 to 1 channel",
+			input_shape: []int{4, 6}, kernel_shape: []int{2, 2, 3}, groups: 3,
+			stride: []int{1}, dilation: []int{1}, padding: K2C_PADDING_SAME,
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75, 0.5, -0.5, 0.75, -0.25},
+			output_shape: []int{4, 3},
+			expected:     []float64{1.75, -2.625, -0.25, -2.125, 1.5, -2.25, 1.875, -2.25, 1.0, -1.25, 0.75, 1.375},
+		},
+		{
+			name:        "3d same 2 groups dilation (1,2,1)",
+			input_shape: []int{2, 3, 2, 2}, kernel_shape: []int{2, 2, 1, 1, 2}, groups: 2,
+			stride: []int{1, 1, 1}, dilation: []int{1, 2, 1}, padding: K2C_PADDING_SAME,
+			input:        []float64{-1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5, 0.0, 1.5, -0.5, 1.0, -1.0, 0.5, -1.5},
+			kernel:       []float64{-0.5, 0.75, -0.25, 1.0, 0.0, -1.0, 0.25, -0.75},
+			output_shape: []int{2, 3, 2, 2},
+			expected:     []float64{-0.75, -0.5, 0.125, -0.625, 0.625, -1.375, 0.875, 2.125, -0.75, -0.375, -0.5, -0.25, 0.375, 0.0, -0.375, -0.5, 0.0, -0.25, 0.375, -1.125, 0.75, 0.0, -0.75, -0.375},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ndim = len(test.stride)
+			var shape = make([]int, ndim+1)
+			for i := 0; i < ndim; i++ {
+				shape[i] = K2c_conv_output_length(test.input_shape[i], test.kernel_shape[i], test.padding,
+					test.stride[i], test.dilation[i])
+			}
+			shape[ndim] = test.kernel_shape[ndim+1]
+			for i := range shape {
+				if shape[i] != test.output_shape[i] {
+					t.Fatalf("got shape %v, expected %v", shape, test.output_shape)
+				}
+			}
+			input := newTestTensor(test.input_shape)
+			copy(input.Array, test.input)
+			kernel := newTestTensor(test.kernel_shape)
+			copy(kernel.Array, test.kernel)
+			bias := newTestTensor([]int{shape[ndim]})
+			output := newTestTensor(shape)
+			switch ndim {
+			case 1:
+				K2c_conv1d(output, input, kernel, bias, test.stride[0], test.dilation[0], test.padding, test.groups,
+					K2c_linear)
+			case 2:
+				K2c_conv2d(output, input, kernel, bias, test.stride, test.dilation, test.padding, test.groups, K2c_linear)
+			case 3:
+				K2c_conv3d(output, input, kernel, bias, test.stride, test.dilation, test.padding, test.groups, K2c_linear)
+			}
+			checkArrayTol(t, output.Array, test.expected, 1e-12)
+		})
+	}
+}
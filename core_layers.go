@@ -1,6 +1,18 @@
 package keras2go
 
+/**
+* Dense (fully connected) layer.
+* the activation is applied separately to each vector along the last axis, like keras does.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param kernel: kernel tensor.
+* :param bias: bias tensor.
+* :param activation: activation function to apply to output.
+* :param fwork: Array of working space, size(fwork) = size(input) + size(kernel)
+*/
 func K2c_dense(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, activation k2c_activationType, fwork []float64) {
+	var outcols = kernel.Shape[1]
 	if input.Ndim <= 2 {
 		var outrows int
 		if input.Ndim > 1 {
@@ -8,11 +20,11 @@ func K2c_dense(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *
 		} else {
 			outrows = 1
 		}
-		var outcols = kernel.Shape[1]
 		var innerdim = kernel.Shape[0]
-		var outsize = outrows * outcols
 		k2c_affine_matmul(output.Array, input.Array, kernel.Array, bias.Array, outrows, outcols, innerdim)
-		activation(output.Array[:outsize])
+		for i := 0; i < outrows; i++ {
+			activation(output.Array[i*outcols : (i+1)*outcols])
+		}
 	} else {
 		var axesA = []int{input.Ndim - 1}
 		var axesB = []int{0}
@@ -20,10 +32,46 @@ func K2c_dense(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *
 		var normalize = 0
 		k2c_dot(output, input, kernel, axesA, axesB, naxes, normalize, fwork)
 		k2c_bias_add(output, bias)
-		activation(output.Array[:output.Numel])
+		for i := 0; i < output.Numel; i += outcols {
+			activation(output.Array[i : i+outcols])
+		}
 	}
 }
 
+/**
+* EinsumDense layer.
+* computes einsum(equation, input, kernel) + bias, followed by the activation along the last axis.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param kernel: kernel tensor.
+* :param bias: bias tensor, broadcast to the output aligned from the last axis, or nil.
+* :param plan: plan of the einsum of the input and kernel, without the batch axis, eg "bc,cde->bde". See
+*     K2c_new_einsum_plan.
+* :param activation: activation function to apply to output.
+* :param fwork: Array of working space, size(fwork) = size(input) + size(kernel) + size(output).
+*/
+func K2c_einsum_dense(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor,
+	plan *K2c_einsum_plan, activation k2c_activationType, fwork []float64) {
+	K2c_einsum(output, input, kernel, plan, fwork)
+	if bias != nil {
+		k2c_broadcast_op(output, bias, k2c_op_add)
+	}
+	K2c_activation(output, output, activation)
+}
+
+/**
+* Activation layer.
+* applies the activation separately to each vector along the last axis, like keras does.
+*
+* :param output: output tensor. May be the input tensor.
+* :param input: input tensor.
+* :param activation: activation function to apply.
+*/
+func K2c_activation(output *K2c_tensor, input *K2c_tensor, activation k2c_activationType) {
+	k2c_along_axis(output, input, input.Ndim-1, activation)
+}
+
 func K2c_flatten(output *K2c_tensor, input *K2c_tensor) {
 	copy(output.Array, input.Array[:input.Numel])
 	for i := 0; i < input.Ndim; i++ {
@@ -72,3 +120,33 @@ func K2c_repeat_vector(output *K2c_tensor, input *K2c_tensor, n int) {
 		}
 	}
 }
+
+/**
+* Masking layer.
+* masks a timestep when all of its features equal mask_value. Masked timesteps are set to zero in the output.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param mask: Array[timesteps] output mask, false for masked timesteps.
+* :param mask_value: value marking padded features.
+ */
+func K2c_masking(output *K2c_tensor, input *K2c_tensor, mask []bool, mask_value float64) {
+	var in_height = input.Shape[0]
+	var in_width = input.Numel / in_height
+	for i := 0; i < in_height; i++ {
+		mask[i] = false
+		for j := 0; j < in_width; j++ {
+			if input.Array[i*in_width+j] != mask_value {
+				mask[i] = true
+				break
+			}
+		}
+		for j := 0; j < in_width; j++ {
+			if mask[i] {
+				output.Array[i*in_width+j] = input.Array[i*in_width+j]
+			} else {
+				output.Array[i*in_width+j] = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package keras2go
+
+import (
+	"fmt"
+	"sync"
+)
+
+/**
+* Go implementation of a custom keras layer, ie a Lambda layer or a subclass of Layer.
+* generated models make one instance for each call of the layer through K2c_new_custom_call, once it is registered
+* with K2c_register_layer.
+*/
+type K2c_custom_layer interface {
+	// OutputShape infers the output Shape of the layer from the Shape of each input, without the batch axis.
+	OutputShape(input_shapes [][]int) ([]int, error)
+	// Call runs the forward pass of the layer. weights are in the order of keras get_weights. Call runs concurrently
+	// when the model does. Its error, eg for bad input values, is returned by the model.
+	Call(output *K2c_tensor, inputs []*K2c_tensor, weights []*K2c_tensor) error
+}
+
+/**
+* Makes a custom layer from its keras config.
+*
+* :param config: config of the layer, as the JSON of keras get_config.
+* :return: the layer, or an error if the config is not supported.
+*/
+type K2c_custom_layer_factory func(config []byte) (K2c_custom_layer, error)
+
+var k2c_custom_mutex sync.Mutex
+var k2c_custom_factories = make(map[string]K2c_custom_layer_factory)
+var k2c_custom_activations = make(map[string]k2c_activationType)
+
+/**
+* Registers the implementation of a custom layer.
+* the name is the class name of a subclass of Layer, or the function name of a Lambda layer. Lambda layers of
+* anonymous functions go by the name of the layer. Layers are made on the first run of the model, so they are
+* registered before.
+*
+* :param name: name of the layer.
+* :param factory: makes the layer from its config.
+*/
+func K2c_register_layer(name string, factory K2c_custom_layer_factory) {
+	k2c_custom_mutex.Lock()
+	defer k2c_custom_mutex.Unlock()
+	k2c_custom_factories[name] = factory
+}
+
+/**
+* Registers the implementation of a custom activation.
+*
+* :param name: name of the activation, ie the name of the function or the class name of an activation object.
+* :param activation: activation function, applied in place.
+*/
+func K2c_register_activation(name string, activation k2c_activationType) {
+	k2c_custom_mutex.Lock()
+	defer k2c_custom_mutex.Unlock()
+	k2c_custom_activations[name] = activation
+}
+
+/**
+* Custom activation of a model, looked up by name in the registered and then the built-in activations.
+* generated models make one for each custom activation, in a package level variable, and resolve it at the start of
+* each run. An activation that is not registered yet is looked up again on the next run.
+*/
+type K2c_custom_activation struct {
+	name       string
+	mutex      sync.Mutex
+	activation k2c_activationType
+}
+
+/**
+* Makes a custom activation. It is looked up by Resolve.
+*
+* :param name: name of the activation, see K2c_register_activation.
+* :return: the custom activation.
+*/
+func K2c_new_custom_activation(name string) *K2c_custom_activation {
+	return &K2c_custom_activation{name: name}
+}
+
+/**
+* Looks up the activation function, if it has not been found yet.
+*
+* :return: error if there is no activation of that name.
+*/
+func (a *K2c_custom_activation) Resolve() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.activation != nil {
+		return nil
+	}
+	k2c_custom_mutex.Lock()
+	a.activation = k2c_custom_activations[a.name]
+	k2c_custom_mutex.Unlock()
+	if a.activation == nil {
+		a.activation = K2c_get_activation(a.name)
+	}
+	if a.activation == nil {
+		return fmt.Errorf("keras2go: activation %s is not registered", a.name)
+	}
+	return nil
+}
+
+/**
+* Applies the activation, once Resolve has found it.
+*
+* :param x: Array of input values. Gets overwritten by output.
+*/
+func (a *K2c_custom_activation) Apply(x []float64) {
+	a.activation(x)
+}
+
+/**
+* One call of a custom layer in a model, with its own instance of the layer.
+*/
+type K2c_custom_call struct {
+	name   string
+	config string
+	once   sync.Once
+	layer  K2c_custom_layer
+	err    error
+}
+
+/**
+* Makes a call of a custom layer. The layer is made on the first run of the call.
+*
+* :param name: name of the layer, see K2c_register_layer.
+* :param config: config of the layer, as the JSON of keras get_config.
+* :return: call of the layer.
+*/
+func K2c_new_custom_call(name string, config string) *K2c_custom_call {
+	return &K2c_custom_call{name: name, config: config}
+}
+
+/**
+* Runs the custom layer.
+* the layer is made on the first run, and its output Shape is checked against the output.
+*
+* :param output: output tensor.
+* :param inputs: input tensors.
+* :param weights: weights of the layer.
+* :return: error if the layer is not registered, rejects its config, infers another output Shape or fails to run.
+*/
+func (c *K2c_custom_call) Call(output *K2c_tensor, inputs []*K2c_tensor, weights []*K2c_tensor) error {
+	c.once.Do(func() {
+		c.layer, c.err = k2c_make_custom_layer(c.name, c.config, output, inputs)
+	})
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.layer.Call(output, inputs, weights); err != nil {
+		return fmt.Errorf("keras2go: layer %s: %v", c.name, err)
+	}
+	return nil
+}
+
+/**
+* Makes the custom layer of a name and config, and checks its output Shape.
+*/
+func k2c_make_custom_layer(name string, config string, output *K2c_tensor, inputs []*K2c_tensor) (K2c_custom_layer, error) {
+	k2c_custom_mutex.Lock()
+	var factory, ok = k2c_custom_factories[name]
+	k2c_custom_mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("keras2go: layer %s is not registered", name)
+	}
+	layer, err := factory([]byte(config))
+	if err != nil {
+		return nil, fmt.Errorf("keras2go: layer %s: %v", name, err)
+	}
+	var input_shapes = make([][]int, len(inputs))
+	for i, input := range inputs {
+		input_shapes[i] = append([]int{}, input.Shape[:input.Ndim]...)
+	}
+	shape, err := layer.OutputShape(input_shapes)
+	if err != nil {
+		return nil, fmt.Errorf("keras2go: layer %s: %v", name, err)
+	}
+	var matches = len(shape) == output.Ndim
+	for d := 0; matches && d < output.Ndim; d++ {
+		matches = shape[d] == output.Shape[d]
+	}
+	if !matches {
+		return nil, fmt.Errorf("keras2go: layer %s infers output shape %v, the model has %v", name, shape,
+			output.Shape[:output.Ndim])
+	}
+	return layer, nil
+}
@@ -0,0 +1,102 @@
+package keras2go
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+type testScaleLayer struct {
+	Factor float64 `json:"factor"`
+}
+
+func (l *testScaleLayer) OutputShape(input_shapes [][]int) ([]int, error) {
+	return input_shapes[0], nil
+}
+
+func (l *testScaleLayer) Call(output *K2c_tensor, inputs []*K2c_tensor, weights []*K2c_tensor) error {
+	for i := 0; i < output.Numel; i++ {
+		if math.IsNaN(inputs[0].Array[i]) {
+			return errors.New("input is NaN")
+		}
+		output.Array[i] = l.Factor*inputs[0].Array[i] + weights[0].Array[i%weights[0].Numel]
+	}
+	return nil
+}
+
+func TestK2c_custom_layer_call(t *testing.T) {
+	K2c_register_layer("TestScale", func(config []byte) (K2c_custom_layer, error) {
+		var layer = &testScaleLayer{}
+		return layer, json.Unmarshal(config, layer)
+	})
+	var input = newTestTensor([]int{2, 2})
+	copy(input.Array, []float64{1, 2, 3, 4})
+	var bias = newTestTensor([]int{2})
+	copy(bias.Array, []float64{10, 20})
+	var output = newTestTensor([]int{2, 2})
+	var call = K2c_new_custom_call("TestScale", `{"factor": 3}`)
+	if err := call.Call(output, []*K2c_tensor{input}, []*K2c_tensor{bias}); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, output.Array, []float64{13, 26, 19, 32})
+
+	// each call has its own instance, and checks its own shapes
+	var wrong = newTestTensor([]int{4})
+	var other = K2c_new_custom_call("TestScale", `{"factor": 3}`)
+	if err := other.Call(wrong, []*K2c_tensor{input}, []*K2c_tensor{bias}); err == nil {
+		t.Fatal("expected an error for the output shape")
+	}
+	if call.layer == other.layer {
+		t.Fatal("calls share an instance of the layer")
+	}
+	var nan = newTestTensor([]int{2, 2})
+	nan.Array[3] = math.NaN()
+	if err := call.Call(output, []*K2c_tensor{nan}, []*K2c_tensor{bias}); err == nil ||
+		err.Error() != "keras2go: layer TestScale: input is NaN" {
+		t.Fatal("expected the error of the layer, got", err)
+	}
+	var missing = K2c_new_custom_call("TestMissing", `{}`)
+	for i := 0; i < 2; i++ {
+		if err := missing.Call(output, []*K2c_tensor{input}, nil); err == nil {
+			t.Fatal("expected an error for a layer that is not registered")
+		}
+	}
+
+	K2c_register_activation("test_double", func(x []float64) {
+		for i := range x {
+			x[i] *= 2
+		}
+	})
+	var double = K2c_new_custom_activation("test_double")
+	if err := double.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	K2c_activation(output, input, double.Apply)
+	checkArray(t, output.Array, []float64{2, 4, 6, 8})
+
+	// an activation that is not registered yet is an error, and is looked up again on the next resolve
+	var later = K2c_new_custom_activation("test_later")
+	if err := later.Resolve(); err == nil {
+		t.Fatal("expected an error for an activation that is not registered")
+	}
+	K2c_register_activation("test_later", func(x []float64) {
+		for i := range x {
+			x[i] = -x[i]
+		}
+	})
+	if err := later.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	K2c_activation(output, input, later.Apply)
+	checkArray(t, output.Array, []float64{-1, -2, -3, -4})
+
+	// built-in activations are found by their keras name
+	var relu6 = K2c_new_custom_activation("relu6")
+	if err := relu6.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	copy(input.Array, []float64{-1, 2, 7, 6.5})
+	K2c_activation(output, input, relu6.Apply)
+	checkArray(t, output.Array, []float64{0, 2, 6, 6})
+}
@@ -0,0 +1,296 @@
+package keras2go
+
+import (
+	"errors"
+	"strings"
+)
+
+/**
+* Plan of an einsum of one or two operands.
+* the labels of the operands are sorted into batch labels (in both operands and the output), free labels (in one
+* operand and the output), contracted labels (in both operands only) and summed labels (in one operand only). Each
+* operand is permuted into [batch, free, contracted] order, summing the summed labels, so that the contraction is
+* one matrix product per batch element.
+* a plan is made once for an equation and the shapes of its operands, see K2c_new_einsum_plan.
+*/
+type K2c_einsum_plan struct {
+	operands  [][]rune             /** labels of each operand, with the ellipsis expanded. */
+	output    []rune               /** labels of the output, with the ellipsis expanded. */
+	size      map[rune]int         /** size of each label. */
+	batch     []rune               /** labels kept from both operands. */
+	free      [2][]rune            /** labels kept from only one operand. */
+	contract  []rune               /** labels contracted between the operands. */
+	nbatch    int                  /** number of matrix products. */
+	rows      int                  /** rows of each matrix product. */
+	cols      int                  /** columns of each matrix product. */
+	inner     int                  /** contracted size of each matrix product. */
+	strides   [2][K2C_MAX_NDIM]int /** stride of each axis of the operands when permuted, or in the output with one operand. */
+	c_shape   [K2C_MAX_NDIM]int    /** shape of the products, [batch, free[0], free[1]]. */
+	c_ndim    int                  /** number of axes of the products. */
+	c_strides [K2C_MAX_NDIM]int    /** stride in the output of each axis of the products. */
+}
+
+/**
+* Makes the plan of an einsum, for K2c_einsum.
+* panics if the equation does not match the shapes, which the converter checks beforehand.
+*
+* :param equation: einsum equation, eg "abc,cde->abde".
+* :param shapes: shapes of the one or two operands.
+* :return: plan of the einsum.
+*/
+func K2c_new_einsum_plan(equation string, shapes ...[]int) *K2c_einsum_plan {
+	plan, err := k2c_parse_einsum(equation, shapes...)
+	if err != nil {
+		panic(err)
+	}
+	if len(shapes) == 1 {
+		plan.strides[0] = k2c_einsum_strides(plan.operands[0], plan.output, plan.size)
+		return plan
+	}
+	plan.nbatch = k2c_einsum_numel(plan.batch, plan.size)
+	plan.rows = k2c_einsum_numel(plan.free[0], plan.size)
+	plan.cols = k2c_einsum_numel(plan.free[1], plan.size)
+	plan.inner = k2c_einsum_numel(plan.contract, plan.size)
+
+	var a_labels = append(append(append([]rune{}, plan.batch...), plan.free[0]...), plan.contract...)
+	var b_labels = append(append(append([]rune{}, plan.batch...), plan.contract...), plan.free[1]...)
+	var c_labels = append(append(append([]rune{}, plan.batch...), plan.free[0]...), plan.free[1]...)
+	plan.strides[0] = k2c_einsum_strides(plan.operands[0], a_labels, plan.size)
+	plan.strides[1] = k2c_einsum_strides(plan.operands[1], b_labels, plan.size)
+	plan.c_strides = k2c_einsum_strides(c_labels, plan.output, plan.size)
+	plan.c_ndim = len(c_labels)
+	for d := range plan.c_shape {
+		plan.c_shape[d] = 1
+	}
+	for d, label := range c_labels {
+		plan.c_shape[d] = plan.size[label]
+	}
+	return plan
+}
+
+/**
+* Parses an einsum equation, eg "abc,cde->abde".
+* an ellipsis stands for the axes of an operand that are not labelled, aligned from the last one like numpy does.
+* without "->" the output has the labels appearing once, in alphabetical order. Repeated labels within an operand,
+* ie diagonals, are not supported.
+*
+* :param equation: einsum equation.
+* :param shapes: shapes of the one or two operands.
+* :return: plan of the einsum, or an error if the equation does not match the operands.
+*/
+func k2c_parse_einsum(equation string, shapes ...[]int) (*K2c_einsum_plan, error) {
+	equation = strings.Replace(equation, " ", "", -1)
+	var terms = strings.Split(equation, "->")
+	var inputs = strings.Split(terms[0], ",")
+	if len(terms) > 2 || len(inputs) != len(shapes) || len(shapes) < 1 || len(shapes) > 2 {
+		return nil, errors.New("keras2go: einsum equation " + equation + " does not match its operands")
+	}
+	var plan = &K2c_einsum_plan{size: make(map[rune]int)}
+	var ellipsis_ndim = 0
+	var count = make(map[rune]int)
+	for k, term := range inputs {
+		var labels, n, err = k2c_einsum_labels(term, len(shapes[k]), false)
+		if err != nil {
+			return nil, err
+		}
+		if n > ellipsis_ndim {
+			ellipsis_ndim = n
+		}
+		for d, label := range labels {
+			if size, ok := plan.size[label]; ok && size != shapes[k][d] {
+				return nil, errors.New("keras2go: einsum label " + string(label) + " has different sizes")
+			}
+			plan.size[label] = shapes[k][d]
+			count[label]++
+		}
+		plan.operands = append(plan.operands, labels)
+	}
+	if len(terms) == 2 {
+		var labels, _, err = k2c_einsum_labels(terms[1], ellipsis_ndim, true)
+		if err != nil {
+			return nil, err
+		}
+		plan.output = labels
+	} else {
+		// implicit output: the ellipsis first, then the labels appearing once
+		plan.output, _, _ = k2c_einsum_labels("...", ellipsis_ndim, true)
+		var once []rune
+		for label, n := range count {
+			if n == 1 && label < k2c_einsum_ellipsis {
+				once = append(once, label)
+			}
+		}
+		k2c_sort_runes(once)
+		plan.output = append(plan.output, once...)
+	}
+	for _, label := range plan.output {
+		if _, ok := plan.size[label]; !ok {
+			return nil, errors.New("keras2go: einsum output label " + string(label) + " is not in the operands")
+		}
+	}
+	if len(shapes) == 2 {
+		for _, label := range plan.output {
+			var in0 = k2c_has_rune(plan.operands[0], label)
+			var in1 = k2c_has_rune(plan.operands[1], label)
+			if in0 && in1 {
+				plan.batch = append(plan.batch, label)
+			} else if in0 {
+				plan.free[0] = append(plan.free[0], label)
+			} else {
+				plan.free[1] = append(plan.free[1], label)
+			}
+		}
+		for _, label := range plan.operands[0] {
+			if k2c_has_rune(plan.operands[1], label) && !k2c_has_rune(plan.output, label) {
+				plan.contract = append(plan.contract, label)
+			}
+		}
+	}
+	return plan, nil
+}
+
+/**
+* Labels of the ellipsis axes, above any label of an equation.
+*/
+const k2c_einsum_ellipsis = rune(0x10000)
+
+/**
+* Expands the labels of one term of an einsum equation.
+*
+* :param term: labels of the term, maybe with an ellipsis.
+* :param ndim: number of axes of the operand, or number of ellipsis axes for the output.
+* :param is_output: whether the term is the output.
+* :return: labels of the axes, number of ellipsis axes, and an error if the term does not match.
+*/
+func k2c_einsum_labels(term string, ndim int, is_output bool) ([]rune, int, error) {
+	var parts = strings.Split(term, "...")
+	var named = []rune(strings.Join(parts, ""))
+	var n = 0
+	if len(parts) == 2 {
+		if is_output {
+			n = ndim
+		} else {
+			n = ndim - len(named)
+		}
+	}
+	if len(parts) > 2 || n < 0 || (!is_output && len(named)+n != ndim) {
+		return nil, 0, errors.New("keras2go: einsum term " + term + " does not match its operand")
+	}
+	var labels []rune
+	for k, part := range parts {
+		if k == 1 {
+			// ellipsis axes are labelled from the last one, so they line up across operands
+			for i := n - 1; i >= 0; i-- {
+				labels = append(labels, k2c_einsum_ellipsis+rune(i))
+			}
+		}
+		for _, label := range part {
+			if k2c_has_rune(labels, label) {
+				return nil, 0, errors.New("keras2go: repeated einsum label " + string(label) + " is not supported")
+			}
+			labels = append(labels, label)
+		}
+	}
+	return labels, n, nil
+}
+
+func k2c_has_rune(runes []rune, r rune) bool {
+	for _, x := range runes {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+func k2c_sort_runes(runes []rune) {
+	for i := 1; i < len(runes); i++ {
+		for j := i; j > 0 && runes[j] < runes[j-1]; j-- {
+			runes[j], runes[j-1] = runes[j-1], runes[j]
+		}
+	}
+}
+
+/**
+* Strides of the axes of a tensor permuted to the given labels.
+*
+* :param src_labels: labels of the tensor axes.
+* :param dst_labels: labels of the permuted axes, all of them in src_labels.
+* :param size: size of each label.
+* :return: stride of each tensor axis in the permuted tensor, 0 for the labels that are summed.
+*/
+func k2c_einsum_strides(src_labels []rune, dst_labels []rune, size map[rune]int) [K2C_MAX_NDIM]int {
+	var strides [K2C_MAX_NDIM]int
+	var stride = 1
+	for i := len(dst_labels) - 1; i >= 0; i-- {
+		for d, label := range src_labels {
+			if label == dst_labels[i] {
+				strides[d] = stride
+			}
+		}
+		stride *= size[dst_labels[i]]
+	}
+	return strides
+}
+
+/**
+* Permutes a tensor, summing over the axes that are not kept.
+*
+* :param dst: Array of the permuted tensor, of size numel.
+* :param src: input tensor.
+* :param strides: stride of each input axis in dst, see k2c_einsum_strides.
+*/
+func k2c_einsum_permute(dst []float64, numel int, src *K2c_tensor, strides *[K2C_MAX_NDIM]int) {
+	float64SliceToZero(dst[:numel])
+	var sub [K2C_MAX_NDIM]int
+	var idx = 0
+	for i := 0; i < src.Numel; i++ {
+		dst[idx] += src.Array[i]
+		for d := src.Ndim - 1; d >= 0; d-- {
+			sub[d]++
+			idx += strides[d]
+			if sub[d] < src.Shape[d] {
+				break
+			}
+			idx -= strides[d] * sub[d]
+			sub[d] = 0
+		}
+	}
+}
+
+func k2c_einsum_numel(labels []rune, size map[rune]int) int {
+	var numel = 1
+	for _, label := range labels {
+		numel *= size[label]
+	}
+	return numel
+}
+
+/**
+* Einsum of one or two tensors, like numpy.einsum.
+* with two operands, they are permuted so that the contraction runs as one matrix product per batch element.
+*
+* :param output: output tensor.
+* :param a: first operand.
+* :param b: second operand, or nil for an einsum of one operand.
+* :param plan: plan of the einsum, made for the shapes of a and b, see K2c_new_einsum_plan.
+* :param fwork: Array of working space, size(fwork) = size(a) + size(b) + size(output).
+*/
+func K2c_einsum(output *K2c_tensor, a *K2c_tensor, b *K2c_tensor, plan *K2c_einsum_plan, fwork []float64) {
+	if b == nil {
+		k2c_einsum_permute(output.Array, output.Numel, a, &plan.strides[0])
+		return
+	}
+	var rows, cols, inner = plan.rows, plan.cols, plan.inner
+	var a_work = fwork[:plan.nbatch*rows*inner]
+	var b_work = fwork[len(a_work) : len(a_work)+plan.nbatch*inner*cols]
+	var c_work = fwork[len(a_work)+len(b_work) : len(a_work)+len(b_work)+plan.nbatch*rows*cols]
+	k2c_einsum_permute(a_work, len(a_work), a, &plan.strides[0])
+	k2c_einsum_permute(b_work, len(b_work), b, &plan.strides[1])
+	for g := 0; g < plan.nbatch; g++ {
+		k2c_matmul(c_work[g*rows*cols:(g+1)*rows*cols], a_work[g*rows*inner:(g+1)*rows*inner],
+			b_work[g*inner*cols:(g+1)*inner*cols], rows, cols, inner)
+	}
+	var c = K2c_tensor{Array: c_work, Ndim: plan.c_ndim, Numel: len(c_work), Shape: plan.c_shape}
+	k2c_einsum_permute(output.Array, output.Numel, &c, &plan.c_strides)
+}
@@ -0,0 +1,95 @@
+package keras2go
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestK2c_einsum(t *testing.T) {
+	var a = newTestTensor([]int{2, 3, 4})
+	var b = newTestTensor([]int{4, 2, 5})
+	for i := range a.Array {
+		a.Array[i] = math.Sin(float64(i))
+	}
+	for i := range b.Array {
+		b.Array[i] = math.Cos(float64(i))
+	}
+	var fwork = make([]float64, 200)
+
+	// "abc,cde->abde" against a naive loop
+	var output = newTestTensor([]int{2, 3, 2, 5})
+	K2c_einsum(output, a, b, K2c_new_einsum_plan("abc,cde->abde", []int{2, 3, 4}, []int{4, 2, 5}), fwork)
+	for i := 0; i < output.Numel; i++ {
+		var sub [K2C_MAX_NDIM]int
+		k2c_idx2sub(i, sub[:], output.Shape[:], output.Ndim)
+		var sum = 0.0
+		for c := 0; c < 4; c++ {
+			sum += a.Array[(sub[0]*3+sub[1])*4+c] * b.Array[(c*2+sub[2])*5+sub[3]]
+		}
+		if math.Abs(output.Array[i]-sum) > 1e-12 {
+			t.Fatalf("abc,cde->abde: got %v at %d, expected %v", output.Array[i], i, sum)
+		}
+	}
+
+	// batch label, transposed output and a summed label
+	var x = newTestTensor([]int{2, 3, 4})
+	var y = newTestTensor([]int{2, 4, 5})
+	for i := range x.Array {
+		x.Array[i] = float64(i % 7)
+	}
+	for i := range y.Array {
+		y.Array[i] = float64(i % 5)
+	}
+	var bmm = newTestTensor([]int{2, 5})
+	K2c_einsum(bmm, x, y, K2c_new_einsum_plan("bij,bjk->kb", []int{2, 3, 4}, []int{2, 4, 5}), fwork)
+	for k := 0; k < 5; k++ {
+		for n := 0; n < 2; n++ {
+			var sum = 0.0
+			for i := 0; i < 3; i++ {
+				for j := 0; j < 4; j++ {
+					sum += x.Array[(n*3+i)*4+j] * y.Array[(n*4+j)*5+k]
+				}
+			}
+			if bmm.Array[k*2+n] != sum {
+				t.Fatalf("bij,bjk->kb: got %v, expected %v", bmm.Array[k*2+n], sum)
+			}
+		}
+	}
+
+	// one operand with an ellipsis, and the implicit output
+	var transposed = newTestTensor([]int{4, 2, 3})
+	K2c_einsum(transposed, a, nil, K2c_new_einsum_plan("...j->j...", []int{2, 3, 4}), nil)
+	if transposed.Array[1*6+1*3+2] != a.Array[(1*3+2)*4+1] {
+		t.Fatal("...j->j... is not a transpose")
+	}
+	var implicit = newTestTensor([]int{4, 3, 2})
+	K2c_einsum(implicit, x, nil, K2c_new_einsum_plan("cba", []int{2, 3, 4}), nil)
+	if implicit.Array[(3*3+2)*2+1] != x.Array[(1*3+2)*4+3] {
+		t.Fatal("cba: wrong implicit output")
+	}
+	var summed = newTestTensor([]int{3})
+	K2c_einsum(summed, x, nil, K2c_new_einsum_plan("abc->b", []int{2, 3, 4}), nil)
+	if summed.Array[2] != x.Array[8]+x.Array[9]+x.Array[10]+x.Array[11]+x.Array[20]+x.Array[21]+x.Array[22]+x.Array[23] {
+		t.Fatal("abc->b: wrong sum")
+	}
+}
+
+func TestK2c_new_einsum_plan(t *testing.T) {
+	// diagonals and operands of the wrong rank are rejected
+	for _, equation := range []string{"ii->i", "ij,jk->ikk", "ijk->i"} {
+		var shapes = [][]int{{2, 2}}
+		if strings.Contains(equation, ",") {
+			shapes = append(shapes, []int{2, 2})
+		}
+		if _, err := k2c_parse_einsum(equation, shapes...); err == nil {
+			t.Fatal("expected an error for", equation)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a mismatched equation")
+		}
+	}()
+	K2c_new_einsum_plan("ij,jk->ik", []int{2, 3}, []int{2, 3})
+}
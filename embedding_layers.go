@@ -1,18 +1,148 @@
 package keras2go
 
+import (
+	"fmt"
+	"math"
+)
+
 /**
 * Embedding Layer.
 * turns positive integers (indexes) into dense vectors of fixed size. eg. [[4], [20]] -> [[0.25, 0.1], [0.6, -0.2]]
+* indexes that are not integers, or are outside [0, input_dim), are out of vocabulary.
+*
+* :param output: output tensor. With a combiner, the last axis of the input is reduced.
+* :param input: input tensor of indexes.
+* :param kernel: kernel mapping integers to vectors, shape (input_dim, output_dim).
+* :param oov_mode: one of K2C_OOV_*.
+* :param num_oov_buckets: number of rows at the end of the kernel reserved for out of vocabulary indexes, with
+*     K2C_OOV_BUCKET.
+* :param mask_zero: (0,1) whether index 0 is padding. Padding is left out of the combiner. Without a combiner it
+*     still looks up row 0, and is only masked, see K2c_embedding_mask.
+* :param combiner: one of K2C_COMBINER_*.
+* :return: error for an out of vocabulary index with K2C_OOV_ERROR, or for K2C_OOV_BUCKET without buckets.
+*/
+func K2c_embedding(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, oov_mode int, num_oov_buckets int,
+	mask_zero int, combiner int) error {
+	return k2c_embedding_lookup(output, input.Numel, input.Shape[input.Ndim-1], func(i int) (int, interface{}, bool) {
+		var value = input.Array[i]
+		if value != math.Trunc(value) || math.Abs(value) > math.MaxInt32 {
+			return 0, value, false
+		}
+		return int(value), value, true
+	}, kernel, oov_mode, num_oov_buckets, mask_zero, combiner)
+}
+
+/**
+* Embedding Layer for integer indexes, like K2c_embedding.
+*
+* :param output: output tensor. With a combiner, each bag of indexes gives one vector.
+* :param ids: Array of indexes.
+* :param bag_size: number of indexes in each bag, with a combiner.
+* :param kernel: kernel mapping integers to vectors, shape (input_dim, output_dim).
+* :param oov_mode: one of K2C_OOV_*.
+* :param num_oov_buckets: number of rows at the end of the kernel reserved for out of vocabulary indexes, with
+*     K2C_OOV_BUCKET.
+* :param mask_zero: (0,1) whether index 0 is padding.
+* :param combiner: one of K2C_COMBINER_*.
+* :return: error for an out of vocabulary index with K2C_OOV_ERROR, or for K2C_OOV_BUCKET without buckets.
+*/
+func K2c_embedding_ids(output *K2c_tensor, ids []int, bag_size int, kernel *K2c_tensor, oov_mode int,
+	num_oov_buckets int, mask_zero int, combiner int) error {
+	return k2c_embedding_lookup(output, len(ids), bag_size, func(i int) (int, interface{}, bool) {
+		return ids[i], ids[i], true
+	}, kernel, oov_mode, num_oov_buckets, mask_zero, combiner)
+}
+
+/**
+* Looks up and combines the vectors of the indexes.
 *
 * :param output: output tensor.
-* :param input: input tensor.
+* :param num_ids: number of indexes.
+* :param bag_size: number of indexes in each bag, with a combiner.
+* :param id: gets index i, its value as given for error messages, and whether it is an integer at all.
 * :param kernel: kernel mapping integers to vectors.
+* :param oov_mode: one of K2C_OOV_*.
+* :param num_oov_buckets: number of OOV rows at the end of the kernel, at least 1 with K2C_OOV_BUCKET.
+* :param mask_zero: (0,1) whether index 0 is padding.
+* :param combiner: one of K2C_COMBINER_*.
+* :return: error for an out of vocabulary index with K2C_OOV_ERROR, or for K2C_OOV_BUCKET without buckets.
 */
-func k2c_embedding(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor) {
+func k2c_embedding_lookup(output *K2c_tensor, num_ids int, bag_size int, id func(int) (int, interface{}, bool),
+	kernel *K2c_tensor, oov_mode int, num_oov_buckets int, mask_zero int, combiner int) error {
 	var output_dim = kernel.Shape[1]
-	for i := 0; i < input.Numel; i++ {
-		for j := 0; j < output_dim; j++ {
-			output.Array[i*output_dim+j] = kernel.Array[int(input.Array[i])*output_dim+j]
+	var vocab_size = kernel.Shape[0]
+	if oov_mode == K2C_OOV_BUCKET {
+		if num_oov_buckets < 1 || num_oov_buckets >= vocab_size {
+			return fmt.Errorf("keras2go: %d OOV buckets for an embedding kernel of %d rows", num_oov_buckets, vocab_size)
+		}
+		vocab_size -= num_oov_buckets
+	}
+	if combiner == K2C_COMBINER_NONE {
+		bag_size = 1
+	}
+	output.fillFloat64(0)
+	var count = 0
+	for i := 0; i < num_ids; i++ {
+		var out = output.Array[(i/bag_size)*output_dim : (i/bag_size+1)*output_dim]
+		var row, value, ok = id(i)
+		if mask_zero != 0 && combiner != K2C_COMBINER_NONE && ok && row == 0 {
+			ok = false
+		} else if !ok || row < 0 || row >= vocab_size {
+			switch oov_mode {
+			case K2C_OOV_ERROR:
+				if !ok {
+					return fmt.Errorf("keras2go: embedding indices[%d] = %v is not an integer", i, value)
+				}
+				return fmt.Errorf("keras2go: embedding indices[%d] = %v is not in [0, %d)", i, value, vocab_size)
+			case K2C_OOV_BUCKET:
+				row = vocab_size + (row%num_oov_buckets+num_oov_buckets)%num_oov_buckets
+				ok = true
+			default:
+				// counts as a zero vector
+				ok = false
+				count++
+			}
 		}
+		if ok {
+			var vector = kernel.Array[row*output_dim : (row+1)*output_dim]
+			for j := range out {
+				out[j] += vector[j]
+			}
+			count++
+		}
+		if combiner == K2C_COMBINER_MEAN && (i+1)%bag_size == 0 {
+			if count > 0 {
+				for j := range out {
+					out[j] /= float64(count)
+				}
+			}
+			count = 0
+		}
+	}
+	return nil
+}
+
+/**
+* Mask of an Embedding layer with mask_zero=True.
+* index 0 is reserved for padding, so those timesteps are masked.
+*
+* :param mask: Array[timesteps] output mask, false for masked timesteps.
+* :param input: input tensor of indexes.
+*/
+func K2c_embedding_mask(mask []bool, input *K2c_tensor) {
+	for i := 0; i < input.Numel; i++ {
+		mask[i] = input.Array[i] != 0
+	}
+}
+
+/**
+* Mask of an Embedding layer with mask_zero=True, for integer indexes.
+*
+* :param mask: Array[timesteps] output mask, false for masked timesteps.
+* :param ids: Array of indexes.
+*/
+func K2c_embedding_ids_mask(mask []bool, ids []int) {
+	for i, id := range ids {
+		mask[i] = id != 0
 	}
 }
@@ -0,0 +1,71 @@
+package keras2go
+
+import "testing"
+
+func TestK2c_embedding(t *testing.T) {
+	var kernel = newTestTensor([]int{4, 2})
+	for i := range kernel.Array {
+		kernel.Array[i] = float64(i + 1)
+	}
+	var input = newTestTensor([]int{2, 3})
+	copy(input.Array, []float64{0, 1, 7, 2, 2.5, 3})
+	var output = newTestTensor([]int{2, 3, 2})
+
+	var err = K2c_embedding(output, input, kernel, K2C_OOV_ERROR, 0, 0, K2C_COMBINER_NONE)
+	if err == nil || err.Error() != "keras2go: embedding indices[2] = 7 is not in [0, 4)" {
+		t.Fatal("expected an error for index 7, got", err)
+	}
+	input.Array[2] = 1
+	err = K2c_embedding(output, input, kernel, K2C_OOV_ERROR, 0, 0, K2C_COMBINER_NONE)
+	if err == nil || err.Error() != "keras2go: embedding indices[4] = 2.5 is not an integer" {
+		t.Fatal("expected an error for index 2.5, got", err)
+	}
+	input.Array[2] = 7
+	var tests = []struct {
+		oov_mode int
+		expected []float64
+	}{
+		{K2C_OOV_ZERO, []float64{1, 2, 3, 4, 0, 0, 5, 6, 0, 0, 7, 8}},
+		{K2C_OOV_BUCKET, []float64{1, 2, 3, 4, 7, 8, 5, 6, 7, 8, 7, 8}},
+	}
+	for _, test := range tests {
+		if err := K2c_embedding(output, input, kernel, test.oov_mode, 1, 0, K2C_COMBINER_NONE); err != nil {
+			t.Fatal(err)
+		}
+		checkArray(t, output.Array, test.expected)
+	}
+
+	// bucket mode needs at least one OOV row
+	if err := K2c_embedding(output, input, kernel, K2C_OOV_BUCKET, 0, 0, K2C_COMBINER_NONE); err == nil {
+		t.Fatal("expected an error for index 7 without OOV buckets")
+	}
+	var looked_up = newTestTensor([]int{2, 2})
+	if err := K2c_embedding_ids(looked_up, []int{-1, 2}, 1, kernel, K2C_OOV_BUCKET, 0, 0, K2C_COMBINER_NONE); err == nil {
+		t.Fatal("expected an error for index -1 without OOV buckets")
+	}
+	if err := K2c_embedding_ids(looked_up, []int{-1, 2}, 1, kernel, K2C_OOV_BUCKET, 1, 0, K2C_COMBINER_NONE); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, looked_up.Array, []float64{7, 8, 5, 6})
+	if err := K2c_embedding_ids(looked_up, []int{-1, 4}, 1, kernel, K2C_OOV_ZERO, 0, 0, K2C_COMBINER_NONE); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, looked_up.Array, []float64{0, 0, 0, 0})
+
+	// index 0 is padding, out of vocabulary indexes count as zero vectors
+	var combined = newTestTensor([]int{2, 2})
+	if err := K2c_embedding(combined, input, kernel, K2C_OOV_ZERO, 0, 1, K2C_COMBINER_MEAN); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, combined.Array, []float64{3.0 / 2, 4.0 / 2, 12.0 / 3, 14.0 / 3})
+	if err := K2c_embedding_ids(combined, []int{1, 1, 3, 0}, 2, kernel, K2C_OOV_ERROR, 0, 1, K2C_COMBINER_SUM); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, combined.Array, []float64{6, 8, 7, 8})
+
+	var mask = make([]bool, 3)
+	K2c_embedding_ids_mask(mask, []int{0, 2, 0})
+	if mask[0] || !mask[1] || mask[2] {
+		t.Fatal(mask)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/orestonce/keras2go
 
-go 1.14
+go 1.16
@@ -0,0 +1,212 @@
+package keras2go
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	k2c_farmhash_k0 uint64 = 0xc3a5c85c97cb3127
+	k2c_farmhash_k1 uint64 = 0xb492b66fbe98f273
+	k2c_farmhash_k2 uint64 = 0x9ae16a3b2f90404f
+)
+
+/**
+* Fingerprint of a string, the same as farmhash::Fingerprint64.
+* tensorflow uses it for tf.strings.to_hash_bucket_fast, and so for keras Hashing without a salt.
+*
+* :param s: string to hash.
+* :return: fingerprint of s.
+*/
+func k2c_fingerprint64(s string) uint64 {
+	var data = []byte(s)
+	var length = len(data)
+	if length <= 16 {
+		return k2c_farmhash_len0to16(data)
+	}
+	if length <= 32 {
+		return k2c_farmhash_len17to32(data)
+	}
+	if length <= 64 {
+		return k2c_farmhash_len33to64(data)
+	}
+
+	var seed uint64 = 81
+	var x = seed
+	var y = seed*k2c_farmhash_k1 + 113
+	var z = k2c_farmhash_shift_mix(y*k2c_farmhash_k2+113) * k2c_farmhash_k2
+	var v0, v1, w0, w1 uint64
+	x = x*k2c_farmhash_k2 + k2c_fetch64(data, 0)
+
+	var end = ((length - 1) / 64) * 64
+	var last64 = end + ((length - 1) & 63) - 63
+	for p := 0; p != end; p += 64 {
+		x = k2c_rotate64(x+y+v0+k2c_fetch64(data, p+8), 37) * k2c_farmhash_k1
+		y = k2c_rotate64(y+v1+k2c_fetch64(data, p+48), 42) * k2c_farmhash_k1
+		x ^= w1
+		y += v0 + k2c_fetch64(data, p+40)
+		z = k2c_rotate64(z+w0, 33) * k2c_farmhash_k1
+		v0, v1 = k2c_farmhash_weak_len32(data, p, v1*k2c_farmhash_k1, x+w0)
+		w0, w1 = k2c_farmhash_weak_len32(data, p+32, z+w1, y+k2c_fetch64(data, p+16))
+		z, x = x, z
+	}
+	var mul = k2c_farmhash_k1 + ((z & 0xff) << 1)
+	var p = last64
+	w0 += uint64((length - 1) & 63)
+	v0 += w0
+	w0 += v0
+	x = k2c_rotate64(x+y+v0+k2c_fetch64(data, p+8), 37) * mul
+	y = k2c_rotate64(y+v1+k2c_fetch64(data, p+48), 42) * mul
+	x ^= w1 * 9
+	y += v0*9 + k2c_fetch64(data, p+40)
+	z = k2c_rotate64(z+w0, 33) * mul
+	v0, v1 = k2c_farmhash_weak_len32(data, p, v1*mul, x+w0)
+	w0, w1 = k2c_farmhash_weak_len32(data, p+32, z+w1, y+k2c_fetch64(data, p+16))
+	z, x = x, z
+	return k2c_farmhash_len16(k2c_farmhash_len16(v0, w0, mul)+k2c_farmhash_shift_mix(y)*k2c_farmhash_k0+z,
+		k2c_farmhash_len16(v1, w1, mul)+x, mul)
+}
+
+func k2c_fetch64(data []byte, p int) uint64 {
+	return binary.LittleEndian.Uint64(data[p:])
+}
+
+func k2c_fetch32(data []byte, p int) uint64 {
+	return uint64(binary.LittleEndian.Uint32(data[p:]))
+}
+
+func k2c_rotate64(val uint64, shift int) uint64 {
+	return bits.RotateLeft64(val, -shift)
+}
+
+func k2c_farmhash_shift_mix(val uint64) uint64 {
+	return val ^ (val >> 47)
+}
+
+func k2c_farmhash_len16(u uint64, v uint64, mul uint64) uint64 {
+	var a = (u ^ v) * mul
+	a ^= a >> 47
+	var b = (v ^ a) * mul
+	b ^= b >> 47
+	return b * mul
+}
+
+func k2c_farmhash_len0to16(data []byte) uint64 {
+	var length = len(data)
+	if length >= 8 {
+		var mul = k2c_farmhash_k2 + uint64(length)*2
+		var a = k2c_fetch64(data, 0) + k2c_farmhash_k2
+		var b = k2c_fetch64(data, length-8)
+		var c = k2c_rotate64(b, 37)*mul + a
+		var d = (k2c_rotate64(a, 25) + b) * mul
+		return k2c_farmhash_len16(c, d, mul)
+	}
+	if length >= 4 {
+		var mul = k2c_farmhash_k2 + uint64(length)*2
+		var a = k2c_fetch32(data, 0)
+		return k2c_farmhash_len16(uint64(length)+(a<<3), k2c_fetch32(data, length-4), mul)
+	}
+	if length > 0 {
+		var a = uint32(data[0])
+		var b = uint32(data[length>>1])
+		var c = uint32(data[length-1])
+		var y = a + (b << 8)
+		var z = uint32(length) + (c << 2)
+		return k2c_farmhash_shift_mix(uint64(y)*k2c_farmhash_k2^uint64(z)*k2c_farmhash_k0) * k2c_farmhash_k2
+	}
+	return k2c_farmhash_k2
+}
+
+func k2c_farmhash_len17to32(data []byte) uint64 {
+	var length = len(data)
+	var mul = k2c_farmhash_k2 + uint64(length)*2
+	var a = k2c_fetch64(data, 0) * k2c_farmhash_k1
+	var b = k2c_fetch64(data, 8)
+	var c = k2c_fetch64(data, length-8) * mul
+	var d = k2c_fetch64(data, length-16) * k2c_farmhash_k2
+	return k2c_farmhash_len16(k2c_rotate64(a+b, 43)+k2c_rotate64(c, 30)+d, a+k2c_rotate64(b+k2c_farmhash_k2, 18)+c, mul)
+}
+
+func k2c_farmhash_len33to64(data []byte) uint64 {
+	var length = len(data)
+	var mul = k2c_farmhash_k2 + uint64(length)*2
+	var a = k2c_fetch64(data, 0) * k2c_farmhash_k2
+	var b = k2c_fetch64(data, 8)
+	var c = k2c_fetch64(data, length-8) * mul
+	var d = k2c_fetch64(data, length-16) * k2c_farmhash_k2
+	var y = k2c_rotate64(a+b, 43) + k2c_rotate64(c, 30) + d
+	var z = k2c_farmhash_len16(y, a+k2c_rotate64(b+k2c_farmhash_k2, 18)+c, mul)
+	var e = k2c_fetch64(data, 16) * mul
+	var f = k2c_fetch64(data, 24)
+	var g = (y + k2c_fetch64(data, length-32)) * mul
+	var h = (z + k2c_fetch64(data, length-24)) * mul
+	return k2c_farmhash_len16(k2c_rotate64(e+f, 43)+k2c_rotate64(g, 30)+h, e+k2c_rotate64(f+a, 18)+g, mul)
+}
+
+func k2c_farmhash_weak_len32(data []byte, p int, a uint64, b uint64) (uint64, uint64) {
+	var w = k2c_fetch64(data, p)
+	var x = k2c_fetch64(data, p+8)
+	var y = k2c_fetch64(data, p+16)
+	var z = k2c_fetch64(data, p+24)
+	a += w
+	b = k2c_rotate64(b+a+z, 21)
+	var c = a
+	a += x
+	a += y
+	b += k2c_rotate64(a, 44)
+	return a + z, b + c
+}
+
+/**
+* Keyed hash of a string, SipHash-2-4 like tensorflow's StrongKeyedHash.
+* tensorflow uses it for tf.strings.to_hash_bucket_strong, and so for keras Hashing with a salt.
+*
+* :param key: Array[2] key of the hash.
+* :param s: string to hash.
+* :return: hash of s.
+*/
+func k2c_siphash(key []uint64, s string) uint64 {
+	var data = []byte(s)
+	var v0 = key[0] ^ 0x736f6d6570736575
+	var v1 = key[1] ^ 0x646f72616e646f6d
+	var v2 = key[0] ^ 0x6c7967656e657261
+	var v3 = key[1] ^ 0x7465646279746573
+	var round = func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	var p = 0
+	for ; p+8 <= len(data); p += 8 {
+		var m = k2c_fetch64(data, p)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+	var last = uint64(len(data)) << 56
+	for i := 0; p+i < len(data); i++ {
+		last |= uint64(data[p+i]) << (8 * uint(i))
+	}
+	v3 ^= last
+	round()
+	round()
+	v0 ^= last
+	v2 ^= 0xff
+	for i := 0; i < 4; i++ {
+		round()
+	}
+	return v0 ^ v1 ^ v2 ^ v3
+}
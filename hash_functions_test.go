@@ -0,0 +1,74 @@
+package keras2go
+
+import "testing"
+
+// expected bins are the examples of the keras Hashing documentation
+func TestK2c_hash_strings(t *testing.T) {
+	var output = newTestTensor([]int{5})
+
+	K2c_hash_strings(output, []string{"A", "B", "C", "D", "E"}, 3, "", 0, nil)
+	checkBins(t, output, []float64{1, 0, 1, 1, 2})
+
+	K2c_hash_strings(output, []string{"A", "B", "", "C", "D"}, 3, "", 1, nil)
+	checkBins(t, output, []float64{1, 1, 0, 2, 2})
+
+	K2c_hash_strings(output, []string{"A", "B", "C", "D", "E"}, 3, "", 0, []uint64{133, 133})
+	checkBins(t, output, []float64{0, 0, 2, 1, 0})
+}
+
+func TestK2c_fingerprint64(t *testing.T) {
+	// reference values of farmhash Fingerprint64 (github.com/dgryski/go-farm), which tensorflow hashes with,
+	// covering each length branch
+	tests := []struct {
+		length   int
+		expected uint64
+	}{
+		{0, 0x9ae16a3b2f90404f},
+		{1, 0xbec09cc447469d8b},
+		{4, 0xf3c7ee05197a7b92},
+		{8, 0x0c97ecbc1e9d2626},
+		{16, 0x7eda0ed8c837a168},
+		{17, 0xc9518fe63ebf6127},
+		{24, 0x310064a8de2c42d2},
+		{32, 0xa53f806f23a314e8},
+		{33, 0xc24f9d7ce864f0e9},
+		{48, 0xa77873935894901f},
+		{64, 0x0566266cb579eca2},
+		{65, 0x77e0867714e6b0e4},
+		{100, 0x9e17c36fe4a61b08},
+		{128, 0x666823960b3ac271},
+		{129, 0x98206b8579bca083},
+		{200, 0x6d7105b27cbe2fd7},
+		{300, 0xdfbced116079af13},
+	}
+	for _, tt := range tests {
+		var msg = make([]byte, tt.length)
+		for i := range msg {
+			msg[i] = byte(i*7 + 3)
+		}
+		if got := k2c_fingerprint64(string(msg)); got != tt.expected {
+			t.Fatalf("length %d: got %016x, expected %016x", tt.length, got, tt.expected)
+		}
+	}
+}
+
+func TestK2c_siphash(t *testing.T) {
+	// reference vector of the SipHash paper
+	var key = []uint64{0x0706050403020100, 0x0f0e0d0c0b0a0908}
+	var msg []byte
+	for i := 0; i < 15; i++ {
+		msg = append(msg, byte(i))
+	}
+	if got := k2c_siphash(key, string(msg)); got != 0xa129ca6149be45e5 {
+		t.Fatalf("got %x", got)
+	}
+}
+
+func checkBins(t *testing.T, output *K2c_tensor, expected []float64) {
+	t.Helper()
+	for i := range expected {
+		if output.Array[i] != expected[i] {
+			t.Fatalf("got %v, expected %v", output.Array, expected)
+		}
+	}
+}
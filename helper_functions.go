@@ -307,3 +307,289 @@ func k2c_flip(A *K2c_tensor, axis int) {
 		}
 	}
 }
+
+/**
+* Views a tensor with leading dimensions of size 1 added, eg to run a 1D kernel as a 3D one.
+*
+* :param tensor: tensor to view. Shares its Array with the view.
+* :param ndim: number of dimensions of the view.
+* :return: view of the tensor.
+*/
+func k2c_expand_dims_front(tensor *K2c_tensor, ndim int) K2c_tensor {
+	var view = K2c_tensor{Array: tensor.Array, Ndim: ndim, Numel: tensor.Numel}
+	var lead = ndim - tensor.Ndim
+	for i := 0; i < K2C_MAX_NDIM; i++ {
+		if i < lead {
+			view.Shape[i] = 1
+		} else {
+			view.Shape[i] = tensor.Shape[i-lead]
+		}
+	}
+	return view
+}
+
+/**
+* Computes the leading padding of a windowed operation (convolution or pooling).
+* For 'same' padding the total padding is split as Keras/TensorFlow does, with the extra element after. 'causal'
+* padding puts it all before.
+*
+* :param input_length: length of the input dimension.
+* :param output_length: length of the output dimension.
+* :param filter_size: size of the window along the dimension.
+* :param stride: stride length of the window.
+* :param dilation: dilation rate of the window.
+* :param padding: padding mode, K2C_PADDING_VALID, K2C_PADDING_SAME or K2C_PADDING_CAUSAL.
+* :return: number of padded elements before the first input element.
+*/
+func k2c_pad_before(input_length int, output_length int, filter_size int, stride int, dilation int, padding int) int {
+	if padding == K2C_PADDING_CAUSAL {
+		return (filter_size - 1) * dilation
+	}
+	if padding != K2C_PADDING_SAME {
+		return 0
+	}
+	var pad_total = (output_length-1)*stride + (filter_size-1)*dilation + 1 - input_length
+	if pad_total < 0 {
+		return 0
+	}
+	return pad_total / 2
+}
+
+/**
+* Layout of a reduction over several axes of a tensor.
+* Elements sharing the same subscripts on the kept axes form a group, and the
+* subscripts on the reduced axes index the members of each group.
+*/
+type k2c_reduction struct {
+	ngroups     int               /** number of groups, ie product of the kept dimensions. */
+	nmembers    int               /** number of members per group, ie product of the reduced dimensions. */
+	nfree       int               /** number of kept axes. */
+	naxes       int               /** number of reduced axes. */
+	free_shape  [K2C_MAX_NDIM]int /** Shape of the kept axes. */
+	free_stride [K2C_MAX_NDIM]int /** stride of the kept axes in the tensor Array. */
+	axes_shape  [K2C_MAX_NDIM]int /** Shape of the reduced axes, in the order they were given. */
+	axes_stride [K2C_MAX_NDIM]int /** stride of the reduced axes in the tensor Array. */
+}
+
+/**
+* Builds the layout of a reduction.
+*
+* :param shape: Array[ndim] Shape of the tensor being reduced.
+* :param ndim: number of dimensions of the tensor.
+* :param axes: axes being reduced over.
+* :return: the reduction layout.
+*/
+func k2c_make_reduction(shape []int, ndim int, axes []int) k2c_reduction {
+	var r = k2c_reduction{ngroups: 1, nmembers: 1}
+	var stride [K2C_MAX_NDIM]int
+	var reduced [K2C_MAX_NDIM]bool
+	stride[ndim-1] = 1
+	for i := ndim - 2; i >= 0; i-- {
+		stride[i] = stride[i+1] * shape[i+1]
+	}
+	for _, axis := range axes {
+		reduced[axis] = true
+		r.axes_shape[r.naxes] = shape[axis]
+		r.axes_stride[r.naxes] = stride[axis]
+		r.naxes++
+		r.nmembers *= shape[axis]
+	}
+	for i := 0; i < ndim; i++ {
+		if !reduced[i] {
+			r.free_shape[r.nfree] = shape[i]
+			r.free_stride[r.nfree] = stride[i]
+			r.nfree++
+			r.ngroups *= shape[i]
+		}
+	}
+	return r
+}
+
+/**
+* Offset in the tensor Array of the first element of group g.
+*/
+func (r *k2c_reduction) group_offset(g int) int {
+	var offset = 0
+	for i := r.nfree - 1; i >= 0; i-- {
+		offset += (g % r.free_shape[i]) * r.free_stride[i]
+		g /= r.free_shape[i]
+	}
+	return offset
+}
+
+/**
+* Offset of member m from the first element of its group.
+*/
+func (r *k2c_reduction) member_offset(m int) int {
+	var offset = 0
+	for i := r.naxes - 1; i >= 0; i-- {
+		offset += (m % r.axes_shape[i]) * r.axes_stride[i]
+		m /= r.axes_shape[i]
+	}
+	return offset
+}
+
+/**
+* Interpolation along one axis of a resize.
+* each output element is a weighted sum of taps input elements.
+*/
+type k2c_interpolation struct {
+	taps   int       /** number of input elements per output element. */
+	index  []int     /** Array[out_length*taps] of input indices. */
+	weight []float64 /** Array[out_length*taps] of weights, matching index. */
+}
+
+/**
+* Computes the interpolation along one axis, with the conventions of tf.image.resize: half pixel centers for
+* nearest and bilinear, and the covered fraction of each input element for area.
+*
+* :param in_length: length of the input dimension.
+* :param out_length: length of the output dimension.
+* :param interpolation: interpolation mode, one of K2C_INTERPOLATION_*.
+* :return: interpolation along the axis.
+*/
+func k2c_make_interpolation(in_length int, out_length int, interpolation int) k2c_interpolation {
+	var scale = float64(in_length) / float64(out_length)
+	var r k2c_interpolation
+	switch interpolation {
+	case K2C_INTERPOLATION_BILINEAR:
+		r.taps = 2
+	case K2C_INTERPOLATION_AREA:
+		r.taps = int(math.Ceil(scale)) + 1
+	case K2C_INTERPOLATION_BICUBIC:
+		r.taps = 4
+	default:
+		r.taps = 1
+	}
+	r.index = make([]int, out_length*r.taps)
+	r.weight = make([]float64, out_length*r.taps)
+
+	for j := 0; j < out_length; j++ {
+		var index = r.index[j*r.taps : (j+1)*r.taps]
+		var weight = r.weight[j*r.taps : (j+1)*r.taps]
+		switch interpolation {
+		case K2C_INTERPOLATION_BILINEAR:
+			var in = (float64(j)+0.5)*scale - 0.5
+			var in_floor = math.Floor(in)
+			index[0] = k2c_clamp_index(int(in_floor), in_length)
+			index[1] = k2c_clamp_index(int(math.Ceil(in)), in_length)
+			weight[0] = 1 - (in - in_floor)
+			weight[1] = in - in_floor
+		case K2C_INTERPOLATION_AREA:
+			var start = float64(j) * scale
+			var end = float64(j+1) * scale
+			for t := range index {
+				var i = int(math.Floor(start)) + t
+				index[t] = k2c_clamp_index(i, in_length)
+				var overlap = math.Min(float64(i+1), end) - math.Max(float64(i), start)
+				if overlap > 0 {
+					weight[t] = overlap / scale
+				}
+			}
+		case K2C_INTERPOLATION_BICUBIC:
+			var in = (float64(j)+0.5)*scale - 0.5
+			var in_floor = math.Floor(in)
+			// tensorflow reads the weights from a table of 1024 steps
+			var x = math.RoundToEven((in-in_floor)*1024) / 1024
+			var distance = [4]float64{1 + x, x, 1 - x, 2 - x}
+			var sum = 0.0
+			for t := range index {
+				var i = int(in_floor) - 1 + t
+				index[t] = k2c_clamp_index(i, in_length)
+				// taps outside the input get no weight, and the rest are renormalized
+				if index[t] == i {
+					weight[t] = k2c_keys_cubic(distance[t])
+					sum += weight[t]
+				}
+			}
+			for t := range weight {
+				weight[t] /= sum
+			}
+		default:
+			index[0] = k2c_clamp_index(int(math.Floor((float64(j)+0.5)*scale)), in_length)
+			weight[0] = 1
+		}
+	}
+	return r
+}
+
+/**
+* Keys cubic convolution kernel with a = -0.5, as used by tf.image.resize.
+*
+* :param x: distance from the sampled point, in [0, 2].
+* :return: weight of the input element at that distance.
+*/
+func k2c_keys_cubic(x float64) float64 {
+	const a = -0.5
+	if x <= 1 {
+		return ((a+2)*x-(a+3))*x*x + 1
+	}
+	return ((a*x-5*a)*x+8*a)*x - 4*a
+}
+
+/**
+* Clamps an index to [0, length).
+*/
+func k2c_clamp_index(i int, length int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= length {
+		return length - 1
+	}
+	return i
+}
+
+/**
+* Resizes the spatial dimensions of a tensor, or of a window of it.
+* Assumes a "channels last" structure.
+*
+* :param output: output tensor, with the same number of channels as the input.
+* :param input: input tensor.
+* :param window_start: Array[spatial dims] of the first element of the window to resize, or nil to resize all the input.
+* :param window_shape: Array[spatial dims] of the size of the window, or nil to resize all the input.
+* :param interpolation: interpolation mode, one of K2C_INTERPOLATION_*.
+*/
+func k2c_resize(output *K2c_tensor, input *K2c_tensor, window_start []int, window_shape []int, interpolation int) {
+	var nspatial = input.Ndim - 1
+	var channels = input.Shape[nspatial]
+	var axes [K2C_MAX_NDIM]k2c_interpolation
+	var ncombinations = 1
+	for i := 0; i < nspatial; i++ {
+		if window_shape == nil {
+			axes[i] = k2c_make_interpolation(input.Shape[i], output.Shape[i], interpolation)
+		} else {
+			axes[i] = k2c_make_interpolation(window_shape[i], output.Shape[i], interpolation)
+			for t := range axes[i].index {
+				axes[i].index[t] += window_start[i]
+			}
+		}
+		ncombinations *= axes[i].taps
+	}
+	var outsub [K2C_MAX_NDIM]int
+	var insub [K2C_MAX_NDIM]int
+
+	output.fillFloat64(0)
+	for p := 0; p < output.Numel/channels; p++ {
+		k2c_idx2sub(p*channels, outsub[:], output.Shape[:], output.Ndim)
+		var out = output.Array[p*channels : (p+1)*channels]
+		// every combination of one tap per axis
+		for c := 0; c < ncombinations; c++ {
+			var w = 1.0
+			var rest = c
+			for i := nspatial - 1; i >= 0; i-- {
+				var t = outsub[i]*axes[i].taps + rest%axes[i].taps
+				rest /= axes[i].taps
+				insub[i] = axes[i].index[t]
+				w *= axes[i].weight[t]
+			}
+			if w == 0 {
+				continue
+			}
+			var inIdx = k2c_sub2idx(insub[:], input.Shape[:], input.Ndim)
+			for k := range out {
+				out[k] += w * input.Array[inIdx+k]
+			}
+		}
+	}
+}
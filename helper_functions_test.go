@@ -0,0 +1,161 @@
+package keras2go
+
+import (
+	"math"
+	"testing"
+)
+
+// The expected values follow the tensorflow kernels behind tf.image.resize: resize_nearest_neighbor,
+// resize_bilinear and resize_bicubic with half_pixel_centers, and resize_area, evaluated in float64.
+
+func TestK2c_resize(t *testing.T) {
+	input := newTestTensor([]int{4, 5, 2})
+	input.Array = []float64{
+		0.89, 2.73, -1.59, -2.32, 2.19, 1.75, -2.64, -1.07, 2.92, 0.32,
+		-3, 0.45, 2.88, -1.19, -2.58, 1.86, 2.1, -2.4, -1.48, 2.78,
+		0.76, -2.98, 0, 2.97, -0.77, -2.78, 1.48, 2.39, -2.1, -1.85,
+		2.58, 1.19, -2.88, -0.44, 3, -0.33, -2.91, 1.08, 2.64, -1.76,
+	}
+	tests := []struct {
+		name          string
+		interpolation int
+		shape         []int
+		expected      []float64
+	}{
+		{
+			name: "nearest downscale", interpolation: K2C_INTERPOLATION_NEAREST, shape: []int{3, 2, 2},
+			expected: []float64{
+				-1.59, -2.32, -2.64, -1.07, 0, 2.97, 1.48, 2.39,
+				-2.88, -0.44, -2.91, 1.08,
+			},
+		},
+		{
+			name: "nearest upscale", interpolation: K2C_INTERPOLATION_NEAREST, shape: []int{7, 8, 2},
+			expected: []float64{
+				0.89, 2.73, 0.89, 2.73, -1.59, -2.32, 2.19, 1.75,
+				2.19, 1.75, -2.64, -1.07, 2.92, 0.32, 2.92, 0.32,
+				0.89, 2.73, 0.89, 2.73, -1.59, -2.32, 2.19, 1.75,
+				2.19, 1.75, -2.64, -1.07, 2.92, 0.32, 2.92, 0.32,
+				-3, 0.45, -3, 0.45, 2.88, -1.19, -2.58, 1.86,
+				-2.58, 1.86, 2.1, -2.4, -1.48, 2.78, -1.48, 2.78,
+				0.76, -2.98, 0.76, -2.98, 0, 2.97, -0.77, -2.78,
+				-0.77, -2.78, 1.48, 2.39, -2.1, -1.85, -2.1, -1.85,
+				0.76, -2.98, 0.76, -2.98, 0, 2.97, -0.77, -2.78,
+				-0.77, -2.78, 1.48, 2.39, -2.1, -1.85, -2.1, -1.85,
+				2.58, 1.19, 2.58, 1.19, -2.88, -0.44, 3, -0.33,
+				3, -0.33, -2.91, 1.08, 2.64, -1.76, 2.64, -1.76,
+				2.58, 1.19, 2.58, 1.19, -2.88, -0.44, 3, -0.33,
+				3, -0.33, -2.91, 1.08, 2.64, -1.76, 2.64, -1.76,
+			},
+		},
+		{
+			name: "bilinear downscale", interpolation: K2C_INTERPOLATION_BILINEAR, shape: []int{3, 2, 2},
+			expected: []float64{
+				-0.5733333333, -1.01125, -0.8408333333, -0.78625, 0.8, 0.35125, 0.895, 0.1125,
+				-1.230833333, 0.22, -1.17125, 0.53,
+			},
+		},
+		{
+			name: "bilinear upscale", interpolation: K2C_INTERPOLATION_BILINEAR, shape: []int{7, 8, 2},
+			expected: []float64{
+				0.89, 2.73, -0.195, 0.520625, -1.35375, -2.065625, 1.00875, 0.478125,
+				0.680625, 0.86875, -2.338125, -0.89375, 0.4875, -0.288125, 2.92, 0.32,
+				-0.4992857143, 1.915714286, -0.2780357143, 0.2391517857, 0.03642857143, -1.684821429, 0.3364285714, 0.63125,
+				0.0384375, 0.7473214286, -0.8575446429, -1.336607143, 0.3441964286, -0.001741071429, 1.348571429, 1.198571429,
+				-2.722142857, 0.6128571429, -0.4108928571, -0.2112053571, 2.260714286, -1.075535714, -0.7392857143, 0.87625,
+				-0.9890625, 0.5530357143, 1.511383929, -2.045178571, 0.1149107143, 0.4564732143, -1.165714286, 2.604285714,
+				-1.12, -1.265, 0, -0.3221875, 1.2453125, 0.805625, -0.7015625, -0.038125,
+				-0.5921875, -0.3178125, 1.5734375, -0.0334375, -0.22375, 0.259375, -1.79, 0.465,
+				0.89, -2.682142857, 0.410625, -0.3158928571, -0.2241517857, 2.393214286, -0.4085267857, -0.9389285714,
+				0.02026785714, -1.073303571, 1.062232143, 1.990089286, -0.4804910714, -0.03232142857, -1.761428571, -1.843571429,
+				1.93, -0.2992857143, 0.275625, 0.1719642857, -1.632366071, 0.6539285714, 0.5582589286, -0.5853571429,
+				0.7174107143, -0.3447321429, -1.154910714, 1.375803571, -0.05441964286, -0.3308928571, 0.9471428571, -1.792142857,
+				2.58, 1.19, 0.19125, 0.476875, -2.5125, -0.433125, 1.1625, -0.364375,
+				1.153125, 0.110625, -2.540625, 0.991875, 0.211875, -0.5175, 2.64, -1.76,
+			},
+		},
+		{
+			name: "bicubic downscale", interpolation: K2C_INTERPOLATION_BICUBIC, shape: []int{3, 2, 2},
+			expected: []float64{
+				-0.9105021661, -1.464762915, -1.347969079, -1.060123797, 1.434198473, 0.6669227099, 1.629250954, 0.1720849237,
+				-1.832355724, 0.1906157447, -1.761908137, 0.7285075486,
+			},
+		},
+		{
+			name: "bicubic upscale", interpolation: K2C_INTERPOLATION_BICUBIC, shape: []int{7, 8, 2},
+			expected: []float64{
+				1.424254613, 3.293427037, -0.2695581654, 0.6012738048, -1.853843917, -2.397661271, 1.646492276, 0.6326150346,
+				1.170249831, 1.274651364, -2.973988938, -0.8872185768, 0.5065405738, -0.4413455231, 3.720724653, 0.203542816,
+				-0.4516188872, 2.481780595, -0.3514854983, 0.2642031286, -0.08690943255, -2.098000074, 0.6065000934, 0.9674743778,
+				0.1374873881, 1.181749968, -1.189793749, -1.593976918, 0.4155684901, -0.08459415552, 1.814513766, 1.449179609,
+				-3.350244615, 0.8040029244, -0.4257757387, -0.3114690166, 2.724072827, -1.291990781, -1.136099592, 1.344205416,
+				-1.519470215, 0.847414723, 1.823012969, -2.46088698, 0.2058849183, 0.4936177704, -1.490186952, 3.19212184,
+				-1.719815498, -1.872614161, 0.06288474712, -0.4565501912, 1.832292709, 1.185085678, -1.314570847, -0.07061576843,
+				-1.109774628, -0.5951694489, 2.314341888, -0.04864006042, -0.2670225625, 0.3988084399, -2.701015913, 0.6576925738,
+				1.042125492, -3.317146748, 0.5096790651, -0.3013319268, -0.2660084338, 2.882159691, -0.6284596128, -1.43956907,
+				0.02992493435, -1.646934111, 1.282529218, 2.394876649, -0.563396778, 0.04140152326, -2.132897715, -2.309275893,
+				2.482304651, -0.2704717948, 0.2748521678, 0.2745001275, -2.081455153, 0.6972340185, 0.9441995311, -0.8869567149,
+				1.172182161, -0.4826641358, -1.545180926, 1.647871221, -0.09616001668, -0.387322242, 1.383809995, -2.224913867,
+				3.143842431, 1.680826788, 0.09904010512, 0.6108498563, -3.0201397, -0.7522574471, 1.843030449, -0.4492388088,
+				1.77645099, 0.3008458038, -3.176625239, 1.01123527, 0.208743108, -0.6194450107, 3.466881673, -1.949244664,
+			},
+		},
+		{
+			name: "area downscale", interpolation: K2C_INTERPOLATION_AREA, shape: []int{3, 2, 2},
+			expected: []float64{
+				-0.0225, 0.4045, 0.3455, 0.1685, -0.207, -0.242, -0.335, 0.092,
+				0.3975, 0.0355, 0.2685, -0.3385,
+			},
+		},
+		{
+			name: "area upscale", interpolation: K2C_INTERPOLATION_AREA, shape: []int{7, 8, 2},
+			expected: []float64{
+				0.89, 2.73, -0.102, 0.71, -1.59, -2.32, 1.434, 0.936,
+				1.224, 1.186, -2.64, -1.07, 0.696, -0.236, 2.92, 0.32,
+				-0.0825, 2.16, -0.2385, 0.481, -0.4725, -2.0375, 0.7035, 1.0145,
+				0.507, 1.1415, -1.455, -1.4025, 0.51, 0, 1.82, 0.935,
+				-3, 0.45, -0.648, -0.206, 2.88, -1.19, -1.488, 1.25,
+				-1.644, 1.008, 2.1, -2.4, -0.048, 0.708, -1.48, 2.78,
+				-1.12, -1.265, -0.096, -0.403, 1.44, 0.89, -1.052, -0.19,
+				-0.982, -0.369, 1.79, -0.005, -0.358, 0.277, -1.79, 0.465,
+				0.76, -2.98, 0.456, -0.6, 0, 2.97, -0.616, -1.63,
+				-0.32, -1.746, 1.48, 2.39, -0.668, -0.154, -2.1, -1.85,
+				2.125, 0.1475, 0.411, 0.2535, -2.16, 0.4125, 1.214, -0.6715,
+				1.2835, -0.4725, -1.8125, 1.4075, 0.148, -0.5065, 1.455, -1.7825,
+				2.58, 1.19, 0.396, 0.538, -2.88, -0.44, 1.824, -0.352,
+				1.818, -0.048, -2.91, 1.08, 0.42, -0.624, 2.64, -1.76,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := newTestTensor(tt.shape)
+			k2c_resize(output, input, nil, nil, tt.interpolation)
+			for i := range tt.expected {
+				if math.Abs(output.Array[i]-tt.expected[i]) > 1e-8 {
+					t.Fatalf("index %d: got %v, expected %v", i, output.Array, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestK2c_resize_window(t *testing.T) {
+	// resizing a window is resizing the cropped input
+	input := newTestTensor([]int{4, 5, 1})
+	for i := range input.Array {
+		input.Array[i] = math.Sin(float64(i))
+	}
+	cropped := newTestTensor([]int{2, 3, 1})
+	for i := 0; i < 2; i++ {
+		copy(cropped.Array[i*3:(i+1)*3], input.Array[(i+1)*5+2:(i+1)*5+5])
+	}
+	for _, interpolation := range []int{K2C_INTERPOLATION_NEAREST, K2C_INTERPOLATION_BILINEAR,
+		K2C_INTERPOLATION_BICUBIC, K2C_INTERPOLATION_AREA} {
+		output := newTestTensor([]int{3, 2, 1})
+		expected := newTestTensor([]int{3, 2, 1})
+		k2c_resize(output, input, []int{1, 2}, []int{2, 3}, interpolation)
+		k2c_resize(expected, cropped, nil, nil, interpolation)
+		checkArray(t, output.Array, expected.Array)
+	}
+}
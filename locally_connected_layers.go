@@ -0,0 +1,69 @@
+package keras2go
+
+/**
+* 1D (temporal) Locally Connected layer.
+* Like a 1D convolution, but with a different set of weights at each output position.
+* Assumes a "channels last" structure and 'valid' padding.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param kernel: kernel tensor, Shape is {output length, kernel_size*in_channels, filters}.
+* :param bias: bias tensor, Shape is {output length, filters}.
+* :param kernel_size: length of the local window.
+* :param stride: stride length of the window.
+* :param activation: activation function to apply to output.
+ */
+func K2c_locally_connected1d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, kernel_size int, stride int, activation k2c_activationType) {
+	var out_times = output.Shape[0]
+	var filters = output.Shape[1]
+	var in_channels = input.Shape[1]
+	var feature_dim = kernel_size * in_channels
+
+	for x0 := 0; x0 < out_times; x0++ {
+		// the window is contiguous in memory, so it can be used as is
+		k2c_affine_matmul(output.Array[x0*filters:(x0+1)*filters],
+			input.Array[x0*stride*in_channels:],
+			kernel.Array[x0*feature_dim*filters:],
+			bias.Array[x0*filters:], 1, filters, feature_dim)
+	}
+	activation(output.Array[:output.Numel])
+}
+
+/**
+* 2D (spatial) Locally Connected layer.
+* Like a 2D convolution, but with a different set of weights at each output position.
+* Assumes a "channels last" structure and 'valid' padding.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param kernel: kernel tensor, Shape is {output rows*output cols, kernel rows*kernel cols*in_channels, filters}.
+* :param bias: bias tensor, Shape is {output rows, output cols, filters}.
+* :param kernel_size: Array[2] size of the local window. Order is {rows, cols}.
+* :param stride: Array[2] stride length of the window. Order is {stride dim 1, stride dim 2}.
+* :param fwork: Array[kernel rows*kernel cols*in_channels] working storage.
+* :param activation: activation function to apply to output.
+ */
+func K2c_locally_connected2d(output *K2c_tensor, input *K2c_tensor, kernel *K2c_tensor, bias *K2c_tensor, kernel_size []int, stride []int, fwork []float64, activation k2c_activationType) {
+	var out_rows = output.Shape[0]
+	var out_cols = output.Shape[1]
+	var filters = output.Shape[2]
+	var in_cols = input.Shape[1]
+	var in_channels = input.Shape[2]
+	var row_width = kernel_size[1] * in_channels
+	var feature_dim = kernel_size[0] * row_width
+
+	for x0 := 0; x0 < out_rows; x0++ {
+		for x1 := 0; x1 < out_cols; x1++ {
+			// gather the window, ordered {kernel row, kernel col, channel}
+			for z0 := 0; z0 < kernel_size[0]; z0++ {
+				inIdx := ((x0*stride[0]+z0)*in_cols + x1*stride[1]) * in_channels
+				copy(fwork[z0*row_width:(z0+1)*row_width], input.Array[inIdx:inIdx+row_width])
+			}
+			pos := x0*out_cols + x1
+			k2c_affine_matmul(output.Array[pos*filters:(pos+1)*filters], fwork,
+				kernel.Array[pos*feature_dim*filters:],
+				bias.Array[pos*filters:], 1, filters, feature_dim)
+		}
+	}
+	activation(output.Array[:output.Numel])
+}
@@ -0,0 +1,85 @@
+package keras2go
+
+import "testing"
+
+// The expected outputs below are inputs_flat*kernel + bias, with the kernel laid out
+// the way Keras stores it for implementation 2 (dense, masked) or implementation 3
+// (sparse). The kernels passed to the layers are the same weights converted to the
+// implementation 1 layout, as the converter writes them.
+
+func TestK2c_locally_connected1d(t *testing.T) {
+	// implementation 2: input length 5 with 2 channels, kernel_size 2, stride 2, 3 filters
+	input := K2c_tensor{Array: []float64{0.1, -0.31, 0.69, -0.42, 0.02, -0.31, -0.17, 0.95, -0.79, -0.11},
+		Ndim: 2, Numel: 10, Shape: [K2C_MAX_NDIM]int{5, 2, 1, 1, 1}}
+	kernel := K2c_tensor{Array: []float64{
+		0.46, -0.18, 0.35, -0.11, 0.59, -0.4, -0.66, 0.13, 0.02, -0.13, -0.61, -0.56,
+		-0.18, 0.51, 0.24, 0.57, 0.17, 0.55, 0.03, 0.29, -0.8, 0.5, -0.21, 0.88,
+	}, Ndim: 3, Numel: 24, Shape: [K2C_MAX_NDIM]int{2, 4, 3, 1, 1}}
+	bias := K2c_tensor{Array: []float64{-0.55, -0.3, 1.0, -0.34, 0.22, -0.16}, Ndim: 2, Numel: 6, Shape: [K2C_MAX_NDIM]int{2, 3, 1, 1, 1}}
+	output := K2c_tensor{Array: make([]float64, 6), Ndim: 2, Numel: 6, Shape: [K2C_MAX_NDIM]int{2, 3, 1, 1, 1}}
+
+	K2c_locally_connected1d(&output, &input, &kernel, &bias, 2, 2, K2c_linear)
+	checkArrayClose(t, output.Array, []float64{-0.8707, -0.155, 1.408, -0.0504, -0.0713, 0.6463})
+}
+
+func TestK2c_locally_connected2d(t *testing.T) {
+	tests := []struct {
+		name        string
+		inShape     []int
+		input       []float64
+		kernelSize  []int
+		stride      []int
+		kernelShape []int
+		kernel      []float64
+		bias        []float64
+		expected    []float64
+	}{
+		{
+			name:        "implementation 1",
+			inShape:     []int{3, 3, 1},
+			input:       []float64{-0.38, -0.79, -0.4, -0.36, -0.77, 0.4, 0.2, 0.44, -0.79},
+			kernelSize:  []int{2, 2},
+			stride:      []int{1, 1},
+			kernelShape: []int{4, 4, 2},
+			kernel: []float64{
+				-0.5, 0.32, -0.33, 0.47, -0.22, 0.52, -0.4, 0.96, -0.85, 0.52, -0.49, -0.56, 0.97, -0.74, 0.6, -0.45,
+				0.79, 0.47, 0.5, -0.43, -0.27, -0.79, 0.32, 0.62, 0.42, 0.31, 0.61, -0.27, 0.49, 0.81, -0.27, -0.75,
+			},
+			bias:     []float64{-0.5, 0.85, -0.98, -0.26, 0.64, -0.54, -0.56, 0.27},
+			expected: []float64{0.3379, -0.5693, -0.6194, -0.057, 0.0574, -0.2633, -0.2105, 0.8722},
+		},
+		{
+			name:    "implementation 3",
+			inShape: []int{4, 3, 2},
+			input: []float64{
+				-0.27, -0.72, -0.7, -0.32, -0.86, -0.2, -0.17, 0.31, -0.07, 0.87, 0.32, -0.36,
+				0.75, 0.4, 0.93, 0.47, 0.93, -0.59, -0.44, 0.94, -0.96, 0.85, 0.82, -0.73,
+			},
+			kernelSize:  []int{2, 2},
+			stride:      []int{2, 1},
+			kernelShape: []int{4, 8, 2},
+			kernel: []float64{
+				0.26, -0.94, 0.76, -0.61, 0.9, 0, 0.03, -0.75, -0.98, -0.77, -0.65, -0.67, -0.1, -0.93, -0.54, 0.57,
+				0.73, 0.24, -0.6, 0.59, 0.27, 0.15, 0.75, -0.37, -0.51, 0.37, -0.33, 0.57, 0.76, -0.89, 0.52, -0.36,
+				-0.41, 0.32, 0.77, -0.22, -0.42, -0.7, 0.35, -0.31, -0.13, -0.06, -0.24, 0.29, 0, 1.0, 0.79, -0.35,
+				0.88, 0.3, 0.82, -0.67, -0.11, -0.94, -0.56, 0.28, -0.53, -0.89, 0.84, 0.1, -0.65, 0.94, 0.3, -0.07,
+			},
+			bias:     []float64{-0.7, 0.83, 0.92, 0.74, 0.45, 0.52, -0.38, -0.99},
+			expected: []float64{-2.4547, 2.2472, 0.0234, 0.643, 0.7275, -1.0832, 1.5227, -0.304},
+		},
+	}
+	for _, tt := range tests {
+		input := K2c_tensor{Array: tt.input, Ndim: 3, Numel: len(tt.input)}
+		copy(input.Shape[:], tt.inShape)
+		kernel := K2c_tensor{Array: tt.kernel, Ndim: 3, Numel: len(tt.kernel)}
+		copy(kernel.Shape[:], tt.kernelShape)
+		bias := K2c_tensor{Array: tt.bias, Ndim: 3, Numel: len(tt.bias), Shape: [K2C_MAX_NDIM]int{2, 2, 2, 1, 1}}
+		output := K2c_tensor{Array: make([]float64, 8), Ndim: 3, Numel: 8, Shape: [K2C_MAX_NDIM]int{2, 2, 2, 1, 1}}
+		fwork := make([]float64, tt.kernelShape[1])
+
+		K2c_locally_connected2d(&output, &input, &kernel, &bias, tt.kernelSize, tt.stride, fwork, K2c_linear)
+		t.Run(tt.name, func(t *testing.T) {
+			checkArrayClose(t, output.Array, tt.expected)
+		})
+	}
+}
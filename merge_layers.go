@@ -1,131 +1,263 @@
 package keras2go
 
 /**
-* Element-wise sum of several tensors.
+* Strides for reading a tensor broadcast to the shape of another, numpy style.
+* dimensions are aligned from the last one. Missing dimensions and dimensions of size 1 get a stride of 0, so the
+* same elements are read again along them.
 *
-* :param output: output tensor.
-* :param num_tensors: number of tensors being summed.
-* :param ...: variadic. Tensors to be summed.
+* :param input: tensor being broadcast.
+* :param output: tensor with the broadcast shape.
+* :return: stride of input along each dimension of output.
 */
-func k2c_add(output *K2c_tensor, inputList ...*K2c_tensor) {
-	output.fillFloat64(0)
-	for _, input := range inputList {
+func k2c_broadcast_strides(input *K2c_tensor, output *K2c_tensor) [K2C_MAX_NDIM]int {
+	var strides [K2C_MAX_NDIM]int
+	var stride = 1
+	for i := 1; i <= output.Ndim; i++ {
+		var d = input.Ndim - i
+		if d < 0 {
+			break
+		}
+		if input.Shape[d] != 1 {
+			strides[output.Ndim-i] = stride
+		}
+		stride *= input.Shape[d]
+	}
+	return strides
+}
+
+/**
+* Combines a tensor into output element by element, broadcasting it to the shape of output.
+*   output = op(output, input)
+*
+* :param output: output tensor, also the left operand.
+* :param input: right operand.
+* :param op: binary operation.
+*/
+func k2c_broadcast_op(output *K2c_tensor, input *K2c_tensor, op func(a float64, b float64) float64) {
+	if input.Numel == output.Numel {
 		for j := 0; j < output.Numel; j++ {
-			output.Array[j] += input.Array[j]
+			output.Array[j] = op(output.Array[j], input.Array[j])
 		}
+		return
+	}
+	var strides = k2c_broadcast_strides(input, output)
+	var sub [K2C_MAX_NDIM]int
+	var idx = 0
+	for j := 0; j < output.Numel; j++ {
+		output.Array[j] = op(output.Array[j], input.Array[idx])
+		for d := output.Ndim - 1; d >= 0; d-- {
+			sub[d]++
+			idx += strides[d]
+			if sub[d] < output.Shape[d] {
+				break
+			}
+			idx -= strides[d] * sub[d]
+			sub[d] = 0
+		}
+	}
+}
+
+func k2c_op_first(a float64, b float64) float64 {
+	return b
+}
+
+func k2c_op_add(a float64, b float64) float64 {
+	return a + b
+}
+
+func k2c_op_subtract(a float64, b float64) float64 {
+	return a - b
+}
+
+func k2c_op_multiply(a float64, b float64) float64 {
+	return a * b
+}
+
+func k2c_op_max(a float64, b float64) float64 {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+func k2c_op_min(a float64, b float64) float64 {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+/**
+* Element-wise sum of several tensors.
+* inputs are broadcast to the shape of the output.
+*
+* :param output: output tensor. May be the first input.
+* :param inputList: Tensors to be summed.
+*/
+func K2c_add(output *K2c_tensor, inputList ...*K2c_tensor) {
+	k2c_broadcast_op(output, inputList[0], k2c_op_first)
+	for _, input := range inputList[1:] {
+		k2c_broadcast_op(output, input, k2c_op_add)
 	}
 }
 
 
 /**
 * Element-wise difference of two tensors.
+* inputs are broadcast to the shape of the output.
 *
-* :param output: output tensor.
-* :param num_tensors: number of tensors being summed. Not used but kept for a consistent API with other merge layers.
+* :param output: output tensor. May be tensor1.
 * :param tensor1: first input tensor.
 * :param tensor2: second input tensor.
 */
-func k2c_subtract(output *K2c_tensor, num_tensors int, tensor1 *K2c_tensor, tensor2 *K2c_tensor) {
-	for i := 0; i < output.Numel; i++ {
-		output.Array[i] = tensor1.Array[i] - tensor2.Array[i]
-	}
+func K2c_subtract(output *K2c_tensor, tensor1 *K2c_tensor, tensor2 *K2c_tensor) {
+	k2c_broadcast_op(output, tensor1, k2c_op_first)
+	k2c_broadcast_op(output, tensor2, k2c_op_subtract)
 }
 
 
 /**
 * Element-wise product of several tensors.
+* inputs are broadcast to the shape of the output.
 *
-* :param output: output tensor.
-* :param num_tensors: number of tensors being multiplied.
-* :param ...: variadic. Tensors to be multiplied.
+* :param output: output tensor. May be the first input.
+* :param inputList: Tensors to be multiplied.
 */
-func k2c_multiply(output *K2c_tensor, inputList ...*K2c_tensor) {
-	output.fillFloat64(1)
-	for _, input := range inputList {
-		for j := 0; j < output.Numel; j++ {
-			output.Array[j] *= input.Array[j]
-		}
+func K2c_multiply(output *K2c_tensor, inputList ...*K2c_tensor) {
+	k2c_broadcast_op(output, inputList[0], k2c_op_first)
+	for _, input := range inputList[1:] {
+		k2c_broadcast_op(output, input, k2c_op_multiply)
 	}
 }
 
 
 /**
 * Element-wise average of several tensors.
+* inputs are broadcast to the shape of the output.
 *
-* :param output: output tensor.
-* :param num_tensors: number of tensors being averaged.
-* :param ...: variadic. Tensors to be averaged.
+* :param output: output tensor. May be the first input.
+* :param inputList: Tensors to be averaged.
 */
-func k2c_average(output *K2c_tensor, inputList ...*K2c_tensor) {
+func K2c_average(output *K2c_tensor, inputList ...*K2c_tensor) {
 	var num_tensors_inv = 1.0 / float64(len(inputList))
-	output.fillFloat64(0)
-	for _, input := range inputList {
-		for j := 0; j < output.Numel; j++ {
-			output.Array[j] += input.Array[j] * num_tensors_inv
-		}
+	K2c_add(output, inputList...)
+	for j := 0; j < output.Numel; j++ {
+		output.Array[j] *= num_tensors_inv
 	}
 }
 
 
 /**
 * Element-wise maximum of several tensors.
+* inputs are broadcast to the shape of the output.
 *
-* :param output: output tensor.
-* :param num_tensors: number of tensors over which to take max.
-* :param ...: variadic. Tensors to take the max of.
+* :param output: output tensor. May be the first input.
+* :param inputList: Tensors to take the max of.
 */
-func k2c_max(output *K2c_tensor, inputList ...*K2c_tensor) {
-	for i := 0; i < output.Numel; i++ {
-		output.Array[i] = inputList[0].Array[i]
-	}
+func K2c_max(output *K2c_tensor, inputList ...*K2c_tensor) {
+	k2c_broadcast_op(output, inputList[0], k2c_op_first)
 	for _, input := range inputList[1:] {
-		for j := 0; j < output.Numel; j++ {
-			if output.Array[j] < input.Array[j] {
-				output.Array[j] = input.Array[j]
-			}
-		}
+		k2c_broadcast_op(output, input, k2c_op_max)
 	}
 }
 
 
 /**
 * Element-wise minimum of several tensors.
+* inputs are broadcast to the shape of the output.
 *
-* :param output: output tensor.
+* :param output: output tensor. May be the first input.
 * :param inputList: Tensors to take the min of.
 */
-func k2c_min(output *K2c_tensor, inputList ...*K2c_tensor) {
-	for i := 0; i < output.Numel; i++ {
-		output.Array[i] = inputList[0].Array[i]
-	}
+func K2c_min(output *K2c_tensor, inputList ...*K2c_tensor) {
+	k2c_broadcast_op(output, inputList[0], k2c_op_first)
 	for _, input := range inputList[1:] {
-		for j := 0; j < output.Numel; j++ {
-			if output.Array[j] > input.Array[j] {
-				output.Array[j] = input.Array[j]
-			}
-		}
+		k2c_broadcast_op(output, input, k2c_op_min)
 	}
 }
 
 
 /**
 * Concatenation of several tensors.
+* the inputs must have the same shape except along axis.
 *
 * :param output: output tensor.
 * :param axis: axis along which to concatenate.
 * :param inputList: Tensors to concatenate.
 */
-func k2c_concatenate(output *K2c_tensor, axis int, inputList ...*K2c_tensor) {
+func K2c_concatenate(output *K2c_tensor, axis int, inputList ...*K2c_tensor) {
+	var outer, inner = k2c_split_sizes(output, axis)
+	var out_chunk = output.Shape[axis] * inner
 	var offset = 0
-	var outidx int
-	var insub, outsub [K2C_MAX_NDIM]int
 	for _, input := range inputList {
-		for j := 0; j < input.Numel; j++ {
-			k2c_idx2sub(j, insub[:], input.Shape[:], input.Ndim)
-			copy(outsub[:], insub[:])
-			outidx = k2c_sub2idx(outsub[:], output.Shape[:], output.Ndim)
-			output.Array[outidx] = input.Array[j]
+		var chunk = input.Shape[axis] * inner
+		for i := 0; i < outer; i++ {
+			copy(output.Array[i*out_chunk+offset:i*out_chunk+offset+chunk], input.Array[i*chunk:(i+1)*chunk])
+		}
+		offset += chunk
+	}
+}
+
+
+/**
+* Split of a tensor into several tensors, the inverse of concatenate.
+* the outputs must have the same shape as the input except along axis, and their sizes along axis must add up to
+* the size of the input.
+*
+* :param input: input tensor.
+* :param axis: axis along which to split.
+* :param outputList: Tensors to split into.
+*/
+func K2c_split(input *K2c_tensor, axis int, outputList ...*K2c_tensor) {
+	var outer, inner = k2c_split_sizes(input, axis)
+	var in_chunk = input.Shape[axis] * inner
+	var offset = 0
+	for _, output := range outputList {
+		var chunk = output.Shape[axis] * inner
+		for i := 0; i < outer; i++ {
+			copy(output.Array[i*chunk:(i+1)*chunk], input.Array[i*in_chunk+offset:i*in_chunk+offset+chunk])
+		}
+		offset += chunk
+	}
+}
+
+
+/**
+* Number of elements before and after an axis of a tensor.
+*
+* :param tensor: tensor.
+* :param axis: axis.
+* :return: product of the dimensions before axis, and product of the dimensions after axis.
+*/
+func k2c_split_sizes(tensor *K2c_tensor, axis int) (int, int) {
+	var outer, inner = 1, 1
+	for i := 0; i < axis; i++ {
+		outer *= tensor.Shape[i]
+	}
+	for i := axis + 1; i < tensor.Ndim; i++ {
+		inner *= tensor.Shape[i]
+	}
+	return outer, inner
+}
+
+
+/**
+* Mask of a merge layer.
+* a timestep is kept only if it is kept in every input. Inputs without a mask are passed as nil.
+*
+* :param output: Array[timesteps] output mask.
+* :param maskList: masks of the merged tensors.
+*/
+func K2c_merge_mask(output []bool, maskList ...[]bool) {
+	for i := range output {
+		output[i] = true
+	}
+	for _, mask := range maskList {
+		if mask == nil {
+			continue
+		}
+		for i := range output {
+			output[i] = output[i] && mask[i]
 		}
-		offset += input.Shape[axis]
 	}
 }
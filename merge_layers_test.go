@@ -0,0 +1,99 @@
+package keras2go
+
+import "testing"
+
+func TestK2c_concatenate(t *testing.T) {
+	var baseShape = []int{2, 3, 4, 2, 3}
+	for ndim := 1; ndim <= K2C_MAX_NDIM; ndim++ {
+		for axis := 0; axis < ndim; axis++ {
+			// inputs of sizes 1, 2 and 3 along axis
+			var inputs []*K2c_tensor
+			var outShape = append([]int{}, baseShape[:ndim]...)
+			outShape[axis] = 0
+			for n := 1; n <= 3; n++ {
+				var shape = append([]int{}, baseShape[:ndim]...)
+				shape[axis] = n
+				var input = newTestTensor(shape)
+				for i := range input.Array {
+					input.Array[i] = float64(100*n + i)
+				}
+				inputs = append(inputs, input)
+				outShape[axis] += n
+			}
+			var output = newTestTensor(outShape)
+
+			K2c_concatenate(output, axis, inputs...)
+			var sub [K2C_MAX_NDIM]int
+			var offset = 0
+			for _, input := range inputs {
+				for i := 0; i < input.Numel; i++ {
+					k2c_idx2sub(i, sub[:], input.Shape[:], input.Ndim)
+					sub[axis] += offset
+					var got = output.Array[k2c_sub2idx(sub[:], output.Shape[:], output.Ndim)]
+					if got != input.Array[i] {
+						t.Fatalf("ndim %d axis %d: got %v, expected %v", ndim, axis, got, input.Array[i])
+					}
+				}
+				offset += input.Shape[axis]
+			}
+
+			var splits []*K2c_tensor
+			for _, input := range inputs {
+				splits = append(splits, newTestTensor(input.Shape[:input.Ndim]))
+			}
+			K2c_split(output, axis, splits...)
+			for n, split := range splits {
+				for i := range split.Array {
+					if split.Array[i] != inputs[n].Array[i] {
+						t.Fatalf("ndim %d axis %d: split %d differs at %d", ndim, axis, n, i)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestK2c_merge_broadcast(t *testing.T) {
+	x := newTestTensor([]int{2, 2, 3})
+	for i := range x.Array {
+		x.Array[i] = float64(i)
+	}
+	// (H,W,C) with (1,1,C)
+	y := newTestTensor([]int{1, 1, 3})
+	y.Array = []float64{10, 20, 30}
+	output := newTestTensor([]int{2, 2, 3})
+	K2c_add(output, x, y)
+	checkArray(t, output.Array, []float64{10, 21, 32, 13, 24, 35, 16, 27, 38, 19, 30, 41})
+	K2c_subtract(output, y, x)
+	checkArray(t, output.Array, []float64{10, 19, 28, 7, 16, 25, 4, 13, 22, 1, 10, 19})
+
+	// inputs of different ranks, each broadcast along other axes
+	a := newTestTensor([]int{2, 1, 3})
+	a.Array = []float64{1, -2, 3, 0.5, 4, -1}
+	b := newTestTensor([]int{4, 1})
+	b.Array = []float64{2, -1, 0.5, 3}
+	product := newTestTensor([]int{2, 4, 3})
+	K2c_multiply(product, a, b)
+	checkArray(t, product.Array, []float64{2, -4, 6, -1, 2, -3, 0.5, -1, 1.5, 3, -6, 9,
+		1, 8, -2, -0.5, -4, 1, 0.25, 2, -0.5, 1.5, 12, -3})
+
+	c := newTestTensor([]int{2, 3})
+	c.Array = []float64{3, 4, -1, 0, 6, 2}
+	d := newTestTensor([]int{3})
+	d.Array = []float64{1, 5, 2}
+	merged := newTestTensor([]int{2, 3})
+	K2c_max(merged, c, d)
+	checkArray(t, merged.Array, []float64{3, 5, 2, 1, 6, 2})
+	K2c_min(merged, c, d)
+	checkArray(t, merged.Array, []float64{1, 4, -1, 0, 5, 2})
+	K2c_average(merged, c, d)
+	checkArray(t, merged.Array, []float64{2, 4.5, 0.5, 0.5, 5.5, 2})
+
+	// in place, the output is the first input
+	K2c_binary(c, c, d, K2C_BINARY_SUBTRACT)
+	checkArray(t, c.Array, []float64{2, -1, -3, -1, 1, 0})
+	K2c_add(x, x, y)
+	checkArray(t, x.Array, []float64{10, 21, 32, 13, 24, 35, 16, 27, 38, 19, 30, 41})
+	K2c_multiply(b, b, b)
+	checkArray(t, b.Array, []float64{4, 1, 0.25, 9})
+}
@@ -1,5 +1,7 @@
 package keras2go
 
+import "math"
+
 /**
 * Batch normalization layer.
 * applies a transformation that maintains the mean activation close to 0 and the activation standard deviation close to 1.
@@ -26,3 +28,133 @@ func k2c_batch_norm(output *K2c_tensor, input *K2c_tensor, mean *K2c_tensor, std
 			beta.Array[idx]
 	}
 }
+
+/**
+* Layer normalization layer.
+* normalizes each sample independently over the given axes, then applies a learned scale and offset.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param gamma: tensor of gamma (scale) values, with the shape of input along axes. nil if scale is disabled.
+* :param beta: tensor of beta (offset) values, with the shape of input along axes. nil if center is disabled.
+* :param axes: axes to be normalized over.
+* :param epsilon: small constant added to the variance to avoid dividing by zero.
+*/
+func K2c_layer_norm(output *K2c_tensor, input *K2c_tensor, gamma *K2c_tensor, beta *K2c_tensor, axes []int, epsilon float64) {
+	var r = k2c_make_reduction(input.Shape[:], input.Ndim, axes)
+	var n = float64(r.nmembers)
+	for g := 0; g < r.ngroups; g++ {
+		var start = r.group_offset(g)
+		var mean = 0.0
+		for m := 0; m < r.nmembers; m++ {
+			mean += input.Array[start+r.member_offset(m)]
+		}
+		mean /= n
+		var variance = 0.0
+		for m := 0; m < r.nmembers; m++ {
+			var d = input.Array[start+r.member_offset(m)] - mean
+			variance += d * d
+		}
+		var inv_stdev = 1 / math.Sqrt(variance/n+epsilon)
+		for m := 0; m < r.nmembers; m++ {
+			var idx = start + r.member_offset(m)
+			var y = (input.Array[idx] - mean) * inv_stdev
+			if gamma != nil {
+				y *= gamma.Array[m]
+			}
+			if beta != nil {
+				y += beta.Array[m]
+			}
+			output.Array[idx] = y
+		}
+	}
+}
+
+/**
+* Group normalization layer.
+* splits the channels into groups and normalizes each group over its channels and all other axes.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param gamma: tensor of gamma (scale) values, one per channel. nil if scale is disabled.
+* :param beta: tensor of beta (offset) values, one per channel. nil if center is disabled.
+* :param axis: channel axis.
+* :param groups: number of groups, must divide the number of channels.
+* :param epsilon: small constant added to the variance to avoid dividing by zero.
+*/
+func K2c_group_norm(output *K2c_tensor, input *K2c_tensor, gamma *K2c_tensor, beta *K2c_tensor, axis int, groups int, epsilon float64) {
+	var offset = 1
+	for i := axis + 1; i < input.Ndim; i++ {
+		offset *= input.Shape[i]
+	}
+	var channels = input.Shape[axis]
+	var outer = input.Numel / (channels * offset)
+	var group_size = channels / groups
+	var n = float64(outer * group_size * offset)
+
+	for g := 0; g < groups; g++ {
+		var c0 = g * group_size
+		var mean = 0.0
+		for o := 0; o < outer; o++ {
+			for c := c0; c < c0+group_size; c++ {
+				var start = (o*channels + c) * offset
+				for j := start; j < start+offset; j++ {
+					mean += input.Array[j]
+				}
+			}
+		}
+		mean /= n
+		var variance = 0.0
+		for o := 0; o < outer; o++ {
+			for c := c0; c < c0+group_size; c++ {
+				var start = (o*channels + c) * offset
+				for j := start; j < start+offset; j++ {
+					var d = input.Array[j] - mean
+					variance += d * d
+				}
+			}
+		}
+		var inv_stdev = 1 / math.Sqrt(variance/n+epsilon)
+		for o := 0; o < outer; o++ {
+			for c := c0; c < c0+group_size; c++ {
+				var scale = inv_stdev
+				var shift = 0.0
+				if gamma != nil {
+					scale *= gamma.Array[c]
+				}
+				if beta != nil {
+					shift = beta.Array[c]
+				}
+				var start = (o*channels + c) * offset
+				for j := start; j < start+offset; j++ {
+					output.Array[j] = (input.Array[j]-mean)*scale + shift
+				}
+			}
+		}
+	}
+}
+
+/**
+* Unit normalization layer.
+* scales the input so that it has unit L2 norm over the given axes.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param axes: axes to be normalized over.
+*/
+func K2c_unit_norm(output *K2c_tensor, input *K2c_tensor, axes []int) {
+	var r = k2c_make_reduction(input.Shape[:], input.Ndim, axes)
+	for g := 0; g < r.ngroups; g++ {
+		var start = r.group_offset(g)
+		var sum = 0.0
+		for m := 0; m < r.nmembers; m++ {
+			var x = input.Array[start+r.member_offset(m)]
+			sum += x * x
+		}
+		var inv_norm = 1 / math.Sqrt(math.Max(sum, 1e-12))
+		for m := 0; m < r.nmembers; m++ {
+			var idx = start + r.member_offset(m)
+			output.Array[idx] = input.Array[idx] * inv_norm
+		}
+	}
+}
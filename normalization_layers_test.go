@@ -0,0 +1,70 @@
+package keras2go
+
+import (
+	"testing"
+)
+
+func TestK2c_layer_norm(t *testing.T) {
+	var x = []float64{0.5, -1, 2, 0.25, -0.75, 1.5, 3, -2, 0, 1, -0.5, 0.8}
+	var input = newTestTensor([]int{2, 3, 2})
+	copy(input.Array, x)
+	var output = newTestTensor([]int{2, 3, 2})
+
+	// axes that are not next to each other, gamma and beta have the Shape {2, 2}
+	var gamma = newTestTensor([]int{2, 2})
+	copy(gamma.Array, []float64{1, 0.5, -1, 2})
+	var beta = newTestTensor([]int{2, 2})
+	copy(beta.Array, []float64{0, 0.1, -0.2, 0.3})
+	K2c_layer_norm(output, input, gamma, beta, []int{0, 2}, 1e-3)
+	checkArrayClose(t, output.Array, []float64{0.19908893005805878, -0.19863339508708813, 1.524858006849227,
+		-0.26115058056955376, -1.093257788236635, 0.7681019817001659, -1.7263484637784505, -1.9563412073246662,
+		0.8433238994231553, 0.7815341074260718, 0.6233175936103053, 1.4607428368932176})
+
+	gamma = newTestTensor([]int{3, 2})
+	copy(gamma.Array, []float64{1, 2, 0.5, -1, 1.5, 0.25})
+	K2c_layer_norm(output, input, gamma, nil, []int{1, 2}, 1e-5)
+	checkArrayClose(t, output.Array, []float64{0.07669617405457009, -2.6076699178553837, 0.7286136535184159,
+		0.15339234810914024, -1.6106196551459724, 0.24926256567735283, 1.7124971738548511, -3.119580838996735,
+		-0.12543769107854005, -0.40358213651356367, -0.867156212238603, 0.06817265819485874})
+}
+
+func TestK2c_group_norm(t *testing.T) {
+	// two groups of two channels along the last axis
+	var input = newTestTensor([]int{2, 2, 4})
+	copy(input.Array, []float64{0.5, -1, 2, 0.25, -0.75, 1.5, 3, -2, 0, 1, -0.5, 0.8, 1.2, -0.3, 0.6, 2.5})
+	var output = newTestTensor([]int{2, 2, 4})
+	var gamma = newTestTensor([]int{4})
+	copy(gamma.Array, []float64{1, 0.5, -1, 2})
+	var beta = newTestTensor([]int{4})
+	copy(beta.Array, []float64{0, 0.1, -0.2, 0.3})
+	K2c_group_norm(output, input, gamma, beta, 2, 2, 1e-3)
+	checkArrayClose(t, output.Array, []float64{0.2665210835130983, -0.6311321615291753, -0.9575346407012002,
+		-0.45348365331777113, -1.174133421963109, 0.809522343947032, -1.6056926220498207, -3.370194569386563,
+		-0.3097407186773846, 0.5213914428517906, 0.6628603126703507, 0.2594901261657114, 1.0732876065797743,
+		-0.22774889999583722, -0.050113466813131585, 2.4632272627510208})
+
+	// channels along the first axis
+	input = newTestTensor([]int{4, 3})
+	copy(input.Array, []float64{0.5, -1, 2, 0.25, -0.75, 1.5, 3, -2, 0, 1, -0.5, 0.8})
+	output = newTestTensor([]int{4, 3})
+	K2c_group_norm(output, input, nil, nil, 0, 2, 1e-3)
+	checkArrayClose(t, output.Array, []float64{0.07666403628352442, -1.3032886168199154, 1.456616689386964,
+		-0.1533280725670489, -1.0732965079693422, 0.9966324716858175, 1.712134212910546, -1.5594598244981404,
+		-0.25082220953466594, 0.4034965979470713, -0.5779816132755345, 0.2726328364507239})
+}
+
+func TestK2c_unit_norm(t *testing.T) {
+	// a row of zeros stays zero
+	var input = newTestTensor([]int{3, 3})
+	copy(input.Array, []float64{3, 4, 0, 1, -2, 2, 0, 0, 0})
+	var output = newTestTensor([]int{3, 3})
+	K2c_unit_norm(output, input, []int{1})
+	checkArrayClose(t, output.Array, []float64{0.6, 0.8, 0, 1.0 / 3, -2.0 / 3, 2.0 / 3, 0, 0, 0})
+
+	input = newTestTensor([]int{2, 3})
+	copy(input.Array, []float64{3, 4, 0, 1, -2, 2})
+	output = newTestTensor([]int{2, 3})
+	K2c_unit_norm(output, input, []int{0, 1})
+	checkArrayClose(t, output.Array, []float64{0.5144957554275265, 0.6859943405700353, 0, 0.17149858514250882,
+		-0.34299717028501764, 0.34299717028501764})
+}
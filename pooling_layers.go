@@ -2,118 +2,240 @@ package keras2go
 
 import "math"
 
-func K2c_global_max_pooling(output *K2c_tensor, input *K2c_tensor) {
-	var in_chan = input.Shape[input.Ndim-1]
-	copy(output.Array, input.Array[:in_chan])
+/**
+* Splits a tensor of spatial data into its channel count and spatial Shape.
+*
+* :param tensor: tensor to split.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+* :param shape: Array[Ndim-1] output spatial Shape.
+* :return: number of channels, number of spatial positions, stride between two channels, stride between two positions.
+ */
+func k2c_spatial_shape(tensor *K2c_tensor, data_format int, shape []int) (channels int, positions int, chan_step int, pos_step int) {
+	var ndim = tensor.Ndim - 1
+	if data_format == K2C_CHANNELS_FIRST {
+		channels = tensor.Shape[0]
+		copy(shape, tensor.Shape[1:tensor.Ndim])
+	} else {
+		channels = tensor.Shape[ndim]
+		copy(shape, tensor.Shape[:ndim])
+	}
+	positions = 1
+	for i := 0; i < ndim; i++ {
+		positions *= shape[i]
+	}
+	if data_format == K2C_CHANNELS_FIRST {
+		return channels, positions, positions, 1
+	}
+	return channels, positions, 1, channels
+}
 
-	for i := 0; i < input.Numel; i += in_chan {
-		for j := 0; j < in_chan; j++ {
-			if output.Array[j] < input.Array[i+j] {
-				output.Array[j] = input.Array[i+j]
+/**
+* Global max pooling.
+* works for 1D, 2D, or 3D inputs. Like keras it pools over all positions, masked or not.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+ */
+func K2c_global_max_pooling(output *K2c_tensor, input *K2c_tensor, data_format int) {
+	var shape [K2C_MAX_NDIM]int
+	var in_chan, positions, chan_step, pos_step = k2c_spatial_shape(input, data_format, shape[:])
+
+	for j := 0; j < in_chan; j++ {
+		output.Array[j] = -math.MaxFloat64
+		for i := 0; i < positions; i++ {
+			if output.Array[j] < input.Array[i*pos_step+j*chan_step] {
+				output.Array[j] = input.Array[i*pos_step+j*chan_step]
 			}
 		}
 	}
 }
 
-func K2c_global_avg_pooling(output *K2c_tensor, input *K2c_tensor) {
-	var in_chan = input.Shape[input.Ndim-1]
+/**
+* Global average pooling.
+* works for 1D, 2D, or 3D inputs. Only keras GlobalAveragePooling1D uses a mask.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+* :param mask: Array[timesteps] of which timesteps to pool over, or nil to pool over all positions.
+ */
+func K2c_global_avg_pooling(output *K2c_tensor, input *K2c_tensor, data_format int, mask []bool) {
+	var shape [K2C_MAX_NDIM]int
+	var in_chan, positions, chan_step, pos_step = k2c_spatial_shape(input, data_format, shape[:])
 	output.fillFloat64(0)
-	num_inv := 1 / float64(input.Numel/in_chan)
+	var count = positions
+	if mask != nil {
+		count = 0
+		for i := 0; i < positions; i++ {
+			if mask[i] {
+				count++
+			}
+		}
+	}
+	num_inv := 1 / float64(count)
 
-	for i := 0; i < input.Numel; i += in_chan {
+	for i := 0; i < positions; i++ {
+		if mask != nil && !mask[i] {
+			continue
+		}
 		for j := 0; j < in_chan; j++ {
-			output.Array[j] += input.Array[i+j] * num_inv
+			output.Array[j] += input.Array[i*pos_step+j*chan_step] * num_inv
 		}
 	}
 }
 
-func K2c_maxpool1d(output *K2c_tensor, input *K2c_tensor, pool_size int, stride int) {
-	var channels = input.Shape[1]
+/**
+* Max pooling for 1D (temporal) data.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param pool_size: size of the max pooling window.
+* :param stride: factor by which to downscale.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+ */
+func K2c_maxpool1d(output *K2c_tensor, input *K2c_tensor, pool_size int, stride int, padding int, data_format int) {
+	k2c_pool(output, input, []int{pool_size}, []int{stride}, padding, data_format, false)
+}
 
-	for i := 0; i < channels; i++ {
-		var j, k int
-		for j < output.Shape[0]*channels {
-			output.Array[j+i] = input.Array[k+i]
-			for l := 0; l < pool_size*channels; l += channels {
-				if output.Array[j+i] < input.Array[k+i+l] {
-					output.Array[j+i] = input.Array[k+i+l]
-				}
-			}
-			j += channels
-			k += stride * channels
-		}
-	}
+/**
+* Max pooling for 2D (spatial) data.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param pool_size: Array[2] size of the max pooling window. Order is {pool dim 1, pool dim 2}.
+* :param stride: Array[2] factor by which to downscale. Order is {stride dim 1, stride dim 2}.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+ */
+func K2c_maxpool2d(output *K2c_tensor, input *K2c_tensor, pool_size []int, stride []int, padding int, data_format int) {
+	k2c_pool(output, input, pool_size, stride, padding, data_format, false)
 }
 
-func K2c_maxpool2d(output *K2c_tensor, input *K2c_tensor, pool_size []int, stride []int) {
-	var channels = input.Shape[2]
-	for i := 0; i < channels; i++ {
-		var j, k int
-		for j < output.Shape[1]*channels {
-			var l, m int
-			for l < output.Numel {
-				output.Array[l+j+i] = input.Array[m+k+i]
-				for n := 0; n < pool_size[1]*channels; n += channels {
-					for p := 0; p < pool_size[0]*channels*input.Shape[1]; p += channels * input.Shape[1] {
-						if output.Array[l+j+i] < input.Array[m+k+i+n+p] {
-							output.Array[l+j+i] = input.Array[m+k+i+n+p]
-						}
-					}
-				}
-				l += channels * output.Shape[1]
-				m += channels * input.Shape[1] * stride[0]
-			}
+/**
+* Max pooling for 3D (spatial or spatio-temporal) data.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param pool_size: Array[3] size of the max pooling window. Order is {pool dim 1, pool dim 2, pool dim 3}.
+* :param stride: Array[3] factor by which to downscale. Order is {stride dim 1, stride dim 2, stride dim 3}.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+ */
+func K2c_maxpool3d(output *K2c_tensor, input *K2c_tensor, pool_size []int, stride []int, padding int, data_format int) {
+	k2c_pool(output, input, pool_size, stride, padding, data_format, false)
+}
 
-			j += channels
-			k += channels * stride[1]
-		}
-	}
+/**
+* Average pooling for 1D (temporal) data.
+* Padded elements are not counted in the average.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param pool_size: size of the averaging window.
+* :param stride: factor by which to downscale.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+ */
+func K2c_avgpool1d(output *K2c_tensor, input *K2c_tensor, pool_size int, stride int, padding int, data_format int) {
+	k2c_pool(output, input, []int{pool_size}, []int{stride}, padding, data_format, true)
 }
 
+/**
+* Average pooling for 2D (spatial) data.
+* Padded elements are not counted in the average.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param pool_size: Array[2] size of the averaging window. Order is {pool dim 1, pool dim 2}.
+* :param stride: Array[2] factor by which to downscale. Order is {stride dim 1, stride dim 2}.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+ */
+func K2c_avgpool2d(output *K2c_tensor, input *K2c_tensor, pool_size []int, stride []int, padding int, data_format int) {
+	k2c_pool(output, input, pool_size, stride, padding, data_format, true)
+}
 
-func K2c_avgpool1d(output *K2c_tensor, input *K2c_tensor, pool_size int, stride int) {
-	var channels = input.Shape[1]
-	output.fillFloat64(0)
-	for i := 0; i < channels; i++ {
-		var j, k int
-		for j < output.Numel {
-			var count int
-			for l := 0; l < pool_size*channels; l += channels {
-				if input.Array[k+i+l] > -math.MaxFloat64 {
-					output.Array[j+i] += input.Array[k+i+l]
-					count++
-				}
-			}
-			output.Array[i+j] /= float64(count)
-			j += channels
-			k += stride * channels
-		}
-	}
+/**
+* Average pooling for 3D (spatial or spatio-temporal) data.
+* Padded elements are not counted in the average.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param pool_size: Array[3] size of the averaging window. Order is {pool dim 1, pool dim 2, pool dim 3}.
+* :param stride: Array[3] factor by which to downscale. Order is {stride dim 1, stride dim 2, stride dim 3}.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+ */
+func K2c_avgpool3d(output *K2c_tensor, input *K2c_tensor, pool_size []int, stride []int, padding int, data_format int) {
+	k2c_pool(output, input, pool_size, stride, padding, data_format, true)
 }
 
-func K2c_avgpool2d(output *K2c_tensor, input *K2c_tensor, pool_size []int, stride []int) {
-	output.fillFloat64(0)
-	var channels = input.Shape[2]
-	for i := 0; i < channels; i++ {
-		var j, k int
-		for j < output.Shape[1]*channels {
-			var l, m int
-			for l < output.Numel {
-				var count int
-				for n := 0; n < pool_size[1]*channels; n += channels {
-					for p := 0; p < pool_size[0]*channels*input.Shape[1]; p += channels * input.Shape[1] {
-						if -math.MaxFloat64 < input.Array[m+k+i+n+p] {
-							output.Array[l+j+i] += input.Array[m+k+i+n+p]
-							count++
-						}
-					}
+/**
+* Max or average pooling over any number of spatial dimensions.
+* 'same' padding is split as Keras does, with the extra element after. Padded elements never take part in a window.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param pool_size: Array[Ndim-1] size of the pooling window.
+* :param stride: Array[Ndim-1] factor by which to downscale.
+* :param padding: padding mode, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param data_format: K2C_CHANNELS_LAST or K2C_CHANNELS_FIRST.
+* :param average: whether to take the average (true) or the maximum (false) of each window.
+ */
+func k2c_pool(output *K2c_tensor, input *K2c_tensor, pool_size []int, stride []int, padding int, data_format int, average bool) {
+	var ndim = input.Ndim - 1
+	var in_shape, out_shape, pad [K2C_MAX_NDIM]int
+	var osub, wsub, isub [K2C_MAX_NDIM]int
+	var channels, _, in_chan_step, in_pos_step = k2c_spatial_shape(input, data_format, in_shape[:])
+	var _, out_positions, out_chan_step, out_pos_step = k2c_spatial_shape(output, data_format, out_shape[:])
+	var window = 1
+	for i := 0; i < ndim; i++ {
+		pad[i] = k2c_pad_before(in_shape[i], out_shape[i], pool_size[i], stride[i], 1, padding)
+		window *= pool_size[i]
+	}
+
+	for o := 0; o < out_positions; o++ {
+		var outIdx = o * out_pos_step
+		for k := 0; k < channels; k++ {
+			if average {
+				output.Array[outIdx+k*out_chan_step] = 0
+			} else {
+				output.Array[outIdx+k*out_chan_step] = -math.MaxFloat64
+			}
+		}
+		k2c_idx2sub(o, osub[:], out_shape[:], ndim)
+		var count = 0
+		for w := 0; w < window; w++ {
+			k2c_idx2sub(w, wsub[:], pool_size, ndim)
+			var inside = true
+			for i := 0; i < ndim; i++ {
+				isub[i] = osub[i]*stride[i] + wsub[i] - pad[i]
+				if isub[i] < 0 || isub[i] >= in_shape[i] {
+					inside = false
+					break
 				}
-				output.Array[l+j+i] /= float64(count)
-				l += channels * output.Shape[1]
-				m += channels * input.Shape[1] * stride[0]
 			}
-			j += channels
-			k += channels * stride[1]
+			if !inside {
+				continue
+			}
+			count++
+			var inIdx = k2c_sub2idx(isub[:], in_shape[:], ndim) * in_pos_step
+			for k := 0; k < channels; k++ {
+				var x = input.Array[inIdx+k*in_chan_step]
+				if average {
+					output.Array[outIdx+k*out_chan_step] += x
+				} else if output.Array[outIdx+k*out_chan_step] < x {
+					output.Array[outIdx+k*out_chan_step] = x
+				}
+			}
+		}
+		if average {
+			var count_inv = 1 / float64(count)
+			for k := 0; k < channels; k++ {
+				output.Array[outIdx+k*out_chan_step] *= count_inv
+			}
 		}
 	}
 }
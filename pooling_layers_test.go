@@ -0,0 +1,166 @@
+package keras2go
+
+import "testing"
+
+func TestK2c_global_pooling(t *testing.T) {
+	// 5 timesteps of 2 channels, the last 2 are padding
+	input := newTestTensor([]int{5, 2})
+	input.Array = []float64{-1.5, -0.5, -2, -0.25, -0.75, -3, 0, 0, 0, 0}
+	mask := []bool{true, true, true, false, false}
+	output := newTestTensor([]int{2})
+
+	// like keras, max pooling counts the padding
+	K2c_global_max_pooling(output, input, K2C_CHANNELS_LAST)
+	checkArray(t, output.Array, []float64{0, 0})
+	K2c_global_avg_pooling(output, input, K2C_CHANNELS_LAST, mask)
+	checkArrayClose(t, output.Array, []float64{-4.25 / 3, -3.75 / 3})
+	K2c_global_avg_pooling(output, input, K2C_CHANNELS_LAST, nil)
+	checkArrayClose(t, output.Array, []float64{-4.25 / 5, -3.75 / 5})
+}
+
+// The expected outputs of the pooling layers are windows over the input like tensorflow takes them, with the extra
+// element of 'same' padding after and padded elements left out of the maximum and the average.
+
+type poolTest struct {
+	name         string
+	input_shape  []int
+	output_shape []int
+	pool_size    []int
+	stride       []int
+	padding      int
+	data_format  int
+	average      bool
+	input        []float64
+	expected     []float64
+}
+
+func runPoolTests(t *testing.T, tests []poolTest) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := newTestTensor(test.input_shape)
+			copy(input.Array, test.input)
+			output := newTestTensor(test.output_shape)
+			switch len(test.pool_size) {
+			case 1:
+				if test.average {
+					K2c_avgpool1d(output, input, test.pool_size[0], test.stride[0], test.padding, test.data_format)
+				} else {
+					K2c_maxpool1d(output, input, test.pool_size[0], test.stride[0], test.padding, test.data_format)
+				}
+			case 2:
+				if test.average {
+					K2c_avgpool2d(output, input, test.pool_size, test.stride, test.padding, test.data_format)
+				} else {
+					K2c_maxpool2d(output, input, test.pool_size, test.stride, test.padding, test.data_format)
+				}
+			case 3:
+				if test.average {
+					K2c_avgpool3d(output, input, test.pool_size, test.stride, test.padding, test.data_format)
+				} else {
+					K2c_maxpool3d(output, input, test.pool_size, test.stride, test.padding, test.data_format)
+				}
+			}
+			checkArrayTol(t, output.Array, test.expected, 1e-12)
+		})
+	}
+}
+
+func TestK2c_pool_3d_channels_first(t *testing.T) {
+	runPoolTests(t, []poolTest{
+		{
+			name:        "3d max",
+			input_shape: []int{3, 4, 2, 2}, output_shape: []int{2, 2, 2, 2},
+			pool_size: []int{2, 2, 1}, stride: []int{1, 2, 1}, padding: K2C_PADDING_VALID,
+			data_format: K2C_CHANNELS_LAST, average: false,
+			input:    []float64{-1.0, 0.625, -1.25, 0.375, 2.0, 0.125, 1.75, -0.125, 1.5, -0.375, 1.25, 2.875, 1.0, 2.625, 0.75, 2.375, 0.5, 2.125, 3.75, 1.875, 3.5, 1.625, 3.25, 1.375, 3.0, 4.625, 2.75, 4.375, 2.5, 4.125, 2.25, 3.875, 5.5, 3.625, 5.25, 3.375, 5.0, 3.125, 4.75, 6.375, 4.5, 6.125, 4.25, 5.875, 4.0, 5.625, 7.25, 5.375},
+			expected: []float64{3.5, 2.125, 3.75, 1.875, 3.0, 4.625, 2.75, 4.375, 5.5, 3.625, 5.25, 6.375, 4.5, 6.125, 7.25, 5.875},
+		},
+		{
+			name:        "3d average",
+			input_shape: []int{3, 4, 2, 2}, output_shape: []int{2, 2, 2, 2},
+			pool_size: []int{2, 2, 1}, stride: []int{1, 2, 1}, padding: K2C_PADDING_VALID,
+			data_format: K2C_CHANNELS_LAST, average: true,
+			input:    []float64{-1.0, 0.625, -1.25, 0.375, 2.0, 0.125, 1.75, -0.125, 1.5, -0.375, 1.25, 2.875, 1.0, 2.625, 0.75, 2.375, 0.5, 2.125, 3.75, 1.875, 3.5, 1.625, 3.25, 1.375, 3.0, 4.625, 2.75, 4.375, 2.5, 4.125, 2.25, 3.875, 5.5, 3.625, 5.25, 3.375, 5.0, 3.125, 4.75, 6.375, 4.5, 6.125, 4.25, 5.875, 4.0, 5.625, 7.25, 5.375},
+			expected: []float64{1.25, 1.125, 1.875, 0.875, 2.0, 2.75, 1.75, 3.375, 3.625, 2.625, 4.25, 3.25, 3.5, 5.125, 4.125, 4.875},
+		},
+		{
+			name:        "3d max channels_first",
+			input_shape: []int{2, 3, 4, 2}, output_shape: []int{2, 2, 2, 2},
+			pool_size: []int{2, 2, 1}, stride: []int{1, 2, 1}, padding: K2C_PADDING_VALID,
+			data_format: K2C_CHANNELS_FIRST, average: false,
+			input:    []float64{-1.0, -1.25, 2.0, 1.75, 1.5, 1.25, 1.0, 0.75, 0.5, 3.75, 3.5, 3.25, 3.0, 2.75, 2.5, 2.25, 5.5, 5.25, 5.0, 4.75, 4.5, 4.25, 4.0, 7.25, 0.625, 0.375, 0.125, -0.125, -0.375, 2.875, 2.625, 2.375, 2.125, 1.875, 1.625, 1.375, 4.625, 4.375, 4.125, 3.875, 3.625, 3.375, 3.125, 6.375, 6.125, 5.875, 5.625, 5.375},
+			expected: []float64{3.5, 3.75, 3.0, 2.75, 5.5, 5.25, 4.5, 7.25, 2.125, 1.875, 4.625, 4.375, 3.625, 6.375, 6.125, 5.875},
+		},
+		{
+			name:        "2d average channels_first",
+			input_shape: []int{3, 3, 3}, output_shape: []int{3, 2, 2},
+			pool_size: []int{2, 2}, stride: []int{1, 1}, padding: K2C_PADDING_VALID,
+			data_format: K2C_CHANNELS_FIRST, average: true,
+			input:    []float64{-1.0, 0.375, 1.75, -0.375, 1.0, 2.375, 3.75, 1.625, 3.0, 0.625, 2.0, -0.125, 1.25, 2.625, 0.5, 1.875, 3.25, 4.625, -1.25, 0.125, 1.5, 2.875, 0.75, 2.125, 3.5, 1.375, 2.75},
+			expected: []float64{0.0, 1.375, 1.5, 2.0, 1.625, 1.25, 2.25, 2.75, 0.625, 1.125, 2.125, 1.75},
+		},
+		{
+			name:        "1d max channels_first",
+			input_shape: []int{2, 5}, output_shape: []int{2, 2},
+			pool_size: []int{3}, stride: []int{2}, padding: K2C_PADDING_VALID,
+			data_format: K2C_CHANNELS_FIRST, average: false,
+			input:    []float64{-1.0, -1.25, 2.0, 1.75, 1.5, 0.625, 0.375, 0.125, -0.125, -0.375},
+			expected: []float64{2.0, 2.0, 0.625, 0.125},
+		},
+	})
+}
+
+func TestK2c_pool_same_padding(t *testing.T) {
+	// odd inputs with stride 2: the last window sticks out after the input, and the average only counts the
+	// elements inside it
+	runPoolTests(t, []poolTest{
+		{
+			name:        "1d max odd length",
+			input_shape: []int{5, 2}, output_shape: []int{3, 2},
+			pool_size: []int{2}, stride: []int{2}, padding: K2C_PADDING_SAME,
+			data_format: K2C_CHANNELS_LAST, average: false,
+			input:    []float64{-1.0, 0.625, -1.25, 0.375, 2.0, 0.125, 1.75, -0.125, 1.5, -0.375},
+			expected: []float64{-1.0, 0.625, 2.0, 0.125, 1.5, -0.375},
+		},
+		{
+			name:        "1d average odd length",
+			input_shape: []int{5, 2}, output_shape: []int{3, 2},
+			pool_size: []int{2}, stride: []int{2}, padding: K2C_PADDING_SAME,
+			data_format: K2C_CHANNELS_LAST, average: true,
+			input:    []float64{-1.0, 0.625, -1.25, 0.375, 2.0, 0.125, 1.75, -0.125, 1.5, -0.375},
+			expected: []float64{-1.125, 0.5, 1.875, 0.0, 1.5, -0.375},
+		},
+		{
+			name:        "2d average pool 3",
+			input_shape: []int{5, 5, 1}, output_shape: []int{3, 3, 1},
+			pool_size: []int{3, 3}, stride: []int{2, 2}, padding: K2C_PADDING_SAME,
+			data_format: K2C_CHANNELS_LAST, average: true,
+			input:    []float64{-1.0, 0.625, -1.25, 0.375, 2.0, 0.125, 1.75, -0.125, 1.5, -0.375, 1.25, 2.875, 1.0, 2.625, 0.75, 2.375, 0.5, 2.125, 3.75, 1.875, 3.5, 1.625, 3.25, 1.375, 3.0},
+			expected: []float64{0.375, 0.479166666667, 0.875, 1.479166666667, 1.777777777778, 1.6875, 2.0, 2.104166666667, 2.5},
+		},
+		{
+			name:        "2d average pool 2",
+			input_shape: []int{5, 5, 1}, output_shape: []int{3, 3, 1},
+			pool_size: []int{2, 2}, stride: []int{2, 2}, padding: K2C_PADDING_SAME,
+			data_format: K2C_CHANNELS_LAST, average: true,
+			input:    []float64{-1.0, 0.625, -1.25, 0.375, 2.0, 0.125, 1.75, -0.125, 1.5, -0.375, 1.25, 2.875, 1.0, 2.625, 0.75, 2.375, 0.5, 2.125, 3.75, 1.875, 3.5, 1.625, 3.25, 1.375, 3.0},
+			expected: []float64{0.375, 0.125, 0.8125, 1.75, 2.375, 1.3125, 2.5625, 2.3125, 3.0},
+		},
+		{
+			name:        "2d max pool 2",
+			input_shape: []int{5, 5, 1}, output_shape: []int{3, 3, 1},
+			pool_size: []int{2, 2}, stride: []int{2, 2}, padding: K2C_PADDING_SAME,
+			data_format: K2C_CHANNELS_LAST, average: false,
+			input:    []float64{-1.0, 0.625, -1.25, 0.375, 2.0, 0.125, 1.75, -0.125, 1.5, -0.375, 1.25, 2.875, 1.0, 2.625, 0.75, 2.375, 0.5, 2.125, 3.75, 1.875, 3.5, 1.625, 3.25, 1.375, 3.0},
+			expected: []float64{1.75, 1.5, 2.0, 2.875, 3.75, 1.875, 3.5, 3.25, 3.0},
+		},
+		{
+			name:        "3d average channels_first",
+			input_shape: []int{2, 3, 1, 3}, output_shape: []int{2, 2, 1, 2},
+			pool_size: []int{2, 1, 2}, stride: []int{2, 1, 2}, padding: K2C_PADDING_SAME,
+			data_format: K2C_CHANNELS_FIRST, average: true,
+			input:    []float64{-1.0, -1.25, 2.0, 1.75, 1.5, 1.25, 1.0, 0.75, 0.5, 0.625, 0.375, 0.125, -0.125, -0.375, 2.875, 2.625, 2.375, 2.125},
+			expected: []float64{0.25, 1.625, 0.875, 0.5, 0.125, 1.5, 2.5, 2.125},
+		},
+	})
+}
@@ -0,0 +1,421 @@
+package keras2go
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+* Rescaling layer.
+* computes input*scale + offset.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param scale: tensor of scales, either a single value or one value per element of the last axis.
+* :param offset: tensor of offsets, either a single value or one value per element of the last axis.
+*/
+func K2c_rescaling(output *K2c_tensor, input *K2c_tensor, scale *K2c_tensor, offset *K2c_tensor) {
+	for i := 0; i < input.Numel; i++ {
+		output.Array[i] = input.Array[i]*scale.Array[i%scale.Numel] + offset.Array[i%offset.Numel]
+	}
+}
+
+/**
+* Normalization layer, with the mean and variance found when adapting it.
+* computes (input - mean) / max(sqrt(variance), epsilon), or the inverse.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param mean: tensor of means, broadcast over the trailing axes of the input.
+* :param variance: tensor of variances, same Shape as mean.
+* :param invert: whether to normalize the input (0) or undo the normalization (1).
+*/
+func K2c_normalization(output *K2c_tensor, input *K2c_tensor, mean *K2c_tensor, variance *K2c_tensor, invert int) {
+	const epsilon = 1e-7
+	for i := 0; i < input.Numel; i++ {
+		var j = i % mean.Numel
+		var std = math.Max(math.Sqrt(variance.Array[j]), epsilon)
+		if invert != 0 {
+			output.Array[i] = mean.Array[j] + input.Array[i]*std
+		} else {
+			output.Array[i] = (input.Array[i] - mean.Array[j]) / std
+		}
+	}
+}
+
+/**
+* Resizing layer.
+* Assumes a "channels last" structure, ie input Shape is {rows, cols, channels}.
+*
+* :param output: output tensor, Shape is {height, width, channels}.
+* :param input: input tensor.
+* :param interpolation: interpolation mode, one of K2C_INTERPOLATION_*.
+* :param crop_to_aspect_ratio: whether to first crop the largest centered window of the input with the aspect ratio of
+*     the output (1), or to resize all of the input (0).
+*/
+func K2c_resizing(output *K2c_tensor, input *K2c_tensor, interpolation int, crop_to_aspect_ratio int) {
+	if crop_to_aspect_ratio == 0 {
+		k2c_resize(output, input, nil, nil, interpolation)
+		return
+	}
+	var in_rows = input.Shape[0]
+	var in_cols = input.Shape[1]
+	// same rounding as keras smart_resize
+	var crop_rows = in_cols * output.Shape[0] / output.Shape[1]
+	var crop_cols = in_rows * output.Shape[1] / output.Shape[0]
+	if crop_rows > in_rows {
+		crop_rows = in_rows
+	}
+	if crop_cols > in_cols {
+		crop_cols = in_cols
+	}
+	var window_start = []int{(in_rows - crop_rows) / 2, (in_cols - crop_cols) / 2}
+	var window_shape = []int{crop_rows, crop_cols}
+	k2c_resize(output, input, window_start, window_shape, interpolation)
+}
+
+/**
+* CenterCrop layer.
+* crops the center of the input to the Shape of the output. Inputs smaller than the output are resized instead,
+* like keras does.
+*
+* :param output: output tensor, Shape is {height, width, channels}.
+* :param input: input tensor, Shape is {rows, cols, channels}.
+*/
+func K2c_center_crop(output *K2c_tensor, input *K2c_tensor) {
+	var in_rows = input.Shape[0]
+	var in_cols = input.Shape[1]
+	var out_rows = output.Shape[0]
+	var out_cols = output.Shape[1]
+	var channels = input.Shape[2]
+
+	if in_rows < out_rows || in_cols < out_cols {
+		K2c_resizing(output, input, K2C_INTERPOLATION_BILINEAR, 1)
+		return
+	}
+	var start_row = (in_rows - out_rows) / 2
+	var start_col = (in_cols - out_cols) / 2
+	var num = out_cols * channels
+	for i := 0; i < out_rows; i++ {
+		var inIdx = ((start_row+i)*in_cols + start_col) * channels
+		copy(output.Array[i*num:(i+1)*num], input.Array[inIdx:inIdx+num])
+	}
+}
+
+/**
+* Reads a vocabulary file, as exported in the assets of a keras model.
+* like keras, tokens can not contain a newline. Generated models have their vocabulary in the code instead.
+*
+* :param filename: file with one token per line.
+* :return: tokens of the vocabulary, in order.
+*/
+func K2c_load_vocabulary(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var text = strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+/**
+* Vocabulary of a lookup layer, ie StringLookup, IntegerLookup or TextVectorization.
+* indices are laid out like keras does: the mask token first if there is one, then the OOV indices, then the
+* vocabulary.
+*/
+type K2c_lookup struct {
+	index           map[string]int
+	num_oov_indices int
+	oov_start       int
+	mask_token      string
+	use_mask_token  int
+	size            int
+}
+
+/**
+* Makes the lookup of a vocabulary.
+*
+* :param vocabulary: tokens of the vocabulary, without the mask and OOV tokens. Integers are in decimal.
+* :param num_oov_indices: number of indices of out of vocabulary tokens.
+* :param mask_token: token mapped to index 0.
+* :param use_mask_token: whether there is a mask token (1) or not (0).
+* :return: lookup of the vocabulary.
+*/
+func K2c_new_lookup(vocabulary []string, num_oov_indices int, mask_token string, use_mask_token int) *K2c_lookup {
+	var lookup = &K2c_lookup{index: make(map[string]int, len(vocabulary)), num_oov_indices: num_oov_indices,
+		mask_token: mask_token, use_mask_token: use_mask_token}
+	if use_mask_token != 0 {
+		lookup.oov_start = 1
+	}
+	var vocab_start = lookup.oov_start + num_oov_indices
+	for i, token := range vocabulary {
+		lookup.index[token] = vocab_start + i
+	}
+	lookup.size = vocab_start + len(vocabulary)
+	return lookup
+}
+
+/**
+* Number of indices, including the mask and OOV indices.
+*/
+func (lookup *K2c_lookup) Size() int {
+	return lookup.size
+}
+
+/**
+* Index of a token. OOV tokens are hashed into the OOV indices like tf.strings.to_hash_bucket_fast, and get -1
+* if there are none.
+*/
+func (lookup *K2c_lookup) string_index(token string) int {
+	if lookup.use_mask_token != 0 && token == lookup.mask_token {
+		return 0
+	}
+	if idx, ok := lookup.index[token]; ok {
+		return idx
+	}
+	if lookup.num_oov_indices == 0 {
+		return -1
+	}
+	return lookup.oov_start + int(k2c_fingerprint64(token)%uint64(lookup.num_oov_indices))
+}
+
+/**
+* Index of an integer token. OOV integers get the OOV index of their value modulo the number of OOV indices.
+*/
+func (lookup *K2c_lookup) integer_index(value int64) int {
+	var token = strconv.FormatInt(value, 10)
+	if lookup.use_mask_token != 0 && token == lookup.mask_token {
+		return 0
+	}
+	if idx, ok := lookup.index[token]; ok {
+		return idx
+	}
+	if lookup.num_oov_indices == 0 {
+		return -1
+	}
+	var n = int64(lookup.num_oov_indices)
+	return lookup.oov_start + int((value%n+n)%n)
+}
+
+/**
+* StringLookup layer, with output mode "int".
+* other output modes are K2c_category_encoding of the indices.
+*
+* :param output: output tensor of indices, same size as input.
+* :param input: Array of tokens.
+* :param lookup: vocabulary of the layer.
+*/
+func K2c_string_lookup(output *K2c_tensor, input []string, lookup *K2c_lookup) {
+	for i, token := range input {
+		output.Array[i] = float64(lookup.string_index(token))
+	}
+}
+
+/**
+* IntegerLookup layer, with output mode "int".
+* other output modes are K2c_category_encoding of the indices.
+*
+* :param output: output tensor of indices.
+* :param input: input tensor of integers.
+* :param lookup: vocabulary of the layer.
+*/
+func K2c_integer_lookup(output *K2c_tensor, input *K2c_tensor, lookup *K2c_lookup) {
+	for i := 0; i < input.Numel; i++ {
+		output.Array[i] = float64(lookup.integer_index(int64(input.Array[i])))
+	}
+}
+
+/**
+* Hashing layer for string inputs.
+* uses farmhash Fingerprint64 without a salt and SipHash with one, so the bins match keras.
+*
+* :param output: output tensor of bins, same size as input.
+* :param input: Array of strings to hash.
+* :param num_bins: number of bins, including the mask bin.
+* :param mask_value: value that goes to bin 0.
+* :param use_mask_value: whether there is a mask value (1) or not (0).
+* :param salt: Array[2] key of the hash, or nil to hash without a salt.
+*/
+func K2c_hash_strings(output *K2c_tensor, input []string, num_bins int, mask_value string, use_mask_value int, salt []uint64) {
+	var bins = num_bins
+	var first = 0
+	if use_mask_value != 0 && num_bins > 1 {
+		bins--
+		first = 1
+	}
+	for i, value := range input {
+		if first == 1 && value == mask_value {
+			output.Array[i] = 0
+			continue
+		}
+		var hash uint64
+		if salt != nil {
+			hash = k2c_siphash(salt, value)
+		} else {
+			hash = k2c_fingerprint64(value)
+		}
+		output.Array[i] = float64(first + int(hash%uint64(bins)))
+	}
+}
+
+/**
+* Hashing layer for integer inputs.
+* integers are hashed as their decimal strings, like keras does.
+*
+* :param output: output tensor of bins.
+* :param input: input tensor of integers.
+* :param num_bins: number of bins, including the mask bin.
+* :param mask_value: value that goes to bin 0.
+* :param use_mask_value: whether there is a mask value (1) or not (0).
+* :param salt: Array[2] key of the hash, or nil to hash without a salt.
+*/
+func K2c_hashing(output *K2c_tensor, input *K2c_tensor, num_bins int, mask_value float64, use_mask_value int, salt []uint64) {
+	var values = make([]string, input.Numel)
+	for i := range values {
+		values[i] = strconv.FormatInt(int64(input.Array[i]), 10)
+	}
+	K2c_hash_strings(output, values, num_bins, strconv.FormatInt(int64(mask_value), 10), use_mask_value, salt)
+}
+
+/**
+* CategoryEncoding layer.
+* the number of tokens is the size of the last axis of the output.
+*
+* :param output: output tensor.
+* :param input: input tensor of token indices.
+* :param output_mode: one of K2C_ENCODING_*.
+* :param idf_weights: tensor of num_tokens weights for K2C_ENCODING_TF_IDF, or nil.
+* :return: error for a token outside [0, num_tokens), like keras.
+*/
+func K2c_category_encoding(output *K2c_tensor, input *K2c_tensor, output_mode int, idf_weights *K2c_tensor) error {
+	var num_tokens = output.Shape[output.Ndim-1]
+	var row_length = input.Shape[input.Ndim-1]
+	if output_mode == K2C_ENCODING_ONE_HOT {
+		row_length = 1
+	}
+	output.fillFloat64(0)
+	for i := 0; i < input.Numel; i++ {
+		var token = int(input.Array[i])
+		if token < 0 || token >= num_tokens {
+			return fmt.Errorf("keras2go: category encoding input[%d] = %v is not in [0, %d)", i, input.Array[i], num_tokens)
+		}
+		var row = output.Array[(i/row_length)*num_tokens : (i/row_length+1)*num_tokens]
+		if output_mode == K2C_ENCODING_COUNT || output_mode == K2C_ENCODING_TF_IDF {
+			row[token]++
+		} else {
+			row[token] = 1
+		}
+	}
+	if output_mode == K2C_ENCODING_TF_IDF {
+		for i := 0; i < output.Numel; i++ {
+			output.Array[i] *= idf_weights.Array[i%num_tokens]
+		}
+	}
+	return nil
+}
+
+/**
+* TextVectorization layer.
+* each input text is standardized, split into tokens, extended with n-grams and looked up in the vocabulary.
+*
+* :param output: output tensor, with one row per input text. In output mode "int" the rows hold the token indices,
+*     padded with 0 or truncated to the row size. Otherwise they hold the encoding of the indices.
+* :param input: Array of texts.
+* :param lookup: vocabulary of the layer.
+* :param standardize: one of K2C_STANDARDIZE_*.
+* :param split: one of K2C_SPLIT_*.
+* :param ngrams: Array of the n-gram widths to produce, eg {1, 2}.
+* :param output_mode: -1 for "int", or one of K2C_ENCODING_*.
+* :param idf_weights: tensor of Size() weights for K2C_ENCODING_TF_IDF, or nil.
+* :return: error of K2c_category_encoding, for a token without an index.
+*/
+func K2c_text_vectorization(output *K2c_tensor, input []string, lookup *K2c_lookup, standardize int, split int,
+	ngrams []int, output_mode int, idf_weights *K2c_tensor) error {
+	if len(input) == 0 {
+		return nil
+	}
+	var row_size = output.Numel / len(input)
+	for i, text := range input {
+		var tokens = k2c_ngrams(k2c_split_text(k2c_standardize_text(text, standardize), split), ngrams)
+		var row = K2c_tensor{Array: output.Array[i*row_size : (i+1)*row_size], Ndim: 1, Numel: row_size,
+			Shape: [K2C_MAX_NDIM]int{row_size, 1, 1, 1, 1}}
+		row.fillFloat64(0)
+		if output_mode < 0 {
+			for j := 0; j < len(tokens) && j < row_size; j++ {
+				row.Array[j] = float64(lookup.string_index(tokens[j]))
+			}
+			continue
+		}
+		var indices = K2c_tensor{Array: make([]float64, len(tokens)), Ndim: 1, Numel: len(tokens),
+			Shape: [K2C_MAX_NDIM]int{len(tokens), 1, 1, 1, 1}}
+		K2c_string_lookup(&indices, tokens, lookup)
+		if err := K2c_category_encoding(&row, &indices, output_mode, idf_weights); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+* Standardizes a text like tf.strings.lower and the punctuation stripping of TextVectorization.
+*/
+func k2c_standardize_text(text string, standardize int) string {
+	if standardize == K2C_STANDARDIZE_LOWER || standardize == K2C_STANDARDIZE_LOWER_AND_STRIP_PUNCTUATION {
+		text = strings.Map(func(r rune) rune {
+			if r >= 'A' && r <= 'Z' {
+				return r + 'a' - 'A'
+			}
+			return r
+		}, text)
+	}
+	if standardize == K2C_STANDARDIZE_STRIP_PUNCTUATION || standardize == K2C_STANDARDIZE_LOWER_AND_STRIP_PUNCTUATION {
+		text = strings.Map(func(r rune) rune {
+			if strings.ContainsRune("!\"#$%&()*+,-./:;<=>?@[\\]^_`{|}~'", r) {
+				return -1
+			}
+			return r
+		}, text)
+	}
+	return text
+}
+
+/**
+* Splits a text into tokens like tf.strings.split and tf.strings.unicode_split.
+*/
+func k2c_split_text(text string, split int) []string {
+	switch split {
+	case K2C_SPLIT_WHITESPACE:
+		return strings.FieldsFunc(text, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == '\n' || r == '\v' || r == '\f' || r == '\r'
+		})
+	case K2C_SPLIT_CHARACTER:
+		var tokens []string
+		for _, r := range text {
+			tokens = append(tokens, string(r))
+		}
+		return tokens
+	}
+	return []string{text}
+}
+
+/**
+* Joins the tokens into n-grams like tf.strings.ngrams, for each width in turn.
+*/
+func k2c_ngrams(tokens []string, widths []int) []string {
+	if len(widths) == 1 && widths[0] == 1 {
+		return tokens
+	}
+	var ngrams []string
+	for _, width := range widths {
+		for i := 0; i+width <= len(tokens); i++ {
+			ngrams = append(ngrams, strings.Join(tokens[i:i+width], " "))
+		}
+	}
+	return ngrams
+}
@@ -0,0 +1,147 @@
+package keras2go
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestK2c_normalization(t *testing.T) {
+	input := newTestTensor([]int{2, 3})
+	input.Array = []float64{3, 2, 3.5, -1, 2.5, 2}
+	mean := newTestTensor([]int{3})
+	mean.Array = []float64{1, 2, 3}
+	variance := newTestTensor([]int{3})
+	variance.Array = []float64{4, 0, 0.25}
+	output := newTestTensor([]int{2, 3})
+
+	// a variance of zero divides by epsilon
+	K2c_normalization(output, input, mean, variance, 0)
+	checkArray(t, output.Array, []float64{1, 0, 1, -1, 0.5 / 1e-7, -2})
+
+	inverted := newTestTensor([]int{2, 3})
+	K2c_normalization(inverted, output, mean, variance, 1)
+	for i := range input.Array {
+		if d := inverted.Array[i] - input.Array[i]; d > 1e-12 || d < -1e-12 {
+			t.Fatalf("got %v, expected %v", inverted.Array, input.Array)
+		}
+	}
+}
+
+func TestK2c_resizing(t *testing.T) {
+	input := newTestTensor([]int{4, 6, 1})
+	for i := range input.Array {
+		input.Array[i] = float64(i)
+	}
+	output := newTestTensor([]int{2, 2, 1})
+
+	K2c_resizing(output, input, K2C_INTERPOLATION_NEAREST, 0)
+	checkArray(t, output.Array, []float64{7, 10, 19, 22})
+
+	// crops the centered 4x4 window first
+	K2c_resizing(output, input, K2C_INTERPOLATION_NEAREST, 1)
+	checkArray(t, output.Array, []float64{8, 10, 20, 22})
+
+	// the crop keeps the aspect ratio of the output
+	wide := newTestTensor([]int{2, 4, 1})
+	expected := newTestTensor([]int{2, 4, 1})
+	K2c_resizing(wide, input, K2C_INTERPOLATION_BILINEAR, 1)
+	k2c_resize(expected, input, []int{0, 0}, []int{3, 6}, K2C_INTERPOLATION_BILINEAR)
+	checkArray(t, wide.Array, expected.Array)
+}
+
+func TestK2c_center_crop(t *testing.T) {
+	input := newTestTensor([]int{4, 5, 2})
+	for i := range input.Array {
+		input.Array[i] = float64(i)
+	}
+	output := newTestTensor([]int{2, 3, 2})
+	K2c_center_crop(output, input)
+	checkArray(t, output.Array, []float64{12, 13, 14, 15, 16, 17, 22, 23, 24, 25, 26, 27})
+
+	// inputs smaller than the output are resized like keras smart_resize, here from their first row
+	small := newTestTensor([]int{2, 2, 1})
+	small.Array = []float64{0, 1, 2, 3}
+	output = newTestTensor([]int{3, 4, 1})
+	K2c_center_crop(output, small)
+	checkArray(t, output.Array, []float64{0, 0.25, 0.75, 1, 0, 0.25, 0.75, 1, 0, 0.25, 0.75, 1})
+}
+
+func TestK2c_text_vectorization(t *testing.T) {
+	// index 0 is the mask, 1 the OOV index, then the vocabulary
+	var lookup = K2c_new_lookup([]string{"a", "b", "line\nbreak"}, 1, "", 1)
+	output := newTestTensor([]int{3, 3})
+	if err := K2c_text_vectorization(output, []string{"b A c", "a", "line\nbreak"}, lookup, K2C_STANDARDIZE_LOWER,
+		K2C_SPLIT_NONE, []int{1}, -1, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, output.Array, []float64{1, 0, 0, 2, 0, 0, 4, 0, 0})
+
+	if err := K2c_text_vectorization(output, []string{"b A c", "a", "b b"}, lookup, K2C_STANDARDIZE_LOWER,
+		K2C_SPLIT_WHITESPACE, []int{1}, -1, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, output.Array, []float64{3, 2, 1, 2, 0, 0, 3, 3, 0})
+
+	// no texts
+	empty := newTestTensor([]int{0, 3})
+	if err := K2c_text_vectorization(empty, nil, lookup, K2C_STANDARDIZE_LOWER, K2C_SPLIT_WHITESPACE, []int{1}, -1,
+		nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// without OOV indices an unknown token has no bin
+	var strict = K2c_new_lookup([]string{"a", "b"}, 0, "", 0)
+	counts := newTestTensor([]int{1, 2})
+	if err := K2c_text_vectorization(counts, []string{"a b a"}, strict, K2C_STANDARDIZE_NONE, K2C_SPLIT_WHITESPACE,
+		[]int{1}, K2C_ENCODING_COUNT, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, counts.Array, []float64{2, 1})
+	if err := K2c_text_vectorization(counts, []string{"a c"}, strict, K2C_STANDARDIZE_NONE, K2C_SPLIT_WHITESPACE,
+		[]int{1}, K2C_ENCODING_COUNT, nil); err == nil {
+		t.Fatal("expected an error for token c")
+	}
+}
+
+func TestK2c_category_encoding(t *testing.T) {
+	input := newTestTensor([]int{2, 3})
+	input.Array = []float64{0, 2, 2, 1, 3, 0}
+	output := newTestTensor([]int{2, 4})
+	idf_weights := newTestTensor([]int{4})
+	idf_weights.Array = []float64{0.5, 1, 2, 4}
+	tests := []struct {
+		output_mode int
+		expected    []float64
+	}{
+		{K2C_ENCODING_MULTI_HOT, []float64{1, 0, 1, 0, 1, 1, 0, 1}},
+		{K2C_ENCODING_COUNT, []float64{1, 0, 2, 0, 1, 1, 0, 1}},
+		{K2C_ENCODING_TF_IDF, []float64{0.5, 0, 4, 0, 0.5, 1, 0, 4}},
+	}
+	for _, test := range tests {
+		if err := K2c_category_encoding(output, input, test.output_mode, idf_weights); err != nil {
+			t.Fatal(err)
+		}
+		checkArray(t, output.Array, test.expected)
+	}
+
+	// one row per value
+	one_hot := newTestTensor([]int{3, 4})
+	column := newTestTensor([]int{3, 1})
+	column.Array = []float64{3, 0, 1}
+	if err := K2c_category_encoding(one_hot, column, K2C_ENCODING_ONE_HOT, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, one_hot.Array, []float64{0, 0, 0, 1, 1, 0, 0, 0, 0, 1, 0, 0})
+
+	// keras rejects tokens outside [0, num_tokens)
+	for _, value := range []float64{4, -1} {
+		input.Array[4] = value
+		err := K2c_category_encoding(output, input, K2C_ENCODING_COUNT, nil)
+		if err == nil {
+			t.Fatal("expected an error for token", value)
+		}
+		if expected := fmt.Sprintf("keras2go: category encoding input[4] = %v is not in [0, 4)", value); err.Error() != expected {
+			t.Fatalf("got %q, expected %q", err, expected)
+		}
+	}
+}
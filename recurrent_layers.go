@@ -1,5 +1,91 @@
 package keras2go
 
+/**
+* Recurrent cell, run over the timesteps of a sequence by K2c_rnn.
+* the state holds everything the cell carries from one timestep to the next, and the output of the cell is a part
+* of its state.
+*/
+type K2c_rnn_cell interface {
+	// number of values in the state
+	StateSize() int
+	// output of the cell, a slice of state
+	Output(state []float64) []float64
+	// advances state by one timestep
+	Step(state []float64, input []float64)
+}
+
+/**
+* Generic recurrent layer, runs any cell over the timesteps of the input.
+*
+* :param output: output tensor.
+* :param input: input tensor, Shape is {timesteps, features}.
+* :param mask: Array[timesteps] of which timesteps to process, or nil to process all of them.
+* :param state: Array[cell.StateSize()] recurrent state. holds the initial state when called and the final state on return.
+* :param cell: cell to run at each timestep.
+* :param go_backwards: whether to process input sequences forwards (0) or backwards (1).
+* :param return_sequences: whether to return the last output in the output sequence (0), or the full sequence (1).
+*/
+func K2c_rnn(output *K2c_tensor, input *K2c_tensor, mask []bool, state []float64, cell K2c_rnn_cell, go_backwards int, return_sequences int) {
+	k2c_rnn_loop(output, input, mask, state, cell.Step, cell.Output, go_backwards, return_sequences)
+}
+
+/**
+* Runs the step of a cell over the timesteps of the input, see K2c_rnn.
+* takes the methods of the cell rather than the cell, so that K2c_lstm, K2c_gru and K2c_simpleRNN do not allocate a cell on each call.
+*/
+func k2c_rnn_loop(output *K2c_tensor, input *K2c_tensor, mask []bool, state []float64, step func(state []float64, input []float64), cell_output func(state []float64) []float64, go_backwards int, return_sequences int) {
+	var in_height = input.Shape[0]
+	var in_width = input.Shape[1]
+	var units = len(cell_output(state))
+
+	for i := 0; i < in_height; i++ {
+		var t = i
+		if go_backwards != 0 {
+			t = in_height - 1 - i
+		}
+		// masked timesteps carry the state forward
+		if mask == nil || mask[t] {
+			step(state, input.Array[t*in_width:(t+1)*in_width])
+		}
+		if return_sequences != 0 {
+			copy(output.Array[i*units:(i+1)*units], cell_output(state))
+		}
+	}
+	if return_sequences == 0 {
+		copy(output.Array[:units], cell_output(state))
+	}
+}
+
+/**
+* Stack of cells run as a single cell, like keras StackedRNNCells.
+* each cell gets the output of the previous one as input. The state is the states of the cells one after the
+* other, and the output is the output of the last cell.
+*/
+type K2c_stacked_rnn_cells []K2c_rnn_cell
+
+func (cells K2c_stacked_rnn_cells) StateSize() int {
+	var size = 0
+	for _, cell := range cells {
+		size += cell.StateSize()
+	}
+	return size
+}
+
+func (cells K2c_stacked_rnn_cells) Output(state []float64) []float64 {
+	var last = cells[len(cells)-1]
+	return last.Output(state[len(state)-last.StateSize():])
+}
+
+func (cells K2c_stacked_rnn_cells) Step(state []float64, input []float64) {
+	var offset = 0
+	for _, cell := range cells {
+		var cell_state = state[offset : offset+cell.StateSize()]
+		cell.Step(cell_state, input)
+		input = cell.Output(cell_state)
+		offset += len(cell_state)
+	}
+}
+
 func k2c_lstmcell(state []float64, input []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, recurrent_activation k2c_activationType, output_activation k2c_activationType) {
 	var units = recurrent_kernel.Shape[1]
 	var in_width = kernel.Shape[0] / 4
@@ -70,37 +156,51 @@ func k2c_lstmcell(state []float64, input []float64, kernel *K2c_tensor, recurren
 	}
 }
 
-func K2c_lstm(output *K2c_tensor, input *K2c_tensor, state []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, go_backwards int, return_sequences int, recurrent_activation k2c_activationType, output_activation k2c_activationType) {
-	var in_height = input.Shape[0]
-	var in_width = input.Shape[1]
-	var units = recurrent_kernel.Shape[1]
+/**
+* Cell of the LSTM layer, see k2c_lstmcell.
+*/
+type K2c_lstm_cell struct {
+	Kernel              *K2c_tensor
+	RecurrentKernel     *K2c_tensor
+	Bias                *K2c_tensor
+	Fwork               []float64 // Array[8*units] working storage
+	RecurrentActivation k2c_activationType
+	OutputActivation    k2c_activationType
+}
 
-	if go_backwards != 0 {
-		for i := in_height - 1; i > -1; i-- {
-			k2c_lstmcell(state, input.Array[i*in_width:], kernel, recurrent_kernel,
-				bias, fwork, recurrent_activation, output_activation)
-			if return_sequences != 0 {
-				for j := 0; j < units; j++ {
-					output.Array[(in_height-1-i)*units+j] = state[j]
-				}
-			}
-		}
-	} else {
-		for i := 0; i < in_height; i++ {
-			k2c_lstmcell(state, input.Array[i*in_width:], kernel, recurrent_kernel,
-				bias, fwork, recurrent_activation, output_activation)
-			if return_sequences != 0 {
-				for j := 0; j < units; j++ {
-					output.Array[i*units+j] = state[j]
-				}
-			}
-		}
-	}
-	if return_sequences == 0 {
-		for i := 0; i < units; i++ {
-			output.Array[i] = state[i]
-		}
-	}
+func (cell *K2c_lstm_cell) StateSize() int {
+	return 2 * cell.RecurrentKernel.Shape[1]
+}
+
+func (cell *K2c_lstm_cell) Output(state []float64) []float64 {
+	return state[:cell.RecurrentKernel.Shape[1]]
+}
+
+func (cell *K2c_lstm_cell) Step(state []float64, input []float64) {
+	k2c_lstmcell(state, input, cell.Kernel, cell.RecurrentKernel, cell.Bias, cell.Fwork, cell.RecurrentActivation, cell.OutputActivation)
+}
+
+/**
+* Long Short-Term Memory layer.
+* "units" is the dimension of the output space
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param mask: Array[timesteps] of which timesteps to process, or nil to process all of them.
+* :param state: Array[2*units] recurrent state, {h, c}. holds the initial state when called and the final state on return.
+* :param kernel: kernel tensor.
+* :param recurrent_kernel: recurrent kernel tensor
+* :param bias: bias tensor.
+* :param fwork: Array[8*units] working storage.
+* :param go_backwards: whether to process input sequences forwards (0) or backwards (1).
+* :param return_sequences: whether to return the last output in the output sequence (0), or the full sequence (1).
+* :param recurrent_activation: activation function to apply to internal state.
+* :param output_activation: activation function to apply to output.
+*/
+func K2c_lstm(output *K2c_tensor, input *K2c_tensor, mask []bool, state []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, go_backwards int, return_sequences int, recurrent_activation k2c_activationType, output_activation k2c_activationType) {
+	var cell = K2c_lstm_cell{Kernel: kernel, RecurrentKernel: recurrent_kernel, Bias: bias,
+		Fwork: fwork, RecurrentActivation: recurrent_activation, OutputActivation: output_activation}
+	k2c_rnn_loop(output, input, mask, state, cell.Step, cell.Output, go_backwards, return_sequences)
 }
 
 
@@ -135,13 +235,37 @@ func k2c_simpleRNNcell(state []float64, input []float64, kernel *K2c_tensor, rec
 	}
 }
 
+/**
+* Cell of the SimpleRNN layer, see k2c_simpleRNNcell.
+*/
+type K2c_simpleRNN_cell struct {
+	Kernel           *K2c_tensor
+	RecurrentKernel  *K2c_tensor
+	Bias             *K2c_tensor
+	Fwork            []float64 // Array[2*units] working storage
+	OutputActivation k2c_activationType
+}
+
+func (cell *K2c_simpleRNN_cell) StateSize() int {
+	return cell.RecurrentKernel.Shape[1]
+}
+
+func (cell *K2c_simpleRNN_cell) Output(state []float64) []float64 {
+	return state[:cell.RecurrentKernel.Shape[1]]
+}
+
+func (cell *K2c_simpleRNN_cell) Step(state []float64, input []float64) {
+	k2c_simpleRNNcell(state, input, cell.Kernel, cell.RecurrentKernel, cell.Bias, cell.Fwork, cell.OutputActivation)
+}
+
 /**
 * Fully-connected RNN where the output is to be fed back to input.
 * "units" is the dimension of the output space
 *
 * :param output: output tensor.
 * :param input: input tensor.
-* :param state: Array[units] recurrent state.
+* :param mask: Array[timesteps] of which timesteps to process, or nil to process all of them.
+* :param state: Array[units] recurrent state. holds the initial state when called and the final state on return.
 * :param kernel: kernel tensor.
 * :param recurrent_kernel: recurrent kernel tensor
 * :param bias: bias tensor.
@@ -150,35 +274,10 @@ func k2c_simpleRNNcell(state []float64, input []float64, kernel *K2c_tensor, rec
 * :param return_sequences: whether to return the last output in the output sequence (0), or the full sequence (1).
 * :param output_activation: activation function to apply to output.
 */
-func k2c_simpleRNN(output *K2c_tensor, input *K2c_tensor, state []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, go_backwards int, return_sequences int, output_activation k2c_activationType) {
-	var in_width = input.Shape[1]
-	var in_height = input.Shape[0]
-	var units = recurrent_kernel.Shape[1]
-
-	if go_backwards != 0 {
-		for i := in_height - 1; i > -1; i-- {
-			k2c_simpleRNNcell(state, input.Array[i*in_width:], kernel, recurrent_kernel, bias, fwork, output_activation)
-			if return_sequences != 0 {
-				for j := 0; j < units; j++ {
-					output.Array[(in_height-1-i)*units+j] = state[j]
-				}
-			}
-		}
-	} else {
-		for i := 0; i < in_height; i++ {
-			k2c_simpleRNNcell(state, input.Array[i*in_width:], kernel, recurrent_kernel, bias, fwork, output_activation)
-			if return_sequences != 0 {
-				for j := 0; j < units; j++ {
-					output.Array[i*units+j] = state[j]
-				}
-			}
-		}
-	}
-	if return_sequences == 0 {
-		for i := 0; i < units; i++ {
-			output.Array[i] = state[i]
-		}
-	}
+func K2c_simpleRNN(output *K2c_tensor, input *K2c_tensor, mask []bool, state []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, go_backwards int, return_sequences int, output_activation k2c_activationType) {
+	var cell = K2c_simpleRNN_cell{Kernel: kernel, RecurrentKernel: recurrent_kernel, Bias: bias,
+		Fwork: fwork, OutputActivation: output_activation}
+	k2c_rnn_loop(output, input, mask, state, cell.Step, cell.Output, go_backwards, return_sequences)
 }
 
 
@@ -271,33 +370,197 @@ func k2c_grucell(state []float64, input []float64, kernel *K2c_tensor, recurrent
 	}
 }
 
-func K2c_gru(output *K2c_tensor, input *K2c_tensor, state []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, reset_after int, go_backwards int, return_sequences int, recurrent_activation k2c_activationType, output_activation k2c_activationType) {
-	var in_width = input.Shape[1]
+/**
+* Cell of the GRU layer, see k2c_grucell.
+*/
+type K2c_gru_cell struct {
+	Kernel              *K2c_tensor
+	RecurrentKernel     *K2c_tensor
+	Bias                *K2c_tensor
+	Fwork               []float64 // Array[6*units] working storage
+	ResetAfter          int
+	RecurrentActivation k2c_activationType
+	OutputActivation    k2c_activationType
+}
+
+func (cell *K2c_gru_cell) StateSize() int {
+	return cell.RecurrentKernel.Shape[1]
+}
+
+func (cell *K2c_gru_cell) Output(state []float64) []float64 {
+	return state[:cell.RecurrentKernel.Shape[1]]
+}
+
+func (cell *K2c_gru_cell) Step(state []float64, input []float64) {
+	k2c_grucell(state, input, cell.Kernel, cell.RecurrentKernel, cell.Bias, cell.Fwork, cell.ResetAfter, cell.RecurrentActivation, cell.OutputActivation)
+}
+
+/**
+* Gated Recurrent Unit layer.
+* "units" is the dimension of the output space
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param mask: Array[timesteps] of which timesteps to process, or nil to process all of them.
+* :param state: Array[units] recurrent state. holds the initial state when called and the final state on return.
+* :param kernel: kernel tensor.
+* :param recurrent_kernel: recurrent kernel tensor
+* :param bias: bias tensor.
+* :param fwork: Array[6*units] working storage.
+* :param reset_after: whether to apply the reset gate before (0) or after (1) the matrix multiplication.
+* :param go_backwards: whether to process input sequences forwards (0) or backwards (1).
+* :param return_sequences: whether to return the last output in the output sequence (0), or the full sequence (1).
+* :param recurrent_activation: activation function to apply to internal state.
+* :param output_activation: activation function to apply to output.
+*/
+func K2c_gru(output *K2c_tensor, input *K2c_tensor, mask []bool, state []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, reset_after int, go_backwards int, return_sequences int, recurrent_activation k2c_activationType, output_activation k2c_activationType) {
+	var cell = K2c_gru_cell{Kernel: kernel, RecurrentKernel: recurrent_kernel, Bias: bias,
+		Fwork: fwork, ResetAfter: reset_after, RecurrentActivation: recurrent_activation, OutputActivation: output_activation}
+	k2c_rnn_loop(output, input, mask, state, cell.Step, cell.Output, go_backwards, return_sequences)
+}
+
+/**
+* Cell for the ConvLSTM2D layer.
+* Gates are ordered {input, forget, cell, output} along the last axis of the kernels, as in k2c_lstmcell.
+* "filters" is the number of channels of the state.
+*
+* :param state: Array[2*out_rows*out_cols*filters] recurrent state, {h, c}.
+* :param input: input tensor for one timestep.
+* :param kernel: kernel tensor, Shape is {rows, cols, in_channels, 4*filters}.
+* :param recurrent_kernel: recurrent kernel tensor, Shape is {rows, cols, filters, 4*filters}.
+* :param bias: bias tensor.
+* :param fwork: Array[8*out_rows*out_cols*filters] working storage.
+* :param stride: Array[2] of stride length of the input convolution.
+* :param dilation: Array[2] dilation rate of the input convolution.
+* :param padding: padding mode of the input convolution, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param recurrent_activation: activation function to apply to internal state.
+* :param output_activation: activation function to apply to output.
+ */
+func k2c_conv_lstm2d_cell(state []float64, input *K2c_tensor, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, stride []int, dilation []int, padding int, recurrent_activation k2c_activationType, output_activation k2c_activationType) {
+	var filters = recurrent_kernel.Shape[2]
+	var out_rows = K2c_conv_output_length(input.Shape[0], kernel.Shape[0], padding, stride[0], dilation[0])
+	var out_cols = K2c_conv_output_length(input.Shape[1], kernel.Shape[1], padding, stride[1], dilation[1])
+	var positions = out_rows * out_cols
+	var units = positions * filters
+	var one = []int{1, 1}
+
+	var h_tm1 = K2c_tensor{Array: state[:units], Ndim: 3, Numel: units, Shape: [K2C_MAX_NDIM]int{out_rows, out_cols, filters, 1, 1}}
+	var c_tm1 = state[units : 2*units]
+	var x = K2c_tensor{Array: fwork[:4*units], Ndim: 3, Numel: 4 * units, Shape: [K2C_MAX_NDIM]int{out_rows, out_cols, 4 * filters, 1, 1}}
+	var h = K2c_tensor{Array: fwork[4*units : 8*units], Ndim: 3, Numel: 4 * units, Shape: x.Shape}
+
+	// x = conv(input, kernel) + bias, h = conv(h_tm1, recurrent_kernel)
+	x.fillFloat64(0)
+	k2c_conv2d_accumulate(&x, input, kernel, stride, dilation, padding, 1)
+	k2c_bias_add(&x, bias)
+	h.fillFloat64(0)
+	k2c_conv2d_accumulate(&h, &h_tm1, recurrent_kernel, one, one, K2C_PADDING_SAME, 1)
+
+	for p := 0; p < positions; p++ {
+		var gates = x.Array[p*4*filters : (p+1)*4*filters]
+		for j := range gates {
+			gates[j] += h.Array[p*4*filters+j]
+		}
+		// i and f are next to each other
+		recurrent_activation(gates[:2*filters])
+		output_activation(gates[2*filters : 3*filters])
+		recurrent_activation(gates[3*filters:])
+		var yi = gates
+		var yf = gates[filters:]
+		var yc = gates[2*filters:]
+		var yo = gates[3*filters:]
+		// c = f.*c_tm1 + i.*output_activation(xc + h_tm1*Uc)
+		// h = o.*output_activation(c)
+		var c = c_tm1[p*filters : (p+1)*filters]
+		var tmp = h.Array[p*4*filters : p*4*filters+filters]
+		for j := 0; j < filters; j++ {
+			c[j] = yf[j]*c[j] + yi[j]*yc[j]
+			tmp[j] = c[j]
+		}
+		output_activation(tmp)
+		for j := 0; j < filters; j++ {
+			state[p*filters+j] = yo[j] * tmp[j]
+		}
+	}
+}
+
+/**
+* Convolutional LSTM layer for 2D (spatial) data.
+* Assumes a "channels last" structure, ie input Shape is {timesteps, rows, cols, channels}.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param state: Array[2*out_rows*out_cols*filters] recurrent state, {h, c}. holds the initial state when called and the final state on return.
+* :param kernel: kernel tensor.
+* :param recurrent_kernel: recurrent kernel tensor
+* :param bias: bias tensor.
+* :param fwork: Array[8*out_rows*out_cols*filters] working storage.
+* :param stride: Array[2] of stride length of the input convolution.
+* :param dilation: Array[2] dilation rate of the input convolution.
+* :param padding: padding mode of the input convolution, K2C_PADDING_VALID or K2C_PADDING_SAME.
+* :param go_backwards: whether to process input sequences forwards (0) or backwards (1).
+* :param return_sequences: whether to return the last output in the output sequence (0), or the full sequence (1).
+* :param recurrent_activation: activation function to apply to internal state.
+* :param output_activation: activation function to apply to output.
+ */
+func K2c_conv_lstm2d(output *K2c_tensor, input *K2c_tensor, state []float64, kernel *K2c_tensor, recurrent_kernel *K2c_tensor, bias *K2c_tensor, fwork []float64, stride []int, dilation []int, padding int, go_backwards int, return_sequences int, recurrent_activation k2c_activationType, output_activation k2c_activationType) {
 	var in_height = input.Shape[0]
-	var units = recurrent_kernel.Shape[1]
+	var in_step = input.Numel / in_height
+	var units = len(state) / 2
+	var timeslice = K2c_tensor{Ndim: 3, Numel: in_step, Shape: [K2C_MAX_NDIM]int{input.Shape[1], input.Shape[2], input.Shape[3], 1, 1}}
 
-	if go_backwards != 0 {
-		for i := in_height - 1; i > -1; i-- {
-			k2c_grucell(state, input.Array[i*in_width:], kernel, recurrent_kernel, bias, fwork, reset_after, recurrent_activation, output_activation)
-			if return_sequences != 0 {
-				for j := 0; j < units; j++ {
-					output.Array[(in_height-1-i)*units+j] = state[j]
-				}
-			}
+	for i := 0; i < in_height; i++ {
+		var t = i
+		if go_backwards != 0 {
+			t = in_height - 1 - i
 		}
-	} else {
-		for i := 0; i < in_height; i++ {
-			k2c_grucell(state, input.Array[i*in_width:], kernel, recurrent_kernel, bias, fwork, reset_after, recurrent_activation, output_activation)
-			if return_sequences != 0 {
-				for j := 0; j < units; j++ {
-					output.Array[i*units+j] = state[j]
-				}
-			}
+		timeslice.Array = input.Array[t*in_step : (t+1)*in_step]
+		k2c_conv_lstm2d_cell(state, &timeslice, kernel, recurrent_kernel, bias, fwork, stride, dilation, padding, recurrent_activation, output_activation)
+		if return_sequences != 0 {
+			copy(output.Array[i*units:(i+1)*units], state[:units])
 		}
 	}
 	if return_sequences == 0 {
-		for i := 0; i < units; i++ {
-			output.Array[i] = state[i]
-		}
+		copy(output.Array[:units], state[:units])
+	}
+}
+
+/**
+* Resets the recurrent state of a layer to zero.
+* a layer that is not stateful starts each call from a zero state, which matters when the layer runs more than once
+* per run of the model, eg a shared layer or a layer in a TimeDistributed or Bidirectional wrapper.
+*
+* :param state: recurrent state of the layer.
+*/
+func K2c_rnn_reset_state(state []float64) {
+	float64SliceToZero(state)
+}
+
+/**
+* Sets the recurrent state of a layer from initial state tensors.
+* the tensors are copied one after the other, eg {h, c} for LSTM and {h} for GRU and SimpleRNN.
+*
+* :param state: recurrent state of the layer.
+* :param initial_state: initial state tensors.
+*/
+func K2c_rnn_set_state(state []float64, initial_state ...*K2c_tensor) {
+	var offset = 0
+	for _, tensor := range initial_state {
+		copy(state[offset:offset+tensor.Numel], tensor.Array[:tensor.Numel])
+		offset += tensor.Numel
+	}
+}
+
+/**
+* Copies the recurrent state of a layer into state output tensors, the inverse of K2c_rnn_set_state.
+*
+* :param state: recurrent state of the layer.
+* :param state_output: tensors receiving the state.
+*/
+func K2c_rnn_get_state(state []float64, state_output ...*K2c_tensor) {
+	var offset = 0
+	for _, tensor := range state_output {
+		copy(tensor.Array[:tensor.Numel], state[offset:offset+tensor.Numel])
+		offset += tensor.Numel
 	}
 }
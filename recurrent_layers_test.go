@@ -0,0 +1,97 @@
+package keras2go
+
+import (
+	"testing"
+)
+
+func TestK2c_conv_lstm2d(t *testing.T) {
+	var x = []float64{1, -2, 0.5, 0.25, 1.5, -1, 2, 0, -0.75}
+	var x2 = []float64{0.5, 1, -1, 0, 2, 0.25, -0.5, 1.5, 1}
+
+	// one step with 'same' padding from a given state, the gates of the one filter are {i, f, c, o}
+	var kernel = newTestTensor([]int{2, 2, 1, 4})
+	copy(kernel.Array, []float64{0.5, -0.25, 0.75, 0.1, -0.5, 0.3, 0.2, -0.4, 0.25, 0.6, -0.3, 0.35, -0.1, 0.45, 0.15, -0.2})
+	var recurrent_kernel = newTestTensor([]int{2, 2, 1, 4})
+	copy(recurrent_kernel.Array, []float64{0.2, -0.3, 0.4, 0.1, 0.05, 0.25, -0.15, 0.3, -0.35, 0.1, 0.2, -0.05, 0.3, -0.2, 0.1, 0.15})
+	var bias = newTestTensor([]int{4})
+	copy(bias.Array, []float64{0.1, 1, -0.1, 0.05})
+	var input = newTestTensor([]int{1, 3, 3, 1})
+	copy(input.Array, x)
+	var state = []float64{0.1, -0.2, 0.3, 0, 0.4, -0.1, 0.2, 0.05, -0.3,
+		0.5, -0.5, 0.25, 1, 0, -0.25, 0.75, -1, 0.1}
+	var output = newTestTensor([]int{3, 3, 1})
+	K2c_conv_lstm2d(output, input, state, kernel, recurrent_kernel, bias, make([]float64, 8*9), []int{1, 1},
+		[]int{1, 1}, K2C_PADDING_SAME, 0, 0, K2c_sigmoid, K2c_tanh)
+	var h = []float64{0.4270994075090574, -0.37877298096250367, 0.19151360974063333, 0.38915523815284137,
+		0.3412149155246256, -0.16323770346156996, 0.46499618355296696, -0.3685040089840667, -0.09344349005076105}
+	checkArrayClose(t, output.Array, h)
+	checkArrayClose(t, state, append(h, 0.7403422263765262, -0.741657873022332, 0.4586264107072786,
+		0.82840540937205, 0.5607752119466253, -0.4062035102251968, 1.140790007938668, -0.7774184698133022,
+		-0.1945897524954477))
+
+	// two filters and 'valid' padding, over a sequence read backwards
+	kernel = newTestTensor([]int{2, 2, 1, 8})
+	for i := range kernel.Array {
+		kernel.Array[i] = 0.1 * float64((i*7)%11-5)
+	}
+	recurrent_kernel = newTestTensor([]int{2, 2, 2, 8})
+	for i := range recurrent_kernel.Array {
+		recurrent_kernel.Array[i] = 0.05 * float64((i*5)%13-6)
+	}
+	bias = newTestTensor([]int{8})
+	copy(bias.Array, []float64{0, 0.5, 1, 1.5, -0.5, 0.25, 0, 0.1})
+	input = newTestTensor([]int{2, 3, 3, 1})
+	copy(input.Array, append(x, x2...))
+	state = make([]float64, 2*8)
+	output = newTestTensor([]int{2, 2, 2, 2})
+	K2c_conv_lstm2d(output, input, state, kernel, recurrent_kernel, bias, make([]float64, 8*8), []int{1, 1},
+		[]int{1, 1}, K2C_PADDING_VALID, 1, 1, K2c_sigmoid, K2c_tanh)
+	checkArrayClose(t, output.Array, []float64{0.061289179829955444, -0.059671583574333234, 0.0073990410437362935,
+		-0.02432185560016712, 0.06145400769052894, -0.054805381164571156, 0.03522655261045775, -0.2812874474779073,
+		0.01277990577958584, 0.09749919108879626, -0.08949185914370253, 0.16171247914350517, 0.06508816418199824,
+		-0.15473146646111524, -0.1613931495714357, -0.1540654365918475})
+	checkArrayClose(t, state[8:], []float64{0.06442069137440744, 0.16284263191922457, -0.3296163869880289,
+		0.3021944241858594, 0.1430095219927792, -0.2468996403711194, -0.27518595658186656, -0.3735521924428798})
+}
+
+func TestK2c_stacked_rnn_cells(t *testing.T) {
+	var kernel = newTestTensor([]int{1, 2})
+	copy(kernel.Array, []float64{0.5, -0.3})
+	var recurrent_kernel = newTestTensor([]int{2, 2})
+	copy(recurrent_kernel.Array, []float64{0.1, 0.2, -0.4, 0.3})
+	var bias = newTestTensor([]int{2})
+	copy(bias.Array, []float64{0.05, -0.1})
+	var rnn = &K2c_simpleRNN_cell{Kernel: kernel, RecurrentKernel: recurrent_kernel, Bias: bias,
+		Fwork: make([]float64, 4), OutputActivation: K2c_tanh}
+	// kernels of the lstm cell are the {i, f, c, o} blocks one after the other
+	var lstm_kernel = newTestTensor([]int{8, 1})
+	copy(lstm_kernel.Array, []float64{0.3, -0.2, 0.1, 0.4, -0.5, 0.25, 0.6, -0.1})
+	var lstm_recurrent_kernel = newTestTensor([]int{4, 1})
+	copy(lstm_recurrent_kernel.Array, []float64{0.2, -0.3, 0.5, 0.1})
+	var lstm_bias = newTestTensor([]int{4})
+	copy(lstm_bias.Array, []float64{0, 1, 0.1, -0.2})
+	var lstm = &K2c_lstm_cell{Kernel: lstm_kernel, RecurrentKernel: lstm_recurrent_kernel, Bias: lstm_bias,
+		Fwork: make([]float64, 8), RecurrentActivation: K2c_sigmoid, OutputActivation: K2c_tanh}
+
+	var cells = K2c_stacked_rnn_cells{rnn, lstm}
+	if cells.StateSize() != 4 {
+		t.Fatal("expected a state of 4 values, got", cells.StateSize())
+	}
+	var input = newTestTensor([]int{3, 1})
+	copy(input.Array, []float64{1, -0.5, 2})
+	var state = make([]float64, cells.StateSize())
+	var output = newTestTensor([]int{3, 1})
+	K2c_rnn(output, input, nil, state, cells, 0, 1)
+	var expected = []float64{-0.07107387666797979, -0.028142687914152652, -0.16464675401954873}
+	checkArrayClose(t, output.Array, expected)
+	checkArrayClose(t, state, []float64{0.7762088769305364, -0.5971864738753389, -0.16464675401954873,
+		-0.2919089328601642})
+
+	// the same as the layers one after the other
+	var hidden = newTestTensor([]int{3, 2})
+	K2c_simpleRNN(hidden, input, nil, make([]float64, 2), kernel, recurrent_kernel, bias, make([]float64, 4), 0, 1,
+		K2c_tanh)
+	K2c_lstm(output, hidden, nil, make([]float64, 2), lstm_kernel, lstm_recurrent_kernel, lstm_bias,
+		make([]float64, 8), 0, 1, K2c_sigmoid, K2c_tanh)
+	checkArrayClose(t, output.Array, expected)
+}
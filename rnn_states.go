@@ -0,0 +1,177 @@
+package keras2go
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+/**
+* Recurrent states of the stateful layers of a model, by layer name.
+* generated stateful models take their states as the first argument, which each call reads and updates. Make them
+* with the generated <function name>_new_states, one per stream, eg one per user. Calls using the same states must
+* not run concurrently.
+*/
+type K2c_states map[string]*K2c_tensor
+
+/**
+* Makes an empty state of the given size.
+*
+* :param size: number of values in the state.
+* :return: state tensor filled with zeros.
+*/
+func K2c_new_state(size int) *K2c_tensor {
+	return &K2c_tensor{Array: make([]float64, size), Ndim: 1, Numel: size, Shape: [K2C_MAX_NDIM]int{size, 1, 1, 1, 1}}
+}
+
+/**
+* Overwrites the state of a layer.
+*
+* :param name: name of the layer.
+* :param values: Array of new state values, same size as the state.
+* :return: error if there is no state with that name or values has the wrong size.
+*/
+func (states K2c_states) Set(name string, values []float64) error {
+	var state, ok = states[name]
+	if !ok {
+		return errors.New("keras2go: no state named " + strconv.Quote(name))
+	}
+	if len(values) != state.Numel {
+		return errors.New("keras2go: state " + strconv.Quote(name) + " has " + strconv.Itoa(state.Numel) +
+			" values, got " + strconv.Itoa(len(values)))
+	}
+	copy(state.Array, values)
+	return nil
+}
+
+/**
+* Resets all states to zero.
+*/
+func (states K2c_states) Reset() {
+	for _, state := range states {
+		state.fillFloat64(0)
+	}
+}
+
+/**
+* Copies all states.
+*
+* :return: independent copy of the states.
+*/
+func (states K2c_states) Snapshot() K2c_states {
+	var snapshot = make(K2c_states, len(states))
+	for name, state := range states {
+		var copied = *state
+		copied.Array = append([]float64(nil), state.Array...)
+		snapshot[name] = &copied
+	}
+	return snapshot
+}
+
+/**
+* Overwrites all states with the values of a snapshot.
+*
+* the states are left untouched if the snapshot does not have the same states.
+*
+* :param snapshot: states taken with Snapshot.
+* :return: error if snapshot lacks a state or a state has the wrong size.
+*/
+func (states K2c_states) Restore(snapshot K2c_states) error {
+	for name, state := range states {
+		var saved, ok = snapshot[name]
+		if !ok || saved.Numel != state.Numel {
+			return errors.New("keras2go: snapshot does not match the state " + strconv.Quote(name))
+		}
+	}
+	for name, state := range states {
+		copy(state.Array, snapshot[name].Array)
+	}
+	return nil
+}
+
+/**
+* Layer names in a stable order.
+*/
+func (states K2c_states) names() []string {
+	var names = make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**
+* Serializes the states.
+* each state is written as the length of the layer name, the name, the number of values and the values, in order
+* of layer name. Integers are uint32 and values float64, both little endian.
+*
+* :return: serialized states.
+*/
+func (states K2c_states) MarshalBinary() ([]byte, error) {
+	var data []byte
+	var buf [8]byte
+	for _, name := range states.names() {
+		var state = states[name]
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(name)))
+		data = append(data, buf[:4]...)
+		data = append(data, name...)
+		binary.LittleEndian.PutUint32(buf[:4], uint32(state.Numel))
+		data = append(data, buf[:4]...)
+		for _, value := range state.Array[:state.Numel] {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(value))
+			data = append(data, buf[:]...)
+		}
+	}
+	return data, nil
+}
+
+/**
+* Overwrites the states with serialized states.
+* the serialized states must come from a model with the same stateful layers. The states are left untouched if
+* they do not.
+*
+* :param data: states serialized with MarshalBinary.
+* :return: error if data does not match the states.
+*/
+func (states K2c_states) UnmarshalBinary(data []byte) error {
+	if !states.unmarshal(data, false) {
+		return errors.New("keras2go: serialized states do not match the model states")
+	}
+	states.unmarshal(data, true)
+	return nil
+}
+
+/**
+* Reads serialized states.
+*
+* :param data: states serialized with MarshalBinary.
+* :param write: whether to overwrite the states, or only check that data matches them.
+* :return: whether data matches the states.
+*/
+func (states K2c_states) unmarshal(data []byte, write bool) bool {
+	for _, name := range states.names() {
+		var state = states[name]
+		if len(data) < 4 {
+			return false
+		}
+		var name_len = int(binary.LittleEndian.Uint32(data))
+		if len(data) < 8+name_len || string(data[4:4+name_len]) != name {
+			return false
+		}
+		data = data[4+name_len:]
+		if int(binary.LittleEndian.Uint32(data)) != state.Numel || len(data) < 4+8*state.Numel {
+			return false
+		}
+		data = data[4:]
+		if write {
+			for i := 0; i < state.Numel; i++ {
+				state.Array[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+			}
+		}
+		data = data[8*state.Numel:]
+	}
+	return len(data) == 0
+}
@@ -0,0 +1,105 @@
+package keras2go
+
+import (
+	"testing"
+)
+
+func newTestStates() K2c_states {
+	var states = K2c_states{
+		"lstm": K2c_new_state(4),
+		"gru":  K2c_new_state(2),
+	}
+	copy(states["lstm"].Array, []float64{1, -2, 3.5, 0})
+	copy(states["gru"].Array, []float64{0.25, -1e-3})
+	return states
+}
+
+func TestK2c_states_Set(t *testing.T) {
+	var states = newTestStates()
+	if err := states.Set("gru", []float64{7, 8}); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, states["gru"].Array, []float64{7, 8})
+
+	if err := states.Set("dense", []float64{1, 2}); err == nil {
+		t.Fatal("expected an error for an unknown state")
+	}
+	if err := states.Set("gru", []float64{1, 2, 3}); err == nil {
+		t.Fatal("expected an error for a state of the wrong size")
+	}
+	if err := states.Set("gru", []float64{1}); err == nil {
+		t.Fatal("expected an error for a state of the wrong size")
+	}
+	checkArray(t, states["gru"].Array, []float64{7, 8})
+}
+
+func TestK2c_states_Snapshot(t *testing.T) {
+	var states = newTestStates()
+	var snapshot = states.Snapshot()
+	states.Reset()
+	checkArray(t, states["lstm"].Array, []float64{0, 0, 0, 0})
+	checkArray(t, snapshot["lstm"].Array, []float64{1, -2, 3.5, 0})
+
+	if err := states.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, states["lstm"].Array, []float64{1, -2, 3.5, 0})
+	checkArray(t, states["gru"].Array, []float64{0.25, -1e-3})
+
+	states.Reset()
+	delete(snapshot, "gru")
+	if err := states.Restore(snapshot); err == nil {
+		t.Fatal("expected an error for a snapshot without all states")
+	}
+	snapshot["gru"] = K2c_new_state(3)
+	if err := states.Restore(snapshot); err == nil {
+		t.Fatal("expected an error for a snapshot with a state of the wrong size")
+	}
+	checkArray(t, states["lstm"].Array, []float64{0, 0, 0, 0})
+}
+
+func TestK2c_states_MarshalBinary(t *testing.T) {
+	var states = newTestStates()
+	data, err := states.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 names and 2 sizes, the names and 6 values
+	if len(data) != 4*4+len("gru")+len("lstm")+6*8 {
+		t.Fatal(len(data))
+	}
+
+	var restored = K2c_states{
+		"lstm": K2c_new_state(4),
+		"gru":  K2c_new_state(2),
+	}
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, restored["lstm"].Array, []float64{1, -2, 3.5, 0})
+	checkArray(t, restored["gru"].Array, []float64{0.25, -1e-3})
+
+	var mismatched = []K2c_states{
+		{"lstm": K2c_new_state(4)},
+		{"lstm": K2c_new_state(4), "gru": K2c_new_state(3)},
+		{"lstm": K2c_new_state(4), "gru2": K2c_new_state(2)},
+		{"lstm": K2c_new_state(4), "gru": K2c_new_state(2), "rnn": K2c_new_state(1)},
+	}
+	for i, other := range mismatched {
+		if err := other.UnmarshalBinary(data); err == nil {
+			t.Fatal(i, "expected an error for mismatched states")
+		}
+		for name, state := range other {
+			for _, value := range state.Array {
+				if value != 0 {
+					t.Fatal(i, name, "state was written")
+				}
+			}
+		}
+	}
+	for _, n := range []int{0, 3, 10, len(data) - 1} {
+		if err := restored.UnmarshalBinary(data[:n]); err == nil {
+			t.Fatal(n, "expected an error for truncated data")
+		}
+	}
+}
@@ -17,3 +17,156 @@ type K2c_tensor struct {
 }
 
 type k2c_activationType func(x []float64)
+
+/**
+* Padding modes understood by the convolution and pooling kernels.
+ */
+const (
+	K2C_PADDING_VALID = iota /** no padding, windows only cover the input. */
+	K2C_PADDING_SAME         /** pad so that output size is ceil(input size / stride). */
+	K2C_PADDING_CAUSAL       /** like K2C_PADDING_SAME, with all the padding before the input, for temporal data. */
+)
+
+/**
+* Data formats, ie where the channel axis sits in a tensor of spatial data.
+ */
+const (
+	K2C_CHANNELS_LAST  = iota /** Shape is {dim 1, ..., dim n, channels}. */
+	K2C_CHANNELS_FIRST        /** Shape is {channels, dim 1, ..., dim n}. */
+)
+
+/**
+* A layer kernel bound to its weights and settings, eg a closure calling K2c_dense.
+* layer wrappers call it on slices of their input, and return its error.
+ */
+type K2c_layer func(output *K2c_tensor, input *K2c_tensor) error
+
+/**
+* Merge modes of the Bidirectional wrapper.
+ */
+const (
+	K2C_MERGE_CONCAT = iota /** concatenate the forward and backward outputs along the last axis. */
+	K2C_MERGE_SUM           /** add the forward and backward outputs. */
+	K2C_MERGE_MUL           /** multiply the forward and backward outputs. */
+	K2C_MERGE_AVE           /** average the forward and backward outputs. */
+	K2C_MERGE_NONE          /** keep the forward and backward outputs apart. */
+)
+
+/**
+* Interpolation modes of the upsampling and resizing kernels, following tf.image.resize.
+ */
+const (
+	K2C_INTERPOLATION_NEAREST  = iota /** value of the nearest input element. */
+	K2C_INTERPOLATION_BILINEAR        /** linear interpolation along each axis, with half pixel centers. */
+	K2C_INTERPOLATION_AREA            /** average of the input elements covered by each output element. */
+	K2C_INTERPOLATION_BICUBIC         /** Keys cubic interpolation along each axis, with half pixel centers. */
+)
+
+/**
+* Output modes of the categorical preprocessing layers, like CategoryEncoding.
+ */
+const (
+	K2C_ENCODING_ONE_HOT   = iota /** one row of num_tokens per input value, with a 1 at the value. */
+	K2C_ENCODING_MULTI_HOT        /** one row of num_tokens per input row, with a 1 at each value of the row. */
+	K2C_ENCODING_COUNT            /** like K2C_ENCODING_MULTI_HOT, with the number of times each value appears. */
+	K2C_ENCODING_TF_IDF           /** like K2C_ENCODING_COUNT, with the counts multiplied by the idf weights. */
+)
+
+/**
+* Standardizations of TextVectorization.
+ */
+const (
+	K2C_STANDARDIZE_NONE                        = iota /** keep the text as is. */
+	K2C_STANDARDIZE_LOWER                              /** lowercase ASCII letters. */
+	K2C_STANDARDIZE_STRIP_PUNCTUATION                  /** remove ASCII punctuation. */
+	K2C_STANDARDIZE_LOWER_AND_STRIP_PUNCTUATION        /** both of the above. */
+)
+
+/**
+* Splits of TextVectorization.
+ */
+const (
+	K2C_SPLIT_NONE       = iota /** the whole text is one token. */
+	K2C_SPLIT_WHITESPACE        /** tokens are separated by runs of ASCII whitespace. */
+	K2C_SPLIT_CHARACTER         /** each unicode character is a token. */
+)
+
+/**
+* Handling of the out of vocabulary indices of an Embedding, ie indices outside [0, input_dim).
+ */
+const (
+	K2C_OOV_ERROR  = iota /** report an error, like keras does. */
+	K2C_OOV_ZERO          /** look up a zero vector. */
+	K2C_OOV_BUCKET        /** look up one of the last rows of the kernel, which are reserved as OOV buckets. */
+)
+
+/**
+* Reductions of the vectors of a bag of indices, eg in an Embedding.
+ */
+const (
+	K2C_COMBINER_NONE = iota /** one vector per index. */
+	K2C_COMBINER_SUM         /** sum of the vectors of each bag. */
+	K2C_COMBINER_MEAN        /** mean of the vectors of each bag. */
+)
+
+/**
+* Reductions of K2c_reduce.
+ */
+const (
+	K2C_REDUCE_SUM  = iota /** sum over the reduced axes. */
+	K2C_REDUCE_MEAN        /** mean over the reduced axes. */
+	K2C_REDUCE_MAX         /** maximum over the reduced axes. */
+	K2C_REDUCE_MIN         /** minimum over the reduced axes. */
+)
+
+/**
+* Element-wise operations of K2c_unary, named like their tf.math functions.
+ */
+const (
+	K2C_UNARY_ABS = iota
+	K2C_UNARY_NEGATIVE
+	K2C_UNARY_SIGN
+	K2C_UNARY_SQUARE
+	K2C_UNARY_SQRT
+	K2C_UNARY_RSQRT
+	K2C_UNARY_RECIPROCAL
+	K2C_UNARY_EXP
+	K2C_UNARY_EXPM1
+	K2C_UNARY_LOG
+	K2C_UNARY_LOG1P
+	K2C_UNARY_SIN
+	K2C_UNARY_COS
+	K2C_UNARY_TAN
+	K2C_UNARY_TANH
+	K2C_UNARY_SIGMOID
+	K2C_UNARY_ERF
+	K2C_UNARY_FLOOR
+	K2C_UNARY_CEIL
+	K2C_UNARY_ROUND
+	K2C_UNARY_LOGICAL_NOT
+)
+
+/**
+* Element-wise operations of K2c_binary, named like their tf.math functions.
+* comparisons and logical operations give 1 for true and 0 for false.
+ */
+const (
+	K2C_BINARY_ADD = iota
+	K2C_BINARY_SUBTRACT
+	K2C_BINARY_MULTIPLY
+	K2C_BINARY_DIVIDE
+	K2C_BINARY_FLOORDIV
+	K2C_BINARY_FLOORMOD
+	K2C_BINARY_POW
+	K2C_BINARY_MAXIMUM
+	K2C_BINARY_MINIMUM
+	K2C_BINARY_SQUARED_DIFFERENCE
+	K2C_BINARY_EQUAL
+	K2C_BINARY_NOT_EQUAL
+	K2C_BINARY_LESS
+	K2C_BINARY_LESS_EQUAL
+	K2C_BINARY_GREATER
+	K2C_BINARY_GREATER_EQUAL
+	K2C_BINARY_LOGICAL_AND
+	K2C_BINARY_LOGICAL_OR
+)
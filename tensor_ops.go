@@ -0,0 +1,287 @@
+package keras2go
+
+import (
+	"fmt"
+	"math"
+)
+
+/**
+* Reduces a tensor over some of its axes, like tf.math.reduce_sum and friends.
+* this and the other generic ops of this file run the tf functions called on keras tensors in functional models,
+* ie TFOpLambda layers.
+*
+* :param output: output tensor, with the kept axes in order. Whether the reduced axes are kept with size 1 does not
+*     matter. Reducing over an empty axis gives the identity of the reduction, and NaN for the mean, like tf.
+* :param input: input tensor.
+* :param axes: axes to reduce over.
+* :param reduction: one of K2C_REDUCE_*.
+*/
+func K2c_reduce(output *K2c_tensor, input *K2c_tensor, axes []int, reduction int) {
+	var r = k2c_make_reduction(input.Shape[:], input.Ndim, axes)
+	for g := 0; g < r.ngroups; g++ {
+		var group = input.Array[r.group_offset(g):]
+		var acc float64
+		switch reduction {
+		case K2C_REDUCE_MAX:
+			acc = math.Inf(-1)
+		case K2C_REDUCE_MIN:
+			acc = math.Inf(1)
+		}
+		for m := 0; m < r.nmembers; m++ {
+			var x = group[r.member_offset(m)]
+			switch reduction {
+			case K2C_REDUCE_MAX:
+				acc = math.Max(acc, x)
+			case K2C_REDUCE_MIN:
+				acc = math.Min(acc, x)
+			default:
+				acc += x
+			}
+		}
+		if reduction == K2C_REDUCE_MEAN {
+			acc /= float64(r.nmembers)
+		}
+		output.Array[g] = acc
+	}
+}
+
+/**
+* Strided slice of a tensor, like tf.strided_slice and python indexing.
+* axes added or removed by the slice do not change the order of the elements, so the output only needs the right
+* number of elements.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param begin: Array[input.Ndim] first index along each axis.
+* :param size: Array[input.Ndim] number of indices along each axis.
+* :param strides: Array[input.Ndim] step between indices along each axis, may be negative.
+*/
+func K2c_strided_slice(output *K2c_tensor, input *K2c_tensor, begin []int, size []int, strides []int) {
+	var ndim = input.Ndim
+	var in_stride [K2C_MAX_NDIM]int
+	in_stride[ndim-1] = 1
+	for d := ndim - 2; d >= 0; d-- {
+		in_stride[d] = in_stride[d+1] * input.Shape[d+1]
+	}
+	var numel = 1
+	var idx = 0
+	for d := 0; d < ndim; d++ {
+		numel *= size[d]
+		idx += begin[d] * in_stride[d]
+	}
+	var sub [K2C_MAX_NDIM]int
+	for j := 0; j < numel; j++ {
+		output.Array[j] = input.Array[idx]
+		for d := ndim - 1; d >= 0; d-- {
+			sub[d]++
+			idx += strides[d] * in_stride[d]
+			if sub[d] < size[d] {
+				break
+			}
+			idx -= sub[d] * strides[d] * in_stride[d]
+			sub[d] = 0
+		}
+	}
+}
+
+/**
+* Gathers slices of a tensor along an axis, like tf.gather.
+*
+* :param output: output tensor, shape input.Shape[:axis] + indices.Shape + input.Shape[axis+1:].
+* :param input: input tensor.
+* :param indices: tensor of indices along axis.
+* :param axis: axis to gather along.
+* :return: error for an index outside [0, input.Shape[axis]), like tf raises on CPU. The output is not written then.
+*/
+func K2c_gather(output *K2c_tensor, input *K2c_tensor, indices *K2c_tensor, axis int) error {
+	for k := 0; k < indices.Numel; k++ {
+		var index = indices.Array[k]
+		if index != math.Trunc(index) || index < 0 || index >= float64(input.Shape[axis]) {
+			return fmt.Errorf("keras2go: gather indices[%d] = %v is not in [0, %d)", k, index, input.Shape[axis])
+		}
+	}
+	var outer = 1
+	for d := 0; d < axis; d++ {
+		outer *= input.Shape[d]
+	}
+	var inner = input.Numel / outer / input.Shape[axis]
+	var n = input.Shape[axis]
+	var out = output.Array
+	for o := 0; o < outer; o++ {
+		for k := 0; k < indices.Numel; k++ {
+			var start = (o*n + int(indices.Array[k])) * inner
+			copy(out[:inner], input.Array[start:start+inner])
+			out = out[inner:]
+		}
+	}
+	return nil
+}
+
+/**
+* Removes axes of size 1, like tf.squeeze.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param axes: axes to remove, or nil to remove all axes of size 1.
+*/
+func K2c_squeeze(output *K2c_tensor, input *K2c_tensor, axes []int) {
+	var newshp []int
+	for d := 0; d < input.Ndim; d++ {
+		var squeezed = axes == nil && input.Shape[d] == 1
+		for _, axis := range axes {
+			squeezed = squeezed || axis == d
+		}
+		if !squeezed {
+			newshp = append(newshp, input.Shape[d])
+		}
+	}
+	K2c_reshape(output, input, newshp)
+}
+
+/**
+* Inserts an axis of size 1, like tf.expand_dims.
+*
+* :param output: output tensor.
+* :param input: input tensor.
+* :param axis: position of the new axis in the output.
+*/
+func K2c_expand_dims(output *K2c_tensor, input *K2c_tensor, axis int) {
+	var newshp = append([]int{}, input.Shape[:axis]...)
+	newshp = append(newshp, 1)
+	newshp = append(newshp, input.Shape[axis:input.Ndim]...)
+	K2c_reshape(output, input, newshp)
+}
+
+var k2c_unary_ops = [...]func(x float64) float64{
+	K2C_UNARY_ABS:      math.Abs,
+	K2C_UNARY_NEGATIVE: func(x float64) float64 { return -x },
+	K2C_UNARY_SIGN: func(x float64) float64 {
+		if x > 0 {
+			return 1
+		} else if x < 0 {
+			return -1
+		}
+		return x
+	},
+	K2C_UNARY_SQUARE:      func(x float64) float64 { return x * x },
+	K2C_UNARY_SQRT:        math.Sqrt,
+	K2C_UNARY_RSQRT:       func(x float64) float64 { return 1 / math.Sqrt(x) },
+	K2C_UNARY_RECIPROCAL:  func(x float64) float64 { return 1 / x },
+	K2C_UNARY_EXP:         math.Exp,
+	K2C_UNARY_EXPM1:       math.Expm1,
+	K2C_UNARY_LOG:         math.Log,
+	K2C_UNARY_LOG1P:       math.Log1p,
+	K2C_UNARY_SIN:         math.Sin,
+	K2C_UNARY_COS:         math.Cos,
+	K2C_UNARY_TAN:         math.Tan,
+	K2C_UNARY_TANH:        math.Tanh,
+	K2C_UNARY_SIGMOID:     func(x float64) float64 { return 1 / (1 + math.Exp(-x)) },
+	K2C_UNARY_ERF:         math.Erf,
+	K2C_UNARY_FLOOR:       math.Floor,
+	K2C_UNARY_CEIL:        math.Ceil,
+	K2C_UNARY_ROUND:       math.RoundToEven,
+	K2C_UNARY_LOGICAL_NOT: func(x float64) float64 { return k2c_bool(x == 0) },
+}
+
+var k2c_binary_ops = [...]func(a float64, b float64) float64{
+	K2C_BINARY_ADD:      k2c_op_add,
+	K2C_BINARY_SUBTRACT: k2c_op_subtract,
+	K2C_BINARY_MULTIPLY: k2c_op_multiply,
+	K2C_BINARY_DIVIDE:   func(a float64, b float64) float64 { return a / b },
+	K2C_BINARY_FLOORDIV: func(a float64, b float64) float64 { return math.Floor(a / b) },
+	K2C_BINARY_FLOORMOD: func(a float64, b float64) float64 {
+		var r = math.Mod(a, b)
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+		return r
+	},
+	K2C_BINARY_POW:                math.Pow,
+	K2C_BINARY_MAXIMUM:            k2c_op_max,
+	K2C_BINARY_MINIMUM:            k2c_op_min,
+	K2C_BINARY_SQUARED_DIFFERENCE: func(a float64, b float64) float64 { return (a - b) * (a - b) },
+	K2C_BINARY_EQUAL:              func(a float64, b float64) float64 { return k2c_bool(a == b) },
+	K2C_BINARY_NOT_EQUAL:          func(a float64, b float64) float64 { return k2c_bool(a != b) },
+	K2C_BINARY_LESS:               func(a float64, b float64) float64 { return k2c_bool(a < b) },
+	K2C_BINARY_LESS_EQUAL:         func(a float64, b float64) float64 { return k2c_bool(a <= b) },
+	K2C_BINARY_GREATER:            func(a float64, b float64) float64 { return k2c_bool(a > b) },
+	K2C_BINARY_GREATER_EQUAL:      func(a float64, b float64) float64 { return k2c_bool(a >= b) },
+	K2C_BINARY_LOGICAL_AND:        func(a float64, b float64) float64 { return k2c_bool(a != 0 && b != 0) },
+	K2C_BINARY_LOGICAL_OR:         func(a float64, b float64) float64 { return k2c_bool(a != 0 || b != 0) },
+}
+
+func k2c_bool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+/**
+* Element-wise unary operation.
+*
+* :param output: output tensor. May be the input tensor.
+* :param input: input tensor.
+* :param op: one of K2C_UNARY_*.
+*/
+func K2c_unary(output *K2c_tensor, input *K2c_tensor, op int) {
+	var f = k2c_unary_ops[op]
+	for i := 0; i < input.Numel; i++ {
+		output.Array[i] = f(input.Array[i])
+	}
+}
+
+/**
+* Element-wise binary operation.
+* x and y are broadcast to the shape of the output, numpy style.
+*
+* :param output: output tensor. May be x, but not y.
+* :param x: left operand.
+* :param y: right operand.
+* :param op: one of K2C_BINARY_*.
+*/
+func K2c_binary(output *K2c_tensor, x *K2c_tensor, y *K2c_tensor, op int) {
+	if x != output {
+		k2c_broadcast_op(output, x, k2c_op_first)
+	}
+	k2c_broadcast_op(output, y, k2c_binary_ops[op])
+}
+
+/**
+* Element-wise choice between two tensors, like tf.where with x and y.
+* condition, x and y are broadcast to the shape of the output, numpy style.
+*
+* :param output: output tensor.
+* :param condition: tensor, nonzero where the output takes x.
+* :param x: values where condition is nonzero.
+* :param y: values where condition is zero.
+*/
+func K2c_where(output *K2c_tensor, condition *K2c_tensor, x *K2c_tensor, y *K2c_tensor) {
+	var inputs = [3]*K2c_tensor{condition, x, y}
+	var strides [3][K2C_MAX_NDIM]int
+	for k, input := range inputs {
+		strides[k] = k2c_broadcast_strides(input, output)
+	}
+	var sub [K2C_MAX_NDIM]int
+	var idx [3]int
+	for j := 0; j < output.Numel; j++ {
+		if condition.Array[idx[0]] != 0 {
+			output.Array[j] = x.Array[idx[1]]
+		} else {
+			output.Array[j] = y.Array[idx[2]]
+		}
+		for d := output.Ndim - 1; d >= 0; d-- {
+			sub[d]++
+			for k := range idx {
+				idx[k] += strides[k][d]
+			}
+			if sub[d] < output.Shape[d] {
+				break
+			}
+			for k := range idx {
+				idx[k] -= strides[k][d] * sub[d]
+			}
+			sub[d] = 0
+		}
+	}
+}
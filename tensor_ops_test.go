@@ -0,0 +1,82 @@
+package keras2go
+
+import (
+	"math"
+	"testing"
+)
+
+func TestK2c_reduce(t *testing.T) {
+	var input = newTestTensor([]int{2, 3, 2})
+	for i := range input.Array {
+		input.Array[i] = float64(i)
+	}
+	var output = newTestTensor([]int{3})
+	K2c_reduce(output, input, []int{0, 2}, K2C_REDUCE_SUM)
+	checkArray(t, output.Array, []float64{0 + 1 + 6 + 7, 2 + 3 + 8 + 9, 4 + 5 + 10 + 11})
+	K2c_reduce(output, input, []int{2, 0}, K2C_REDUCE_MAX)
+	checkArray(t, output.Array, []float64{7, 9, 11})
+	var mean = newTestTensor([]int{2, 2})
+	K2c_reduce(mean, input, []int{1}, K2C_REDUCE_MEAN)
+	checkArray(t, mean.Array, []float64{2, 3, 8, 9})
+
+	// an empty axis reduces to the identity
+	var empty = newTestTensor([]int{2, 0})
+	var reduced = newTestTensor([]int{2})
+	K2c_reduce(reduced, empty, []int{1}, K2C_REDUCE_SUM)
+	checkArray(t, reduced.Array, []float64{0, 0})
+	K2c_reduce(reduced, empty, []int{1}, K2C_REDUCE_MAX)
+	checkArray(t, reduced.Array, []float64{math.Inf(-1), math.Inf(-1)})
+	K2c_reduce(reduced, empty, []int{1}, K2C_REDUCE_MIN)
+	checkArray(t, reduced.Array, []float64{math.Inf(1), math.Inf(1)})
+}
+
+func TestK2c_strided_slice(t *testing.T) {
+	var input = newTestTensor([]int{3, 4})
+	for i := range input.Array {
+		input.Array[i] = float64(i)
+	}
+	// input[1:, ::-2]
+	var output = newTestTensor([]int{2, 2})
+	K2c_strided_slice(output, input, []int{1, 3}, []int{2, 2}, []int{1, -2})
+	checkArray(t, output.Array, []float64{7, 5, 11, 9})
+}
+
+func TestK2c_gather(t *testing.T) {
+	var input = newTestTensor([]int{2, 3})
+	for i := range input.Array {
+		input.Array[i] = float64(i + 1)
+	}
+	var indices = newTestTensor([]int{3})
+	copy(indices.Array, []float64{2, 0, 1})
+	var output = newTestTensor([]int{2, 3})
+	if err := K2c_gather(output, input, indices, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, output.Array, []float64{3, 1, 2, 6, 4, 5})
+
+	// out of range indices are errors, like tf on CPU
+	for _, index := range []float64{3, -1, 0.5} {
+		indices.Array[2] = index
+		if err := K2c_gather(output, input, indices, 1); err == nil {
+			t.Fatal("expected an error for index", index)
+		}
+	}
+	checkArray(t, output.Array, []float64{3, 1, 2, 6, 4, 5})
+}
+
+func TestK2c_binary_where(t *testing.T) {
+	var x = newTestTensor([]int{2, 2})
+	copy(x.Array, []float64{1, -2, 3, -4})
+	var y = newTestTensor([]int{2})
+	copy(y.Array, []float64{2, 3})
+	var output = newTestTensor([]int{2, 2})
+	K2c_binary(output, x, y, K2C_BINARY_FLOORMOD)
+	checkArray(t, output.Array, []float64{1, 1, 1, 2})
+	K2c_binary(output, x, y, K2C_BINARY_GREATER)
+	checkArray(t, output.Array, []float64{0, 0, 1, 0})
+
+	var condition = newTestTensor([]int{2, 1})
+	copy(condition.Array, []float64{1, 0})
+	K2c_where(output, condition, x, y)
+	checkArray(t, output.Array, []float64{1, -2, 2, 3})
+}
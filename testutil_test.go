@@ -0,0 +1,47 @@
+package keras2go
+
+import (
+	"math"
+	"testing"
+)
+
+// Helpers shared by the tests of the layers.
+
+func newTestTensor(shape []int) *K2c_tensor {
+	var tensor = &K2c_tensor{Ndim: len(shape), Numel: 1}
+	for i, dim := range shape {
+		tensor.Shape[i] = dim
+		tensor.Numel *= dim
+	}
+	for i := len(shape); i < K2C_MAX_NDIM; i++ {
+		tensor.Shape[i] = 1
+	}
+	tensor.Array = make([]float64, tensor.Numel)
+	return tensor
+}
+
+func checkArray(t *testing.T, got []float64, expected []float64) {
+	t.Helper()
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("got %v, expected %v", got, expected)
+		}
+	}
+}
+
+func checkArrayClose(t *testing.T, got []float64, expected []float64) {
+	t.Helper()
+	checkArrayTol(t, got, expected, 1e-12)
+}
+
+func checkArrayTol(t *testing.T, got []float64, expected []float64, tol float64) {
+	t.Helper()
+	if len(got) < len(expected) {
+		t.Fatalf("got %d values, expected %d", len(got), len(expected))
+	}
+	for i := range expected {
+		if math.Abs(got[i]-expected[i]) > tol {
+			t.Fatalf("index %d: got %v, expected %v", i, got, expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package keras2go
+
+/**
+* Tensor header of one timestep of a tensor, ie a tensor with the first axis dropped.
+* the Array of the returned tensor is not set.
+*
+* :param tensor: tensor with timesteps along the first axis.
+* :return: tensor of a single timestep.
+*/
+func k2c_timeslice(tensor *K2c_tensor) K2c_tensor {
+	var slice = K2c_tensor{Ndim: tensor.Ndim - 1, Numel: tensor.Numel / tensor.Shape[0]}
+	copy(slice.Shape[:], tensor.Shape[1:])
+	slice.Shape[K2C_MAX_NDIM-1] = 1
+	return slice
+}
+
+/**
+* TimeDistributed wrapper.
+* applies a layer separately to every timestep of the input.
+*
+* :param output: output tensor, timesteps along the first axis.
+* :param input: input tensor, timesteps along the first axis.
+* :param layer: layer to apply to each timestep.
+* :return: the first error of the layer.
+*/
+func K2c_time_distributed(output *K2c_tensor, input *K2c_tensor, layer K2c_layer) error {
+	var timesteps = input.Shape[0]
+	var slice_input = k2c_timeslice(input)
+	var slice_output = k2c_timeslice(output)
+	for i := 0; i < timesteps; i++ {
+		slice_input.Array = input.Array[i*slice_input.Numel : (i+1)*slice_input.Numel]
+		slice_output.Array = output.Array[i*slice_output.Numel : (i+1)*slice_output.Numel]
+		if err := layer(&slice_output, &slice_input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+* Bidirectional wrapper.
+* runs a recurrent layer forward and backward over the input and merges both outputs. Like keras, the masked
+* timesteps of returned sequences are zero.
+*
+* :param output: output tensor, nil if merge_mode is K2C_MERGE_NONE.
+* :param input: input tensor.
+* :param mask: Array[timesteps] of which timesteps the layers process, or nil if the input has no mask.
+* :param forward: forward recurrent layer.
+* :param backward: backward recurrent layer, ie with go_backwards set.
+* :param forward_output: output tensor of the forward layer.
+* :param backward_output: output tensor of the backward layer.
+* :param merge_mode: how to merge the outputs, one of K2C_MERGE_CONCAT, K2C_MERGE_SUM, K2C_MERGE_MUL, K2C_MERGE_AVE or
+* K2C_MERGE_NONE to leave them in forward_output and backward_output.
+* :param return_sequences: (0,1) whether the layers return the full sequence, in which case the backward sequence is
+* put back in forward order before merging.
+* :return: the first error of the layers.
+*/
+func K2c_bidirectional(output *K2c_tensor, input *K2c_tensor, mask []bool, forward K2c_layer, backward K2c_layer,
+	forward_output *K2c_tensor, backward_output *K2c_tensor, merge_mode int, return_sequences int) error {
+	if err := forward(forward_output, input); err != nil {
+		return err
+	}
+	if err := backward(backward_output, input); err != nil {
+		return err
+	}
+	if return_sequences != 0 {
+		k2c_flip(backward_output, 0)
+		var step = forward_output.Numel / forward_output.Shape[0]
+		for t := 0; t < len(mask); t++ {
+			if mask[t] {
+				continue
+			}
+			for i := t * step; i < (t+1)*step; i++ {
+				forward_output.Array[i] = 0
+				backward_output.Array[i] = 0
+			}
+		}
+	}
+	switch merge_mode {
+	case K2C_MERGE_CONCAT:
+		K2c_concatenate(output, output.Ndim-1, forward_output, backward_output)
+	case K2C_MERGE_SUM:
+		K2c_add(output, forward_output, backward_output)
+	case K2C_MERGE_MUL:
+		K2c_multiply(output, forward_output, backward_output)
+	case K2C_MERGE_AVE:
+		K2c_average(output, forward_output, backward_output)
+	}
+	return nil
+}
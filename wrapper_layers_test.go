@@ -0,0 +1,118 @@
+package keras2go
+
+import (
+	"errors"
+	"testing"
+)
+
+// newTestSumRNN is a simpleRNN of one unit whose output is the running sum of its input.
+func newTestSumRNN(mask []bool, go_backwards int, return_sequences int) K2c_layer {
+	var ones = newTestTensor([]int{1, 1})
+	ones.Array[0] = 1
+	var bias = newTestTensor([]int{1})
+	return func(output *K2c_tensor, input *K2c_tensor) error {
+		var state = make([]float64, 1)
+		K2c_simpleRNN(output, input, mask, state, ones, ones, bias, make([]float64, 2), go_backwards,
+			return_sequences, K2c_linear)
+		return nil
+	}
+}
+
+func TestK2c_bidirectional(t *testing.T) {
+	var cases = []struct {
+		name             string
+		merge_mode       int
+		return_sequences int
+		mask             []bool
+		shape            []int
+		expected         []float64
+		forward          []float64
+		backward         []float64
+	}{
+		// the backward sequence of running sums from the end, {4, 7, 9, 10}, is put back in forward order
+		{"concat", K2C_MERGE_CONCAT, 1, nil, []int{4, 2}, []float64{1, 10, 3, 9, 6, 7, 10, 4}, nil, nil},
+		{"sum", K2C_MERGE_SUM, 1, nil, []int{4, 1}, []float64{11, 12, 13, 14}, nil, nil},
+		{"mul", K2C_MERGE_MUL, 1, nil, []int{4, 1}, []float64{10, 27, 42, 40}, nil, nil},
+		{"ave", K2C_MERGE_AVE, 1, nil, []int{4, 1}, []float64{5.5, 6, 6.5, 7}, nil, nil},
+		{"none", K2C_MERGE_NONE, 1, nil, nil, nil, []float64{1, 3, 6, 10}, []float64{10, 9, 7, 4}},
+		{"last concat", K2C_MERGE_CONCAT, 0, nil, []int{2}, []float64{10, 10}, nil, nil},
+		{"last none", K2C_MERGE_NONE, 0, nil, nil, nil, []float64{10}, []float64{10}},
+		// the layers carry their state over the masked step, which is zero in the output sequences
+		{"masked concat", K2C_MERGE_CONCAT, 1, []bool{true, true, false, true}, []int{4, 2},
+			[]float64{1, 7, 3, 6, 0, 0, 7, 4}, nil, nil},
+		{"masked none", K2C_MERGE_NONE, 1, []bool{true, true, false, true}, nil, nil,
+			[]float64{1, 3, 0, 7}, []float64{7, 6, 0, 4}},
+		{"masked last", K2C_MERGE_SUM, 0, []bool{true, true, false, true}, []int{1}, []float64{14}, nil, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var input = newTestTensor([]int{4, 1})
+			copy(input.Array, []float64{1, 2, 3, 4})
+			var layer_shape = []int{1}
+			if c.return_sequences != 0 {
+				layer_shape = []int{4, 1}
+			}
+			var forward_output = newTestTensor(layer_shape)
+			var backward_output = newTestTensor(layer_shape)
+			var output *K2c_tensor
+			if c.shape != nil {
+				output = newTestTensor(c.shape)
+			}
+			if err := K2c_bidirectional(output, input, c.mask, newTestSumRNN(c.mask, 0, c.return_sequences),
+				newTestSumRNN(c.mask, 1, c.return_sequences), forward_output, backward_output, c.merge_mode,
+				c.return_sequences); err != nil {
+				t.Fatal(err)
+			}
+			if output != nil {
+				checkArray(t, output.Array, c.expected)
+			}
+			if c.forward != nil {
+				checkArray(t, forward_output.Array, c.forward)
+				checkArray(t, backward_output.Array, c.backward)
+			}
+		})
+	}
+
+	var failing = func(output *K2c_tensor, input *K2c_tensor) error {
+		return errors.New("backward failed")
+	}
+	var input = newTestTensor([]int{4, 1})
+	var output = newTestTensor([]int{2})
+	if err := K2c_bidirectional(output, input, nil, newTestSumRNN(nil, 0, 0), failing, newTestTensor([]int{1}),
+		newTestTensor([]int{1}), K2C_MERGE_CONCAT, 0); err == nil || err.Error() != "backward failed" {
+		t.Fatal("expected the error of the backward layer, got", err)
+	}
+}
+
+func TestK2c_time_distributed(t *testing.T) {
+	var input = newTestTensor([]int{3, 2})
+	copy(input.Array, []float64{1, 2, 3, 4, 5, 6})
+	var output = newTestTensor([]int{3, 1})
+	// sums each timestep, which the layer gets as a tensor of its own
+	var sum = func(output *K2c_tensor, input *K2c_tensor) error {
+		if input.Ndim != 1 || input.Shape[0] != 2 || input.Numel != 2 || output.Ndim != 1 || output.Numel != 1 {
+			return errors.New("wrong timeslice shape")
+		}
+		output.Array[0] = input.Array[0] + input.Array[1]
+		return nil
+	}
+	if err := K2c_time_distributed(output, input, sum); err != nil {
+		t.Fatal(err)
+	}
+	checkArray(t, output.Array, []float64{3, 7, 11})
+
+	var calls = 0
+	var failing = func(output *K2c_tensor, input *K2c_tensor) error {
+		calls++
+		if input.Array[0] == 3 {
+			return errors.New("timestep failed")
+		}
+		return nil
+	}
+	if err := K2c_time_distributed(output, input, failing); err == nil || err.Error() != "timestep failed" {
+		t.Fatal("expected the error of the layer, got", err)
+	}
+	if calls != 2 {
+		t.Fatal("expected the layer to stop at the failing timestep, got", calls, "calls")
+	}
+}